@@ -2,46 +2,138 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/wtfzambo/dotsync/internal/backup"
 	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/journal"
 	"github.com/wtfzambo/dotsync/internal/manifest"
 	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/storage"
 	"github.com/wtfzambo/dotsync/internal/symlink"
 )
 
 var addCmd = &cobra.Command{
-	Use:   "add <path>",
-	Short: "Add a file to be synced",
+	Use:   "add <path>...",
+	Short: "Add files to be synced",
 	Long: `Add a file to be tracked and synced via cloud storage.
 
 The file will be moved to cloud storage and a symlink will be created
 at the original location. The entry name is inferred from the path
 (e.g., ~/.config/opencode/config.json becomes entry "opencode").
 
-Use --name to specify a custom entry name.`,
+Use --name to specify a custom entry name.
+
+Use --cwd-root for project-local files, including ones outside home: the
+current directory becomes the entry root and the path is taken relative
+to it. The entry is named after the directory unless --name is given.
+
+Use --copy for files that can't be symlinks (e.g. macOS plist files).
+The file is copied to cloud storage and stays a regular file; only that
+file uses copy mode, the rest of the entry keeps using symlinks.
+On Windows without Developer Mode, --windows-fallback (or
+"windowsFallback": true in the config) switches to copy mode
+automatically when the symlink can't be created.
+Use --as-copy-if-symlink-unsupported (or "copyIfSymlinkUnsupported": true)
+for the same fallback on any system, including FAT drives and network
+mounts without symlink support; the original is left in place as a
+regular copy instead of rolling back the add.
+
+Several paths can be given at once, or read from stdin with --stdin
+(one per line; blank lines and lines starting with # are ignored).
+Paths whose entry can't be inferred need --name when reading stdin.
+
+Symlinks are rejected unless --follow-symlinks is given, in which case
+the symlink's real target is tracked instead (targets outside the home
+directory additionally require --yes).
+
+Use --dry-run to see which inference pattern matches, and the entry,
+root, relative path and cloud destination a file would get, along with
+any conflicts, without moving or recording anything.
+
+Use --interactive to review the inferred entry for each file before it
+is moved, and accept it, pick a different entry name, or skip the file.
+--yes accepts every inferred entry.
+
+Before a file is moved, add asks for confirmation. Use --yes to skip it,
+or set "confirmAdds": false in the config to turn it off. Copy-mode adds
+leave the original in place and don't ask.
+
+Files larger than --max-file-size (50MB by default) trigger a warning
+that needs confirmation or --yes, since cloud storage isn't meant for
+large binaries. Use --max-file-size 0 to turn the check off.
+
+If storage is a git repository rather than a synced folder, use
+--git-track (or "gitTrack": true in the config) to 'git add' the new
+cloud files and the manifest, ready to commit. Without a repository it
+only prints a warning.
+
+Use --report <file> to write a JSON summary of the run, listing the
+outcome of every path.`,
 	Example: `  dotsync add ~/.config/opencode/config.json
   dotsync add ~/.zshrc --name shell
-  dotsync add ~/.aws/credentials`,
-	Args: cobra.ExactArgs(1),
+  cd ~/work/project && dotsync add .envrc --cwd-root
+  dotsync add ~/.config/aerc/accounts.conf --desc "work email config"
+  dotsync add ~/.aws/credentials
+  dotsync add ~/Library/Preferences/com.app.plist --name app --copy
+  dotsync add ~/.zshrc ~/.gitconfig
+  git ls-files | dotsync add --stdin --yes
+  dotsync add ~/.config/app/config.json --follow-symlinks
+  dotsync add ~/.config/app/config.json --dry-run
+  dotsync add --interactive ~/.zshrc ~/.config/app/config.json`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !addStdin {
+			return fmt.Errorf("requires at least 1 path, or --stdin")
+		}
+		return nil
+	},
 	RunE: runAdd,
 }
 
-var addName string
+var (
+	addName            string
+	addCopy            bool
+	addCwdRoot         bool
+	addStdin           bool
+	addFollow          bool
+	addYes             bool
+	addDryRun          bool
+	addInteractive     bool
+	addWindowsFallback bool
+	addCopyIfNoSymlink bool
+	addGitTrack        bool
+	addDesc            string
+	addMaxFileSize     string
+	addMaxBytes        int64 // addMaxFileSize parsed by runAdd
+)
 
 func init() {
 	addCmd.Flags().StringVarP(&addName, "name", "n", "", "Custom entry name (inferred from path if not specified)")
+	_ = addCmd.RegisterFlagCompletionFunc("name", completeEntryNames)
+	addCmd.Flags().BoolVar(&addCwdRoot, "cwd-root", false, "Use the current directory as the entry root (for project-local files)")
+	addCmd.Flags().BoolVar(&addCopy, "copy", false, "Keep a regular copy at the original location instead of a symlink")
+	addCmd.Flags().BoolVar(&addStdin, "stdin", false, "Read paths to add from stdin, one per line")
+	addCmd.Flags().BoolVar(&addFollow, "follow-symlinks", false, "Track the target of a symlink instead of rejecting it")
+	addCmd.Flags().BoolVarP(&addYes, "yes", "y", false, "Answer yes to confirmations and warnings (e.g. files outside home)")
+	addCmd.Flags().BoolVarP(&addInteractive, "interactive", "i", false, "Confirm the inferred entry for each file, with the option to rename or skip")
+	addCmd.Flags().BoolVar(&addWindowsFallback, "windows-fallback", false, "Track in copy mode when symlinks aren't allowed (Windows)")
+	addCmd.Flags().BoolVar(&addCopyIfNoSymlink, "as-copy-if-symlink-unsupported", false, "Track in copy mode when the symlink can't be created (any platform or filesystem)")
+	addCmd.Flags().BoolVar(&addGitTrack, "git-track", false, "Stage added files and the manifest with git add, if storage is a git repository")
+	addCmd.Flags().StringVar(&addDesc, "desc", "", "Describe the entry (shown in 'dotsync list')")
+	addCmd.Flags().StringVar(&addMaxFileSize, "max-file-size", "50MB", "Ask before adding files larger than this (0 disables the check)")
+	addCmd.Flags().BoolVar(&addDryRun, "dry-run", false, "Show the inferred entry, root and destination without changing anything")
+	addReportFlag(addCmd)
 	rootCmd.AddCommand(addCmd)
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
-	inputPath := args[0]
-
 	// 0. Validate --name flag if provided (Bug #3 fix)
 	if cmd.Flags().Changed("name") {
 		if err := validateEntryName(addName); err != nil {
@@ -49,43 +141,281 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	maxBytes, err := pathutil.ParseSize(addMaxFileSize)
+	if err != nil {
+		return fmt.Errorf("--max-file-size: %w", err)
+	}
+	addMaxBytes = maxBytes
+
+	if addInteractive && addStdin {
+		return fmt.Errorf("--interactive can't be combined with --stdin, which is used for the path list")
+	}
+
 	// 1. Load config (must be initialized)
-	cfg, err := config.Load()
+	cfg, storagePath, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
+		return err
+	}
+
+	if journal.Exists(manifest.DotsyncDir(storagePath, cfg.StorageSubpath)) && !addDryRun {
+		return fmt.Errorf("an interrupted 'dotsync add' was found\nRun 'dotsync recover' before adding more files")
+	}
+
+	if addStdin && !addYes && !addDryRun && !addCopy && cfg.AddsNeedConfirmation() {
+		return fmt.Errorf("--stdin is used for the path list, so adds can't be confirmed\nUse --yes, or set \"confirmAdds\": false in the config")
+	}
+
+	// Collect paths from arguments and stdin
+	inputPaths := args
+	if addStdin {
+		stdinPaths, err := readPathList(stdinReader)
+		if err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+		inputPaths = append(inputPaths, stdinPaths...)
+	}
+	if len(inputPaths) == 0 {
+		return fmt.Errorf("no paths to add")
+	}
+
+	// Normalize messy input (e.g. ~/.config/app//config.json or a trailing
+	// slash) once, so messages and suggestions show the cleaned path
+	cleanPaths := make([]string, len(inputPaths))
+	for i, p := range inputPaths {
+		cleanPaths[i] = pathutil.CleanInput(p)
+	}
+	inputPaths = cleanPaths
+
+	// Load or create manifest, shared by all paths
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		if strings.Contains(err.Error(), "manifest not found") {
+			m = manifest.New()
+			m.Subpath = cfg.StorageSubpath
+		} else {
+			return fmt.Errorf("loading manifest: %w", err)
+		}
+	}
+
+	if len(inputPaths) == 1 {
+		added, err := addPath(inputPaths[0], cfg, storagePath, m)
+		reportAdd(inputPaths[0], added, err)
+		if err != nil || added == nil {
+			return err
+		}
+		if err := saveAdded(m, storagePath, []*addedFile{added}); err != nil {
+			return err
+		}
+		if addGitTrack || cfg.GitTrack {
+			gitTrackAdded(m, storagePath, []*addedFile{added})
+		}
+		return nil
+	}
+
+	// Files are staged one by one and the manifest is saved once at the end.
+	// On Ctrl-C, the files staged so far are still saved.
+	ctx := commandContext(cmd)
+	var staged []*addedFile
+	var skipped, failed int
+	for _, inputPath := range inputPaths {
+		if ctx.Err() != nil {
+			fmt.Println("\nInterrupted, stopping.")
+			break
+		}
+		fmt.Printf("\n%s\n", inputPath)
+		added, err := addPath(inputPath, cfg, storagePath, m)
+		reportAdd(inputPath, added, err)
+		switch {
+		case err != nil:
+			fmt.Printf("  [failed] %v\n", err)
+			failed++
+		case added != nil:
+			staged = append(staged, added)
+		default:
+			skipped++
+		}
+	}
+
+	if addDryRun {
+		return nil
+	}
+
+	fmt.Println()
+	if len(staged) > 0 {
+		if err := saveAdded(m, storagePath, staged); err != nil {
+			return err
+		}
+		if addGitTrack || cfg.GitTrack {
+			gitTrackAdded(m, storagePath, staged)
+		}
+	}
+
+	fmt.Printf("\nSummary: %d added, %d skipped, %d failed\n", len(staged), skipped, failed)
+	if ctx.Err() != nil {
+		return ErrInterrupted
+	}
+	if failed > 0 {
+		return markAs(ErrPartialFailure, fmt.Errorf("some files failed to add"))
+	}
+	return nil
+}
+
+// reportAdd records the outcome of adding inputPath for --report.
+func reportAdd(inputPath string, added *addedFile, err error) {
+	switch {
+	case err != nil:
+		opReport.file("", inputPath, "failed", err, "")
+	case added != nil:
+		opReport.file(added.entryName, added.relPath, "added", nil, "")
+	case addDryRun:
+		opReport.file("", inputPath, "planned", nil, "dry run")
+	default:
+		opReport.file("", inputPath, "skipped", nil, "")
+	}
+}
+
+// addedFile is a file moved to cloud storage and recorded in the in-memory
+// manifest, waiting for the manifest to be saved.
+type addedFile struct {
+	entryName string
+	relPath   string
+	absPath   string
+	destPath  string
+	// bk is the backup of the original file (nil in copy mode)
+	bk *backup.Backup
+}
+
+// saveAdded saves the manifest once for all staged files. If saving fails,
+// every staged file is rolled back so no partial state is persisted.
+func saveAdded(m *manifest.Manifest, storagePath string, staged []*addedFile) error {
+	if addDesc != "" {
+		for _, f := range staged {
+			m.SetDescription(f.entryName, addDesc)
+		}
+	}
+
+	if err := m.Save(storagePath); err != nil {
+		for i := len(staged) - 1; i >= 0; i-- {
+			rollbackAdd(m, staged[i])
+		}
+		journal.Clear(m.DotsyncDir(storagePath))
+		return fmt.Errorf("saving manifest: %w", err)
 	}
-	if cfg == nil {
-		return fmt.Errorf("dotsync not initialized. Run 'dotsync init <provider>' first")
+	if err := journal.Clear(m.DotsyncDir(storagePath)); err != nil {
+		fmt.Printf("Warning: %v\n", err)
 	}
 
-	storagePath := pathutil.ExpandHome(cfg.StoragePath)
+	for _, f := range staged {
+		if f.bk != nil {
+			discardBackup(f.bk)
+			fmt.Printf("Added '%s' to entry '%s'\n", f.relPath, f.entryName)
+		} else {
+			fmt.Printf("Added '%s' to entry '%s' (copy mode)\n", f.relPath, f.entryName)
+		}
+	}
+	return nil
+}
 
-	// Verify storage is available
-	if _, err := os.Stat(storagePath); os.IsNotExist(err) {
-		return fmt.Errorf("storage unavailable: %s\nMake sure your cloud storage is mounted/syncing", storagePath)
+// gitTrackAdded stages the cloud copies of the added files and the manifest
+// in the git repository holding storage. The files are already added, so
+// problems are only warnings.
+func gitTrackAdded(m *manifest.Manifest, storagePath string, added []*addedFile) {
+	dotsyncDir := m.DotsyncDir(storagePath)
+	repo := storage.GitRepo(dotsyncDir, storagePath)
+	if repo == "" {
+		fmt.Printf("Warning: --git-track: %s is not in a git repository, nothing staged\n", pathutil.ContractHome(dotsyncDir))
+		return
 	}
 
+	paths := []string{filepath.Join(dotsyncDir, manifest.ManifestFileName)}
+	for _, f := range added {
+		paths = append(paths, f.destPath)
+	}
+	if err := storage.GitAdd(repo, paths...); err != nil {
+		fmt.Printf("Warning: --git-track: %v\n", err)
+		return
+	}
+	fmt.Printf("Staged %d file(s) and the manifest in %s\n", len(added), pathutil.ContractHome(repo))
+}
+
+// rollbackAdd undoes a staged add: untracks the file and puts the original back.
+func rollbackAdd(m *manifest.Manifest, f *addedFile) {
+	m.RemoveFile(f.entryName, f.relPath)
+	if f.bk == nil {
+		// Copy mode: the original was never touched
+		os.Remove(f.destPath)
+		return
+	}
+	symlink.Remove(f.absPath)
+	symlink.MoveFile(f.destPath, f.absPath)
+	restoreBackup(f.bk)
+}
+
+// addPath moves a single file to cloud storage, links it and records it in
+// the in-memory manifest. The caller saves the manifest with saveAdded.
+// Returns nil without error if nothing was added (already tracked, dry run).
+func addPath(inputPath string, cfg *config.Config, storagePath string, m *manifest.Manifest) (*addedFile, error) {
 	// 2. Convert to absolute path
 	absPath, err := pathutil.AbsolutePath(inputPath)
 	if err != nil {
-		return fmt.Errorf("resolving path: %w", err)
+		return nil, fmt.Errorf("resolving path: %w", err)
 	}
 
-	// 3. Validate the file
-	if err := pathutil.ValidateForAdd(absPath); err != nil {
-		if valErr, ok := err.(pathutil.ValidationError); ok {
-			if valErr.IsWarn {
-				// Warning - ask for confirmation
-				fmt.Printf("Warning: %s\n", valErr.Message)
-				if !confirmPrompt("Continue anyway?") {
-					return fmt.Errorf("aborted")
-				}
-			} else {
-				// Fatal error
-				return fmt.Errorf("%s", valErr.Message)
+	// 2.1. Resolve symlinks to their real target if requested
+	if addFollow {
+		resolved, err := resolveSymlinkTarget(absPath)
+		if err != nil {
+			return nil, err
+		}
+		if resolved != absPath {
+			if !pathutil.IsUnderHome(resolved) && !addYes {
+				return nil, fmt.Errorf("symlink target is outside home directory: %s\nUse --yes to track it anyway", resolved)
 			}
-		} else {
-			return err
+			fmt.Printf("Following symlink: %s -> %s\n", pathutil.ContractHome(absPath), pathutil.ContractHome(resolved))
+			absPath = resolved
+		}
+	}
+
+	// 2.5. Check if already tracked
+	if entryName := pathutil.IsAlreadyTracked(absPath, m); entryName != "" {
+		fmt.Printf("Already tracked in entry '%s'\n", entryName)
+		return nil, nil
+	}
+
+	// 2.6. Refuse dotsync's own config and storage files
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := pathutil.ValidateNotState(absPath, []string{configDir, m.DotsyncDir(storagePath)}); err != nil {
+		return nil, err
+	}
+
+	// 3. Validate the file
+	var warnings []string
+	for _, err := range splitErrors(pathutil.ValidateForAdd(absPath, m.Ignore, addMaxBytes)) {
+		valErr, ok := err.(pathutil.ValidationError)
+		switch {
+		case !ok:
+			return nil, err
+		case valErr.NeedsCopy && addCopy:
+			// Copy mode doesn't need a symlink, so the file can be tracked
+		case valErr.OutsideHome && addCwdRoot:
+			// The root was chosen explicitly, files outside home are expected
+		case !valErr.IsWarn:
+			// Fatal error
+			return nil, fmt.Errorf("%s", valErr.Message)
+		default:
+			warnings = append(warnings, valErr.Message)
+		}
+	}
+	if len(warnings) > 0 {
+		// Warnings - ask for confirmation
+		for _, w := range warnings {
+			fmt.Printf("Warning: %s\n", w)
+		}
+		if !addYes && !addDryRun && !confirmPrompt("Continue anyway?") {
+			return nil, ErrAborted
 		}
 	}
 
@@ -93,162 +423,508 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	// We need to be able to delete the file after moving it, so check write permissions BEFORE copying
 	parentDir := filepath.Dir(absPath)
 	if err := pathutil.CheckWritePermission(parentDir); err != nil {
-		return fmt.Errorf("cannot delete file from read-only directory: %s\n%w", parentDir, err)
+		return nil, fmt.Errorf("cannot delete file from read-only directory: %s\n%w", parentDir, err)
 	}
 
-	// 4. Load or create manifest
-	m, err := manifest.Load(storagePath)
+	// 5.5-7. Decide where the file goes
+	plan, err := planAdd(absPath, inputPath, addName, storagePath, m)
 	if err != nil {
-		if strings.Contains(err.Error(), "manifest not found") {
-			m = manifest.New()
-		} else {
-			return fmt.Errorf("loading manifest: %w", err)
+		return nil, err
+	}
+	if err := symlink.CheckLoop(absPath, plan.destPath, m.DotsyncDir(storagePath)); err != nil {
+		return nil, err
+	}
+
+	if addDryRun {
+		printAddPlan(absPath, plan)
+		return nil, nil
+	}
+
+	// 7.5. Let the user accept, rename or skip the inferred entry
+	if addInteractive && !addYes {
+		plan, err = confirmAddPlan(absPath, inputPath, storagePath, m, plan)
+		if err != nil || plan.entryName == "" {
+			return nil, err
 		}
 	}
+	if len(plan.conflicts) > 0 {
+		return nil, markAs(ErrConflict, fmt.Errorf("%s", plan.conflicts[0]))
+	}
 
-	// 5. Check if already tracked
-	if entryName := pathutil.IsAlreadyTracked(absPath, m); entryName != "" {
-		fmt.Printf("Already tracked in entry '%s'\n", entryName)
-		return nil
+	entryName, root, relPath, destPath := plan.entryName, plan.root, plan.relPath, plan.destPath
+
+	// 7.6. Confirm the move, unless the plan was just accepted interactively
+	if !addCopy && !addYes && !addInteractive && cfg.AddsNeedConfirmation() {
+		question := fmt.Sprintf("Will move %s into cloud and replace with symlink. Continue?", pathutil.ContractHome(absPath))
+		if !confirmPrompt(question) {
+			return nil, ErrAborted
+		}
 	}
 
-	// 6. Infer entry name and root
-	var entryName, root, relPath string
+	// 8. Copy mode: copy to cloud storage and keep the original as a regular file
+	if addCopy {
+		return addCopyFile(m, absPath, entryName, root, relPath, destPath)
+	}
+
+	// 9. Create backup
+	bk, err := createBackup(backupDirFor(cfg, storagePath), absPath)
+	if err != nil {
+		return nil, fmt.Errorf("creating backup: %w", err)
+	}
+
+	// 9.5. Journal the move, so 'dotsync recover' can finish it after a crash
+	// (cleared by saveAdded once the manifest is saved)
+	op := journal.Op{Entry: entryName, Root: root, RelPath: relPath, OriginalPath: absPath, CloudPath: destPath, BackupPath: bk.BackupPath, Hostname: journal.LocalHost()}
+	if err := journal.Append(m.DotsyncDir(storagePath), op); err != nil {
+		bk.Cleanup()
+		return nil, err
+	}
+
+	// 10-11. Move file to cloud storage and create symlink at original location
+	fmt.Printf("Moving to cloud storage and linking: %s -> %s\n", pathutil.ContractHome(absPath), pathutil.ContractHome(destPath))
+	if err := symlink.Reattach(absPath, destPath); err != nil {
+		// The file was moved back (or never moved)
+		journal.Remove(m.DotsyncDir(storagePath), absPath)
+		if copyFallback(addWindowsFallback || cfg.WindowsFallback, addCopyIfNoSymlink || cfg.CopyIfSymlinkUnsupported, err) {
+			discardBackup(bk)
+			fmt.Println("Symlinks not supported here, tracking in copy mode instead")
+			return addCopyFile(m, absPath, entryName, root, relPath, destPath)
+		}
+		restoreBackup(bk)
+		return nil, unsupportedHint(err, "Use --as-copy-if-symlink-unsupported")
+	}
+
+	// 12. Update manifest (saved and backup discarded by saveAdded)
+	m.AddFile(entryName, root, relPath)
+	recordParentMode(m, entryName, relPath, absPath)
+
+	return &addedFile{entryName: entryName, relPath: relPath, absPath: absPath, destPath: destPath, bk: bk}, nil
+}
+
+// recordParentMode stores the permissions of absPath's parent directory in
+// the manifest if link wouldn't recreate it the same way, e.g. a 0750
+// directory, so a machine missing it gets the original mode. Windows
+// doesn't have these permissions.
+func recordParentMode(m *manifest.Manifest, entryName, relPath, absPath string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	// Home is never created by link
+	parent := filepath.Dir(absPath)
+	if home, err := pathutil.HomeDir(); err == nil && parent == home {
+		return
+	}
+	info, err := os.Stat(parent)
+	if err != nil {
+		return
+	}
+	if mode := info.Mode().Perm(); mode != symlink.DirMode(parent) {
+		m.SetParentMode(entryName, relPath, mode)
+	}
+}
+
+// copyFallback reports whether a failed symlink should turn into copy mode:
+// windowsFallback covers missing privileges on Windows, copyIfUnsupported
+// also filesystems without symlinks.
+func copyFallback(windowsFallback, copyIfUnsupported bool, err error) bool {
+	if copyIfUnsupported {
+		return errors.Is(err, symlink.ErrNoSymlinkPrivilege) || errors.Is(err, symlink.ErrSymlinkUnsupported)
+	}
+	return windowsFallback && errors.Is(err, symlink.ErrNoSymlinkPrivilege)
+}
+
+// unsupportedHint adds how to keep copies instead to err if the filesystem
+// can't hold symlinks. use names the way to turn on copy mode.
+func unsupportedHint(err error, use string) error {
+	if !errors.Is(err, symlink.ErrSymlinkUnsupported) {
+		return err
+	}
+	return fmt.Errorf("%w\n\n%s (or set \"copyIfSymlinkUnsupported\": true in the config) to keep copies instead", err, use)
+}
+
+// addCopyFile copies absPath to cloud storage and tracks it in copy mode.
+// The original stays a regular file.
+func addCopyFile(m *manifest.Manifest, absPath, entryName, root, relPath, destPath string) (*addedFile, error) {
+	fmt.Printf("Copying to cloud storage: %s -> %s\n", pathutil.ContractHome(absPath), pathutil.ContractHome(destPath))
+	if err := symlink.CopyFile(absPath, destPath); err != nil {
+		return nil, fmt.Errorf("copying file: %w", err)
+	}
+
+	m.AddFile(entryName, root, relPath)
+	m.SetFileMode(entryName, relPath, manifest.ModeCopy)
+
+	return &addedFile{entryName: entryName, relPath: relPath, absPath: absPath, destPath: destPath}, nil
+}
+
+// resolveSymlinkTarget returns the real path of absPath if it is a symlink,
+// or absPath unchanged otherwise.
+func resolveSymlinkTarget(absPath string) (string, error) {
+	isLink, err := symlink.IsSymlink(absPath)
+	if err != nil {
+		return "", fmt.Errorf("checking file: %w", err)
+	}
+	if !isLink {
+		return absPath, nil
+	}
 
-	if addName != "" {
+	resolved, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return "", fmt.Errorf("resolving symlink: %w", err)
+	}
+	return resolved, nil
+}
+
+// addPlan describes where addPath would put a file.
+type addPlan struct {
+	// pattern is the inference pattern that matched, or how the entry was chosen
+	pattern   string
+	entryName string
+	root      string
+	relPath   string
+	destPath  string
+	// conflicts lists reasons the file can't be added as planned
+	conflicts []string
+}
+
+// planAdd infers the entry name, root and relative path for absPath, or
+// uses name if not empty, and checks it against the manifest and cloud storage. Conflicts are collected
+// in the plan rather than returned, so --dry-run can report them.
+func planAdd(absPath, inputPath, name, storagePath string, m *manifest.Manifest) (addPlan, error) {
+	var plan addPlan
+
+	// 5.5. Refuse paths that overlap a tracked file (would shadow each other)
+	if entryName, trackedPath := pathutil.CheckNesting(absPath, m); entryName != "" {
+		plan.conflicts = append(plan.conflicts, fmt.Sprintf("path overlaps %s, already tracked in entry '%s'", pathutil.ContractHome(trackedPath), entryName))
+	}
+	if len(plan.conflicts) > 0 && !addDryRun {
+		// No point asking for a name
+		return plan, nil
+	}
+
+	// 6. Infer entry name and root
+	if addCwdRoot {
+		if err := planCwdRoot(&plan, absPath, name, m); err != nil {
+			return plan, err
+		}
+	} else if name != "" {
 		// User specified name
-		entryName = addName
+		plan.entryName = name
 
 		// Check for conflict with existing entry
-		conflict, err := pathutil.CheckEntryConflict(absPath, addName, m)
+		conflict, err := pathutil.CheckEntryConflict(absPath, name, m)
 		if err != nil {
-			return fmt.Errorf("checking conflicts: %w", err)
+			return plan, fmt.Errorf("checking conflicts: %w", err)
 		}
-		if conflict != "" && conflict != addName {
-			return fmt.Errorf("file is under entry '%s', cannot add to '%s'", conflict, addName)
+		if conflict != "" && conflict != name {
+			plan.conflicts = append(plan.conflicts, fmt.Sprintf("file is under entry '%s', cannot add to '%s'", conflict, name))
 		}
 
 		// If entry exists, use its root
-		if existing := m.GetEntry(addName); existing != nil {
-			root = existing.Root
-			expandedRoot := pathutil.ExpandHome(root)
-			if !strings.HasPrefix(absPath, expandedRoot) {
-				return fmt.Errorf("file is not under existing entry root: %s", root)
+		if existing := m.GetEntry(name); existing != nil {
+			plan.pattern = fmt.Sprintf("existing entry '%s'", name)
+			plan.root = existing.Root
+			relPath, err := pathutil.SafeRel(pathutil.ExpandHome(plan.root), absPath)
+			if err != nil {
+				return plan, fmt.Errorf("file is not under existing entry root: %s\n%w", plan.root, err)
 			}
-			relPath, _ = filepath.Rel(expandedRoot, absPath)
+			plan.relPath = relPath
 		} else {
 			// Try to infer root from path, or use parent directory
 			inferred := pathutil.InferFromPath(absPath)
 			if inferred != nil {
-				root = inferred.Root
-				relPath = inferred.RelPath
+				plan.pattern = inferred.Pattern
+				plan.root = inferred.Root
+				plan.relPath = inferred.RelPath
 			} else {
 				// Default: use parent directory as root
-				root = pathutil.ContractHome(filepath.Dir(absPath))
-				relPath = filepath.Base(absPath)
+				plan.pattern = "fallback (parent directory)"
+				plan.root = pathutil.ContractHome(filepath.Dir(absPath))
+				plan.relPath = filepath.Base(absPath)
 			}
 		}
 	} else {
 		// Infer from path
 		inferred := pathutil.InferFromPath(absPath)
-		if inferred != nil {
-			entryName = inferred.Name
-			root = inferred.Root
-			relPath = inferred.RelPath
+		if enclosing := containerEntry(inferred, m); enclosing != "" {
+			// The whole container dir is already an entry, keep adding to it
+			plan.pattern = fmt.Sprintf("existing entry '%s'", enclosing)
+			plan.entryName = enclosing
+			plan.root = m.Entries[enclosing].Root
+			relPath, err := pathutil.SafeRel(pathutil.ExpandHome(plan.root), absPath)
+			if err != nil {
+				return plan, fmt.Errorf("file is not under existing entry root: %s\n%w", plan.root, err)
+			}
+			plan.relPath = relPath
+		} else if inferred != nil {
+			plan.pattern = inferred.Pattern
+			plan.entryName = inferred.Name
+			plan.root = inferred.Root
+			plan.relPath = inferred.RelPath
 
 			// Check if this entry already exists with a different root
-			if existing := m.GetEntry(entryName); existing != nil {
-				if existing.Root != root {
-					return fmt.Errorf("entry '%s' exists with different root: %s (expected %s). Use --name to specify a different entry", entryName, existing.Root, root)
+			if existing := m.GetEntry(plan.entryName); existing != nil {
+				if manifest.NormalizeRoot(existing.Root) != manifest.NormalizeRoot(plan.root) {
+					plan.conflicts = append(plan.conflicts, fmt.Sprintf("entry '%s' exists with different root: %s (expected %s). Use --name to specify a different entry", plan.entryName, existing.Root, plan.root))
 				}
-				root = existing.Root
+				plan.root = existing.Root
 			}
 		} else {
+			plan.pattern = "fallback (parent directory)"
+			if addDryRun {
+				// Nothing to name the entry after; add would prompt
+				return plan, nil
+			}
+
 			// Cannot infer - prompt for name
 			fmt.Printf("Cannot infer entry name from path: %s\n", absPath)
-			entryName = promptForName()
-			if entryName == "" {
-				return fmt.Errorf("entry name is required")
+			plan.entryName = promptForName()
+			if plan.entryName == "" {
+				return plan, fmt.Errorf("entry name is required")
 			}
 
 			// Use parent directory as root
-			root = pathutil.ContractHome(filepath.Dir(absPath))
-			relPath = filepath.Base(absPath)
+			plan.root = pathutil.ContractHome(filepath.Dir(absPath))
+			plan.relPath = filepath.Base(absPath)
 		}
 
 		// Check for conflict
 		conflict, err := pathutil.CheckEntryConflict(absPath, "", m)
 		if err != nil {
-			return fmt.Errorf("checking conflicts: %w", err)
+			return plan, fmt.Errorf("checking conflicts: %w", err)
 		}
-		if conflict != "" && conflict != entryName {
-			return fmt.Errorf("file is under entry '%s'. Use 'dotsync add %s --name %s' to add to that entry", conflict, inputPath, conflict)
+		if conflict != "" && conflict != plan.entryName {
+			plan.conflicts = append(plan.conflicts, fmt.Sprintf("file is under entry '%s'. Use 'dotsync add %s --name %s' to add to that entry", conflict, inputPath, conflict))
+		}
+	}
+
+	// 6.5. Refuse new entries whose folder holds another machine's subpath
+	if plan.entryName != "" {
+		tree, err := subpathCollision(m, storagePath, plan.entryName)
+		if err != nil {
+			return plan, err
+		}
+		if tree != "" {
+			plan.conflicts = append(plan.conflicts, fmt.Sprintf("entry name '%s' collides with storage subpath '%s'. Use --name to specify a different entry", plan.entryName, tree))
 		}
 	}
 
 	// 7. Calculate destination path in cloud storage
-	// Structure: <storage>/dotsync/<name>/<relPath>
-	destPath := filepath.Join(storagePath, "dotsync", entryName, relPath)
+	// Structure: <storage>/dotsync/<storage dir>/<relPath>, see Entry.Storage
+	plan.destPath = m.CloudPath(storagePath, plan.entryName, plan.relPath)
 
 	// Check if destination already exists
-	if _, err := os.Stat(destPath); err == nil {
-		return fmt.Errorf("file already exists in cloud storage: %s\nIf syncing from another machine, use 'dotsync link' instead", destPath)
+	if _, err := os.Stat(plan.destPath); err == nil {
+		plan.conflicts = append(plan.conflicts, fmt.Sprintf("file already exists in cloud storage: %s\nIf syncing from another machine, use 'dotsync link' instead", plan.destPath))
+	}
+
+	return plan, nil
+}
+
+// splitErrors returns the errors joined in err by errors.Join, err itself
+// if it isn't a joined error, or nil.
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
 	}
+	return []error{err}
+}
 
-	// 8. Create backup
-	bk, err := backup.Create(absPath)
+// containerEntry returns the existing entry rooted at the container
+// directory inferred was split from (see pathutil.ContainerConfigDirs),
+// e.g. an entry "systemd" for ~/.config/systemd/user/foo.service, or "".
+// Such entries were created before the container was split and keep
+// tracking everything below it.
+func containerEntry(inferred *pathutil.InferResult, m *manifest.Manifest) string {
+	if inferred == nil || inferred.Container == "" {
+		return ""
+	}
+	for _, name := range m.Names() {
+		if manifest.NormalizeRoot(m.Entries[name].Root) == manifest.NormalizeRoot(inferred.Container) {
+			return name
+		}
+	}
+	return ""
+}
+
+// subpathCollision returns the storage subpath nested in this machine's
+// storage folder that a new entry called name would share folders with,
+// or "" if there is none. Existing entries keep their folder.
+func subpathCollision(m *manifest.Manifest, storagePath, name string) (string, error) {
+	if m.GetEntry(name) != nil {
+		return "", nil
+	}
+	trees, err := manifest.NestedTrees(m.DotsyncDir(storagePath))
 	if err != nil {
-		return fmt.Errorf("creating backup: %w", err)
+		return "", err
 	}
+	for _, tree := range trees {
+		if manifest.Overlaps(name, tree) {
+			return tree, nil
+		}
+	}
+	return "", nil
+}
 
-	// 9. Move file to cloud storage
-	fmt.Printf("Moving to cloud storage: %s -> %s\n", pathutil.ContractHome(absPath), pathutil.ContractHome(destPath))
-	if err := symlink.MoveFile(absPath, destPath); err != nil {
-		bk.Restore()
-		return fmt.Errorf("moving file: %w", err)
+// planCwdRoot fills plan for --cwd-root: the current directory is the entry
+// root, and the entry is named after it unless name is given.
+func planCwdRoot(plan *addPlan, absPath, name string, m *manifest.Manifest) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
 	}
+	relPath, err := pathutil.SafeRel(cwd, absPath)
+	if err != nil {
+		return fmt.Errorf("--cwd-root: %w", err)
+	}
+	if name == "" {
+		name = filepath.Base(cwd)
+		if err := validateEntryName(name); err != nil {
+			return fmt.Errorf("can't name the entry after %s: %w\nUse --name to choose a name", cwd, err)
+		}
+	}
+
+	plan.pattern = "current directory (--cwd-root)"
+	plan.entryName = name
+	plan.root = pathutil.ContractHome(cwd)
+	plan.relPath = relPath
 
-	// 10. Create symlink at original location
-	fmt.Printf("Creating symlink: %s -> %s\n", pathutil.ContractHome(absPath), pathutil.ContractHome(destPath))
-	if err := symlink.Create(absPath, destPath); err != nil {
-		// Rollback: move file back
-		symlink.MoveFile(destPath, absPath)
-		bk.Restore()
-		return fmt.Errorf("creating symlink: %w", err)
+	if existing := m.GetEntry(name); existing != nil && manifest.NormalizeRoot(existing.Root) != manifest.NormalizeRoot(cwd) {
+		plan.conflicts = append(plan.conflicts, fmt.Sprintf("entry '%s' exists with root %s, not the current directory. Use --name to specify a different entry", name, existing.Root))
+	}
+	conflict, err := pathutil.CheckEntryConflict(absPath, name, m)
+	if err != nil {
+		return fmt.Errorf("checking conflicts: %w", err)
+	}
+	if conflict != "" && conflict != name {
+		plan.conflicts = append(plan.conflicts, fmt.Sprintf("file is under entry '%s', cannot add to '%s'", conflict, name))
 	}
+	return nil
+}
 
-	// 11. Update manifest
-	m.AddFile(entryName, root, relPath)
-	if err := m.Save(storagePath); err != nil {
-		// Rollback: remove symlink, move file back
-		symlink.Remove(absPath)
-		symlink.MoveFile(destPath, absPath)
-		bk.Restore()
-		return fmt.Errorf("saving manifest: %w", err)
+// confirmAddPlan shows the planned entry for a file and asks whether to
+// accept it, use a different entry name, or skip the file. Returns a plan
+// with an empty entry name if the file should be skipped.
+func confirmAddPlan(absPath, inputPath, storagePath string, m *manifest.Manifest, plan addPlan) (addPlan, error) {
+	for {
+		fmt.Printf("%s\n", pathutil.ContractHome(absPath))
+		fmt.Printf("  Entry: %s\n  Root:  %s\n  Path:  %s\n", plan.entryName, plan.root, plan.relPath)
+		for _, c := range plan.conflicts {
+			fmt.Printf("  Conflict: %s\n", c)
+		}
+		response, err := prompter.Ask("[a]ccept, [r]ename, [s]kip: ")
+		if err != nil {
+			// stdin closed, nothing more to ask
+			return addPlan{}, ErrAborted
+		}
+		switch strings.ToLower(response) {
+		case "a", "accept":
+			return plan, nil
+		case "s", "skip":
+			fmt.Println("Skipped")
+			return addPlan{}, nil
+		case "r", "rename":
+			name := promptForName()
+			if err := validateEntryName(name); err != nil {
+				fmt.Printf("Invalid name: %v\n", err)
+				continue
+			}
+			renamed, err := planAdd(absPath, inputPath, name, storagePath, m)
+			if err != nil {
+				fmt.Printf("Can't use '%s': %v\n", name, err)
+				continue
+			}
+			plan = renamed
+		default:
+			fmt.Println("Invalid choice. Please enter 'a', 'r', or 's'")
+		}
 	}
+}
 
-	// 12. Cleanup backup
+// printAddPlan prints the decisions addPath would make for a file.
+func printAddPlan(absPath string, plan addPlan) {
+	fmt.Printf("Dry run: %s\n", pathutil.ContractHome(absPath))
+	fmt.Printf("  Pattern:     %s\n", plan.pattern)
+	if plan.entryName == "" {
+		fmt.Println("  Entry:       (can't be inferred, use --name)")
+		return
+	}
+	fmt.Printf("  Entry:       %s\n", plan.entryName)
+	fmt.Printf("  Root:        %s\n", plan.root)
+	fmt.Printf("  Path:        %s\n", plan.relPath)
+	fmt.Printf("  Destination: %s\n", pathutil.ContractHome(plan.destPath))
+	if addCopy {
+		fmt.Println("  Mode:        copy")
+	}
+	if len(plan.conflicts) == 0 {
+		fmt.Println("  Conflicts:   none")
+		return
+	}
+	for _, c := range plan.conflicts {
+		fmt.Printf("  Conflict:    %s\n", c)
+	}
+}
+
+// backupDirFor returns the directory conflict backups should go to.
+// Returns empty string for the default local backup directory.
+func backupDirFor(cfg *config.Config, storagePath string) string {
+	if cfg.BackupToStorage {
+		return backup.StorageBackupDir(manifest.DotsyncDir(storagePath, cfg.StorageSubpath))
+	}
+	return ""
+}
+
+// createBackup creates a backup in dir, or in the default backup directory if dir is empty.
+func createBackup(dir, path string) (*backup.Backup, error) {
+	if dir == "" {
+		return backup.Create(path)
+	}
+	return backup.CreateIn(dir, path)
+}
+
+// discardBackup removes a temporary backup once the operation succeeded,
+// unless --keep-backups is set.
+func discardBackup(bk *backup.Backup) {
+	if keepBackups {
+		fmt.Printf("Keeping backup: %s\n", bk.BackupPath)
+		return
+	}
 	bk.Cleanup()
+}
 
-	fmt.Printf("Added '%s' to entry '%s'\n", relPath, entryName)
-	return nil
+// restoreBackup restores a backup after a failed operation. If restoring
+// fails, the backup is kept and its location is printed so nothing is lost.
+func restoreBackup(bk *backup.Backup) {
+	if err := bk.Restore(); err != nil {
+		fmt.Printf("Restoring backup failed: %v\nBackup kept at: %s\n", err, bk.BackupPath)
+	}
+}
+
+// readPathList reads newline-separated paths, skipping blank lines and # comments.
+func readPathList(r io.Reader) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	return paths, scanner.Err()
 }
 
 // confirmPrompt asks the user for yes/no confirmation.
 func confirmPrompt(question string) bool {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("%s [y/N]: ", question)
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
-	return response == "y" || response == "yes"
+	return prompter.Confirm(question)
 }
 
 // promptForName asks the user for an entry name.
 func promptForName() string {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Entry name: ")
-	name, _ := reader.ReadString('\n')
-	return strings.TrimSpace(name)
+	name, _ := prompter.Ask("Entry name: ")
+	return name
 }
 
 // validateEntryName checks if an entry name is valid.
@@ -266,5 +942,9 @@ func validateEntryName(name string) error {
 		return fmt.Errorf("entry name cannot be '.' or '..'")
 	}
 
+	if name == backup.StorageBackupDirName || name == backup.ReplacedDirName || name == journal.FileName {
+		return fmt.Errorf("entry name '%s' is reserved", name)
+	}
+
 	return nil
 }
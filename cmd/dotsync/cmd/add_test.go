@@ -1,8 +1,21 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/backup"
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/symlink"
 )
 
 func TestValidateEntryName(t *testing.T) {
@@ -47,6 +60,12 @@ func TestValidateEntryName(t *testing.T) {
 			wantErr:     true,
 			errContains: "cannot be",
 		},
+		{
+			name:        "reserved backup dir",
+			entryName:   ".backups",
+			wantErr:     true,
+			errContains: "reserved",
+		},
 		{
 			name:        "multiple separators",
 			entryName:   "foo/bar/baz",
@@ -75,3 +94,523 @@ func TestValidateEntryName(t *testing.T) {
 		})
 	}
 }
+
+func TestReadPathList(t *testing.T) {
+	input := `# dotfiles to track
+~/.zshrc
+
+  ~/.gitconfig  
+# ~/.ignored
+~/.config/nvim/init.lua
+`
+	got, err := readPathList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readPathList() failed: %v", err)
+	}
+
+	want := []string{"~/.zshrc", "~/.gitconfig", "~/.config/nvim/init.lua"}
+	if len(got) != len(want) {
+		t.Fatalf("readPathList() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("path[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestSaveAdded_RollbackOnFailure tests that a failed manifest save rolls
+// back every staged file
+func TestSaveAdded_RollbackOnFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tmpDir := t.TempDir()
+
+	// A file where the storage directory should be makes Save fail
+	storagePath := filepath.Join(tmpDir, "storage")
+	if err := os.WriteFile(storagePath, []byte("not a dir"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	m := manifest.New()
+	var staged []*addedFile
+	for _, name := range []string{"a", "b"} {
+		absPath := filepath.Join(tmpDir, "home", name+".conf")
+		destPath := filepath.Join(tmpDir, "cloud", name, name+".conf")
+		os.MkdirAll(filepath.Dir(absPath), 0755)
+		os.WriteFile(absPath, []byte(name), 0644)
+
+		bk, err := backup.Create(absPath)
+		if err != nil {
+			t.Fatalf("failed to create backup: %v", err)
+		}
+		if err := symlink.MoveFile(absPath, destPath); err != nil {
+			t.Fatalf("failed to move file: %v", err)
+		}
+		if err := symlink.Create(absPath, destPath); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+		m.AddFile(name, filepath.Dir(absPath), name+".conf")
+		staged = append(staged, &addedFile{entryName: name, relPath: name + ".conf", absPath: absPath, destPath: destPath, bk: bk})
+	}
+
+	if err := saveAdded(m, storagePath, staged); err == nil {
+		t.Fatal("saveAdded() should fail when the manifest can't be saved")
+	}
+
+	for _, f := range staged {
+		if m.HasEntry(f.entryName) {
+			t.Errorf("entry %q should be removed from the manifest", f.entryName)
+		}
+		info, err := os.Lstat(f.absPath)
+		if err != nil {
+			t.Fatalf("original %s missing after rollback: %v", f.absPath, err)
+		}
+		if !info.Mode().IsRegular() {
+			t.Errorf("original %s should be a regular file after rollback", f.absPath)
+		}
+		if content, _ := os.ReadFile(f.absPath); string(content) != f.entryName {
+			t.Errorf("content of %s = %q, want %q", f.absPath, content, f.entryName)
+		}
+	}
+}
+
+// TestAddPath_ConfirmMove tests that the move is confirmed before anything
+// changes, and that confirmAdds: false skips the question
+func TestAddPath_ConfirmMove(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name        string
+		confirmAdds *bool
+		answers     []string
+		wantErr     error
+		wantAdded   bool
+	}{
+		{name: "declined", answers: []string{"n"}, wantErr: ErrAborted},
+		{name: "accepted", answers: []string{"y"}, wantAdded: true},
+		{name: "disabled in config", confirmAdds: &disabled, wantAdded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			storagePath := filepath.Join(home, "storage")
+			absPath := filepath.Join(home, ".config", "app", "config.json")
+			os.MkdirAll(filepath.Dir(absPath), 0755)
+			os.WriteFile(absPath, []byte("content"), 0644)
+
+			cfg := config.New(storagePath)
+			cfg.ConfirmAdds = tt.confirmAdds
+			useScript(t, tt.answers...)
+
+			m := manifest.New()
+			added, err := addPath(absPath, cfg, storagePath, m)
+			if err != tt.wantErr {
+				t.Fatalf("addPath() error = %v, want %v", err, tt.wantErr)
+			}
+			if (added != nil) != tt.wantAdded {
+				t.Fatalf("addPath() added = %v, want added: %v", added, tt.wantAdded)
+			}
+
+			info, err := os.Lstat(absPath)
+			if err != nil {
+				t.Fatalf("original missing: %v", err)
+			}
+			if isLink := info.Mode()&os.ModeSymlink != 0; isLink != tt.wantAdded {
+				t.Errorf("original is symlink = %v, want %v", isLink, tt.wantAdded)
+			}
+		})
+	}
+}
+
+// TestAddPath_Interactive tests accepting, renaming and skipping the
+// inferred entry with --interactive
+func TestAddPath_Interactive(t *testing.T) {
+	tests := []struct {
+		name      string
+		answers   []string
+		wantErr   error
+		wantEntry string // "" if the file is not added
+	}{
+		{name: "accept", answers: []string{"a"}, wantEntry: "app"},
+		{name: "rename", answers: []string{"r", "renamed", "a"}, wantEntry: "renamed"},
+		{name: "invalid choice, then skip", answers: []string{"x", "s"}},
+		{name: "closed input", wantErr: ErrAborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			storagePath := filepath.Join(home, "storage")
+			absPath := filepath.Join(home, ".config", "app", "config.json")
+			os.MkdirAll(filepath.Dir(absPath), 0755)
+			os.WriteFile(absPath, []byte("content"), 0644)
+
+			addInteractive = true
+			defer func() { addInteractive = false }()
+			p := useScript(t, tt.answers...)
+
+			m := manifest.New()
+			added, err := addPath(absPath, config.New(storagePath), storagePath, m)
+			if err != tt.wantErr {
+				t.Fatalf("addPath() error = %v, want %v", err, tt.wantErr)
+			}
+			// Accepting replaces the move confirmation, nothing else is asked
+			if len(p.answers) != 0 {
+				t.Errorf("unused answers: %v", p.answers)
+			}
+
+			if tt.wantEntry == "" {
+				if added != nil || len(m.Entries) != 0 {
+					t.Errorf("addPath() added %+v, entries %v, want nothing", added, m.Names())
+				}
+				if info, err := os.Lstat(absPath); err != nil || !info.Mode().IsRegular() {
+					t.Errorf("original should be left in place (err: %v)", err)
+				}
+				return
+			}
+			if added == nil || added.entryName != tt.wantEntry || !m.HasEntry(tt.wantEntry) {
+				t.Fatalf("addPath() added %+v, entries %v, want entry %q", added, m.Names(), tt.wantEntry)
+			}
+			if want := m.CloudPath(storagePath, tt.wantEntry, "config.json"); added.destPath != want {
+				t.Errorf("destPath = %q, want %q", added.destPath, want)
+			}
+			if status, _, _ := symlink.Check(absPath, added.destPath); status != symlink.StatusLinked {
+				t.Errorf("original status = %v, want %v", status, symlink.StatusLinked)
+			}
+		})
+	}
+}
+
+// TestAddPath_MessyInput tests that messy-but-valid input paths are added
+// exactly like their clean form
+func TestAddPath_MessyInput(t *testing.T) {
+	disabled := false
+	for _, input := range []string{
+		"~/.config/app/config.json",
+		"~/.config/app//config.json",
+		"~/.config/./app/config.json",
+		"~/.config/app/config.json/",
+		"~/.config/other/../app/config.json",
+	} {
+		t.Run(input, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			storagePath := filepath.Join(home, "storage")
+			absPath := filepath.Join(home, ".config", "app", "config.json")
+			os.MkdirAll(filepath.Dir(absPath), 0755)
+			os.WriteFile(absPath, []byte("content"), 0644)
+
+			cfg := config.New(storagePath)
+			cfg.ConfirmAdds = &disabled
+			m := manifest.New()
+			added, err := addPath(pathutil.CleanInput(input), cfg, storagePath, m)
+			if err != nil || added == nil {
+				t.Fatalf("addPath() = %v, %v", added, err)
+			}
+
+			if added.entryName != "app" || added.relPath != "config.json" || added.absPath != absPath {
+				t.Errorf("added = %s/%s (%s), want app/config.json (%s)", added.entryName, added.relPath, added.absPath, absPath)
+			}
+			if entry := m.GetEntry("app"); entry == nil || entry.Root != "~/.config/app" {
+				t.Errorf("entry = %+v, want root ~/.config/app", entry)
+			}
+		})
+	}
+}
+
+// TestAddPath_DotsyncState tests that the config and storage files of
+// dotsync itself can't be added
+func TestAddPath_DotsyncState(t *testing.T) {
+	home, _, cloudPath := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{configPath, manifest.ManifestPath(storagePath, ""), cloudPath} {
+		m, err := manifest.Load(storagePath, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		added, err := addPath(path, config.New(storagePath), storagePath, m)
+		if err == nil || !strings.Contains(err.Error(), "own state") {
+			t.Errorf("addPath(%s) error = %v, want refusal", path, err)
+		}
+		if added != nil {
+			t.Errorf("addPath(%s) added the file", path)
+		}
+	}
+}
+
+// TestPlanAdd_ContainerEntry tests that files below a container dir like
+// ~/.config/systemd keep going to an entry tracking the whole dir
+func TestPlanAdd_ContainerEntry(t *testing.T) {
+	home, _, _ := setupLinkedFile(t)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	storagePath := filepath.Join(home, "storage")
+	absPath := filepath.Join(home, ".config", "systemd", "user", "bar.service")
+
+	m := manifest.New()
+	plan, err := planAdd(absPath, absPath, "", storagePath, m)
+	if err != nil {
+		t.Fatalf("planAdd() failed: %v", err)
+	}
+	if plan.entryName != "systemd-user" {
+		t.Errorf("new: entryName = %q, want %q", plan.entryName, "systemd-user")
+	}
+
+	m.AddFile("systemd", "~/.config/systemd", "user/foo.service")
+	plan, err = planAdd(absPath, absPath, "", storagePath, m)
+	if err != nil {
+		t.Fatalf("planAdd() failed: %v", err)
+	}
+	if plan.entryName != "systemd" || plan.root != "~/.config/systemd" || plan.relPath != filepath.Join("user", "bar.service") {
+		t.Errorf("existing: plan = %s %s %s, want systemd ~/.config/systemd user/bar.service", plan.entryName, plan.root, plan.relPath)
+	}
+	if len(plan.conflicts) != 0 {
+		t.Errorf("existing: conflicts = %v, want none", plan.conflicts)
+	}
+}
+
+// TestPlanAdd_SubpathCollision tests refusing a new entry whose storage
+// folder holds another machine's storage subpath
+func TestPlanAdd_SubpathCollision(t *testing.T) {
+	home, _, _ := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	other := manifest.New()
+	other.Subpath = "nvim"
+	if err := other.Save(storagePath); err != nil {
+		t.Fatal(err)
+	}
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	absPath := filepath.Join(home, ".config", "nvim", "init.lua")
+	plan, err := planAdd(absPath, absPath, "", storagePath, m)
+	if err != nil {
+		t.Fatalf("planAdd() failed: %v", err)
+	}
+	if len(plan.conflicts) != 1 || !strings.Contains(plan.conflicts[0], "storage subpath 'nvim'") {
+		t.Errorf("conflicts = %v, want a subpath collision", plan.conflicts)
+	}
+
+	plan, err = planAdd(absPath, absPath, "neovim", storagePath, m)
+	if err != nil {
+		t.Fatalf("planAdd(--name) failed: %v", err)
+	}
+	if len(plan.conflicts) != 0 {
+		t.Errorf("--name conflicts = %v, want none", plan.conflicts)
+	}
+}
+
+// TestAddPath_CwdRoot tests adding a project-local file outside home with
+// the current directory as the entry root
+func TestAddPath_CwdRoot(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name      string
+		input     string
+		entryName string
+		wantEntry string
+		wantErr   string
+	}{
+		{name: "named after directory", input: filepath.Join("sub", "config.json"), wantEntry: "project"},
+		{name: "custom name", input: filepath.Join("sub", "config.json"), entryName: "proj", wantEntry: "proj"},
+		{name: "outside current directory", input: filepath.Join("..", "other.json"), wantErr: "--cwd-root"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			storagePath := filepath.Join(home, "storage")
+			project := filepath.Join(t.TempDir(), "project")
+			absPath := filepath.Join(project, "sub", "config.json")
+			os.MkdirAll(filepath.Dir(absPath), 0755)
+			os.WriteFile(absPath, []byte("content"), 0644)
+			os.WriteFile(filepath.Join(project, "..", "other.json"), []byte("other"), 0644)
+			t.Chdir(project)
+
+			addCwdRoot, addName = true, tt.entryName
+			t.Cleanup(func() { addCwdRoot, addName = false, "" })
+			// No answers: the outside-home warning must not prompt
+			useScript(t)
+
+			cfg := config.New(storagePath)
+			cfg.ConfirmAdds = &disabled
+			m := manifest.New()
+			added, err := addPath(pathutil.CleanInput(tt.input), cfg, storagePath, m)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("addPath() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || added == nil {
+				t.Fatalf("addPath() = %v, %v", added, err)
+			}
+
+			wantRel := filepath.Join("sub", "config.json")
+			if added.entryName != tt.wantEntry || added.relPath != wantRel {
+				t.Errorf("added = %s/%s, want %s/%s", added.entryName, added.relPath, tt.wantEntry, wantRel)
+			}
+			entry := m.GetEntry(tt.wantEntry)
+			if entry == nil {
+				t.Fatalf("entry %q not in manifest", tt.wantEntry)
+			}
+			if entry.Root != manifest.ToStorageSlash(project) || strings.HasPrefix(entry.Root, "~") {
+				t.Errorf("root = %q, want absolute %q", entry.Root, project)
+			}
+			if target, err := os.Readlink(absPath); err != nil || target != added.destPath {
+				t.Errorf("symlink target = %q (err: %v), want %q", target, err, added.destPath)
+			}
+		})
+	}
+}
+
+// TestCopyFallback tests which symlink failures turn into copy-mode adds
+func TestCopyFallback(t *testing.T) {
+	privilege := fmt.Errorf("creating symlink: %w", symlink.ErrNoSymlinkPrivilege)
+	unsupported := fmt.Errorf("creating symlink: %w", symlink.ErrSymlinkUnsupported)
+	other := errors.New("disk full")
+
+	tests := []struct {
+		name            string
+		windowsFallback bool
+		copyIfNoSymlink bool
+		err             error
+		want            bool
+	}{
+		{name: "no fallback", err: privilege, want: false},
+		{name: "windows fallback, privilege", windowsFallback: true, err: privilege, want: true},
+		{name: "windows fallback, unsupported", windowsFallback: true, err: unsupported, want: false},
+		{name: "copy flag, privilege", copyIfNoSymlink: true, err: privilege, want: true},
+		{name: "copy flag, unsupported", copyIfNoSymlink: true, err: unsupported, want: true},
+		{name: "copy flag, other error", copyIfNoSymlink: true, err: other, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := copyFallback(tt.windowsFallback, tt.copyIfNoSymlink, tt.err); got != tt.want {
+				t.Errorf("copyFallback() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUnsupportedHint tests that only symlink errors the filesystem can't
+// fix get a hint on how to keep copies
+func TestUnsupportedHint(t *testing.T) {
+	unsupported := fmt.Errorf("creating symlink: %w", symlink.ErrSymlinkUnsupported)
+	if err := unsupportedHint(unsupported, "Use --flag"); !errors.Is(err, symlink.ErrSymlinkUnsupported) || !strings.Contains(err.Error(), "Use --flag") {
+		t.Errorf("unsupportedHint() = %v, want the hint wrapping ErrSymlinkUnsupported", err)
+	}
+
+	other := errors.New("disk full")
+	if err := unsupportedHint(other, "Use --flag"); err != other {
+		t.Errorf("unsupportedHint() = %v, want %v unchanged", err, other)
+	}
+}
+
+// TestGitTrackAdded tests that the added cloud file and the manifest are
+// staged when storage is a git repository
+func TestGitTrackAdded(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	storagePath := filepath.Join(home, "storage")
+	absPath := filepath.Join(home, ".config", "app", "config.json")
+	os.MkdirAll(filepath.Dir(absPath), 0755)
+	os.MkdirAll(storagePath, 0755)
+	os.WriteFile(absPath, []byte("content"), 0644)
+	if out, err := exec.Command("git", "-C", storagePath, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+
+	cfg := config.New(storagePath)
+	useScript(t, "y")
+	m := manifest.New()
+	added, err := addPath(absPath, cfg, storagePath, m)
+	if err != nil || added == nil {
+		t.Fatalf("addPath() = %v, %v", added, err)
+	}
+	if err := saveAdded(m, storagePath, []*addedFile{added}); err != nil {
+		t.Fatalf("saveAdded() error = %v", err)
+	}
+	gitTrackAdded(m, storagePath, []*addedFile{added})
+
+	out, err := exec.Command("git", "-C", storagePath, "diff", "--cached", "--name-only").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	staged := strings.Fields(string(out))
+	want := []string{"dotsync/" + manifest.ManifestFileName, "dotsync/app/config.json"}
+	if !slices.Equal(staged, want) {
+		t.Errorf("staged = %v, want %v", staged, want)
+	}
+}
+
+// TestAddPath_ParentMode tests that a non-default parent directory mode is
+// recorded by add and restored by link when the directory is missing
+func TestAddPath_ParentMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping permission test on Windows - permissions work differently")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	storagePath := filepath.Join(home, "storage")
+	appDir := filepath.Join(home, ".config", "app")
+	absPath := filepath.Join(appDir, "conf.d", "config.json")
+	os.MkdirAll(filepath.Dir(absPath), 0755)
+	os.Chmod(appDir, 0750)
+	os.WriteFile(absPath, []byte("content"), 0644)
+	if err := config.New(storagePath).Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(appDir, "config.json"), []byte("content"), 0644)
+
+	useScript(t, "y", "y")
+	m := manifest.New()
+	var staged []*addedFile
+	for _, p := range []string{filepath.Join(appDir, "config.json"), absPath} {
+		added, err := addPath(p, config.New(storagePath), storagePath, m)
+		if err != nil || added == nil {
+			t.Fatalf("addPath(%s) = %v, %v", p, added, err)
+		}
+		staged = append(staged, added)
+	}
+	if err := saveAdded(m, storagePath, staged); err != nil {
+		t.Fatalf("saveAdded() error = %v", err)
+	}
+
+	entry := m.GetEntry("app")
+	if mode, ok := entry.ParentMode("config.json"); !ok || mode != 0750 {
+		t.Errorf("ParentMode(config.json) = %04o, %v, want 0750, true", mode, ok)
+	}
+	if _, ok := entry.ParentMode("conf.d/config.json"); ok {
+		t.Error("default 0755 parent mode should not be recorded")
+	}
+
+	// Another machine without the directory
+	os.RemoveAll(appDir)
+	if err := runLink(linkCmd, nil); err != nil {
+		t.Fatalf("runLink() error = %v", err)
+	}
+	for dir, want := range map[string]os.FileMode{appDir: 0750, filepath.Dir(absPath): 0755} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", dir, err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s permissions = %04o, want %04o", dir, info.Mode().Perm(), want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+// completeEntryNames suggests the entry names in the manifest that start
+// with toComplete. Completion must never fail loudly, so an uninitialized
+// config, unavailable storage or missing manifest just suggests nothing.
+func completeEntryNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Completion skips PersistentPreRunE, so apply --home here
+	if homeFlag != "" {
+		if err := setHome(homeFlag); err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, name := range m.Names() {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
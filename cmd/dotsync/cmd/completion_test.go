@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+// TestCompleteEntryNames tests that add --name suggests existing entries
+func TestCompleteEntryNames(t *testing.T) {
+	home, _, _ := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddFile("zsh", "~", ".zshrc")
+	m.AddFile("apple", "~/.config/apple", "config.json")
+	if err := m.Save(storagePath); err != nil {
+		t.Fatal(err)
+	}
+
+	names, directive := completeEntryNames(addCmd, nil, "")
+	if want := []string{"app", "apple", "zsh"}; !slices.Equal(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("directive = %v, want NoFileComp", directive)
+	}
+
+	names, _ = completeEntryNames(addCmd, nil, "ap")
+	if want := []string{"app", "apple"}; !slices.Equal(names, want) {
+		t.Errorf("names for %q = %v, want %v", "ap", names, want)
+	}
+}
+
+// TestCompleteEntryNames_Uninitialized tests that completion suggests
+// nothing instead of failing before init
+func TestCompleteEntryNames_Uninitialized(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	names, directive := completeEntryNames(addCmd, nil, "")
+	if len(names) != 0 {
+		t.Errorf("names = %v, want none", names)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("directive = %v, want NoFileComp", directive)
+	}
+}
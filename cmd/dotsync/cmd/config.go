@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/storage"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the local dotsync config",
+	Long:  `Inspect the local dotsync config in ~/.config/dotsync/config.json.`,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check that the config points to usable storage",
+	Long: `Load the config and check that the storage path it points to exists,
+is a writable directory and holds a readable manifest. Each check is
+reported, and the command fails if any of them does.
+
+This only looks at the config and storage; use 'dotsync doctor' to check
+the tracked files. Useful after editing the config by hand or switching
+providers.`,
+	Example: `  dotsync config validate
+  dotsync config validate --storage ~/Dropbox`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// configCheck is the result of one config validation step. Checks after a
+// failed one that depend on it are skipped.
+type configCheck struct {
+	name    string
+	err     error
+	skipped bool
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	// 1. Load config, without requiring storage to be available
+	path, err := config.ConfigPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if cfg == nil && storageOverride == "" {
+		return ErrNotInitialized
+	}
+	if cfg == nil {
+		cfg = config.New(storageOverride)
+	} else if storageOverride != "" {
+		cfg.StoragePath = storageOverride
+	}
+
+	// 2. Run and report each check
+	fmt.Printf("Config: %s\n", pathutil.ContractHome(path))
+	var failed int
+	for _, c := range validateConfig(cfg) {
+		switch {
+		case c.skipped:
+			fmt.Printf("  [skipped] %s\n", c.name)
+		case c.err != nil:
+			fmt.Printf("  [failed]  %s: %v\n", c.name, c.err)
+			failed++
+		default:
+			fmt.Printf("  [ok]      %s\n", c.name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	fmt.Println("\nConfig is valid.")
+	return nil
+}
+
+// validateConfig checks the settings of cfg and the storage it points to,
+// in order.
+func validateConfig(cfg *config.Config) []configCheck {
+	var checks []configCheck
+	failed := false
+	check := func(name string, fn func() error) {
+		if failed {
+			checks = append(checks, configCheck{name: name, skipped: true})
+			return
+		}
+		err := fn()
+		failed = err != nil
+		checks = append(checks, configCheck{name: name, err: err})
+	}
+
+	// Settings don't depend on storage, so a bad one doesn't skip the rest
+	_, err := permRules(cfg)
+	if err == nil {
+		_, err = manifest.CleanSubpath(cfg.StorageSubpath)
+	}
+	checks = append(checks, configCheck{name: "settings are valid", err: err})
+
+	storagePath := storage.ExpandPath(cfg.StoragePath)
+	check("storage path is set", func() error {
+		if cfg.StoragePath == "" {
+			return errors.New(`"storagePath" is empty`)
+		}
+		return nil
+	})
+	check(fmt.Sprintf("storage path exists (%s)", pathutil.ContractHome(storagePath)), func() error {
+		if _, err := os.Stat(storagePath); err != nil {
+			return fmt.Errorf("%w\nMake sure your cloud storage is mounted/syncing", err)
+		}
+		return nil
+	})
+	check("storage path is a writable directory", func() error {
+		return storage.ValidatePath(storagePath)
+	})
+	check("manifest is readable", func() error {
+		// An invalid subpath was reported by the settings check
+		subpath, _ := manifest.CleanSubpath(cfg.StorageSubpath)
+		_, err := manifest.Load(storagePath, subpath)
+		return err
+	})
+	return checks
+}
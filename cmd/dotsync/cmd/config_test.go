@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/journal"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+)
+
+// TestValidateConfig tests the checks run by 'dotsync config validate'
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, storagePath string) *config.Config
+		want  []string // "ok", "failed" or "skipped" per check
+	}{
+		{
+			name: "valid",
+			setup: func(t *testing.T, storagePath string) *config.Config {
+				os.MkdirAll(storagePath, 0755)
+				if err := manifest.New().Save(storagePath); err != nil {
+					t.Fatalf("failed to save manifest: %v", err)
+				}
+				return config.New(storagePath)
+			},
+			want: []string{"ok", "ok", "ok", "ok", "ok"},
+		},
+		{
+			name: "storage missing",
+			setup: func(t *testing.T, storagePath string) *config.Config {
+				return config.New(storagePath)
+			},
+			want: []string{"ok", "ok", "failed", "skipped", "skipped"},
+		},
+		{
+			name: "storage is a file",
+			setup: func(t *testing.T, storagePath string) *config.Config {
+				os.WriteFile(storagePath, []byte("x"), 0644)
+				return config.New(storagePath)
+			},
+			want: []string{"ok", "ok", "ok", "failed", "skipped"},
+		},
+		{
+			name: "no manifest",
+			setup: func(t *testing.T, storagePath string) *config.Config {
+				os.MkdirAll(storagePath, 0755)
+				return config.New(storagePath)
+			},
+			want: []string{"ok", "ok", "ok", "ok", "failed"},
+		},
+		{
+			name: "empty storage path",
+			setup: func(t *testing.T, storagePath string) *config.Config {
+				return config.New("")
+			},
+			want: []string{"ok", "failed", "skipped", "skipped", "skipped"},
+		},
+		{
+			name: "bad permission rule",
+			setup: func(t *testing.T, storagePath string) *config.Config {
+				os.MkdirAll(storagePath, 0755)
+				manifest.New().Save(storagePath)
+				cfg := config.New(storagePath)
+				cfg.Permissions = []config.PermissionRule{{Pattern: ".netrc", Mode: "999"}}
+				return cfg
+			},
+			want: []string{"failed", "ok", "ok", "ok", "ok"},
+		},
+		{
+			name: "bad storage subpath",
+			setup: func(t *testing.T, storagePath string) *config.Config {
+				os.MkdirAll(storagePath, 0755)
+				manifest.New().Save(storagePath)
+				cfg := config.New(storagePath)
+				cfg.StorageSubpath = "../other"
+				return cfg
+			},
+			want: []string{"failed", "ok", "ok", "ok", "ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagePath := filepath.Join(t.TempDir(), "storage")
+			checks := validateConfig(tt.setup(t, storagePath))
+			if len(checks) != len(tt.want) {
+				t.Fatalf("got %d checks, want %d", len(checks), len(tt.want))
+			}
+			for i, c := range checks {
+				got := "ok"
+				if c.skipped {
+					got = "skipped"
+				} else if c.err != nil {
+					got = "failed"
+				}
+				if got != tt.want[i] {
+					t.Errorf("check %q = %s (%v), want %s", c.name, got, c.err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestLoadConfig_Subpath tests that the configured subpath applies to every
+// path into storage
+func TestLoadConfig_Subpath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	storagePath := filepath.Join(home, "storage")
+	os.MkdirAll(storagePath, 0755)
+
+	cfg := config.New(storagePath)
+	cfg.StorageSubpath = `team\alice`
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	if loaded.StorageSubpath != "team/alice" {
+		t.Errorf("StorageSubpath = %q, want %q", loaded.StorageSubpath, "team/alice")
+	}
+	dotsyncDir := manifest.DotsyncDir(storagePath, loaded.StorageSubpath)
+	if want := filepath.Join(storagePath, "dotsync", "team", "alice", ".journal"); journal.Path(dotsyncDir) != want {
+		t.Errorf("journal.Path() = %q, want %q", journal.Path(dotsyncDir), want)
+	}
+
+	cfg.StorageSubpath = "../bob"
+	cfg.Save()
+	if _, _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() with an invalid subpath succeeded")
+	}
+}
+
+// TestLoadConfig_StorageOverride tests that --storage replaces the
+// configured storage path, with or without a config
+func TestLoadConfig_StorageOverride(t *testing.T) {
+	tests := []struct {
+		name        string
+		configured  bool   // save a config pointing at <home>/configured
+		override    string // --storage, relative to home or with ~
+		wantStorage string // relative to home
+		wantErr     error
+	}{
+		{name: "configured", configured: true, wantStorage: "configured"},
+		{name: "override wins over config", configured: true, override: "other", wantStorage: "other"},
+		{name: "override with ~", configured: true, override: "~/other", wantStorage: "other"},
+		{name: "override while uninitialized", override: "other", wantStorage: "other"},
+		{name: "uninitialized", wantErr: ErrNotInitialized},
+		{name: "override unavailable", configured: true, override: "missing", wantErr: ErrStorageUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			os.MkdirAll(filepath.Join(home, "configured"), 0755)
+			os.MkdirAll(filepath.Join(home, "other"), 0755)
+
+			if tt.configured {
+				cfg := config.New(filepath.Join(home, "configured"))
+				cfg.BackupToStorage = true
+				if err := cfg.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.override != "" {
+				storageOverride = tt.override
+				if !strings.HasPrefix(tt.override, "~") {
+					storageOverride = filepath.Join(home, tt.override)
+				}
+				t.Cleanup(func() { storageOverride = "" })
+			}
+
+			cfg, storagePath, err := loadConfig()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("loadConfig() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfig() failed: %v", err)
+			}
+			if want := filepath.Join(home, tt.wantStorage); storagePath != want {
+				t.Errorf("storagePath = %q, want %q", storagePath, want)
+			}
+			if cfg == nil || pathutil.ExpandHome(cfg.StoragePath) != storagePath {
+				t.Errorf("cfg = %+v, want storage path %q", cfg, storagePath)
+			}
+			// Other settings still come from the config
+			if cfg.BackupToStorage != tt.configured {
+				t.Errorf("BackupToStorage = %v, want %v", cfg.BackupToStorage, tt.configured)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <entry> [description]",
+	Short: "Show or set the description of an entry",
+	Long: `Show or set a short note on why an entry is tracked.
+
+Descriptions are shown by 'dotsync list'. With only an entry name, the
+current description is printed. An empty description ("") removes it.`,
+	Example: `  dotsync describe aerc "work email config"
+  dotsync describe aerc
+  dotsync describe aerc ""`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runDescribe,
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+}
+
+func runDescribe(cmd *cobra.Command, args []string) error {
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// 2. Load manifest
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		if strings.Contains(err.Error(), "manifest not found") {
+			return fmt.Errorf("no manifest found. Use 'dotsync add' to start tracking files")
+		}
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+
+	name := args[0]
+	entry := m.GetEntry(name)
+	if entry == nil {
+		return fmt.Errorf("entry '%s' not found", name)
+	}
+
+	// 3. Show the description
+	if len(args) == 1 {
+		if entry.Description == "" {
+			fmt.Printf("Entry '%s' has no description\n", name)
+		} else {
+			fmt.Println(entry.Description)
+		}
+		return nil
+	}
+
+	// 4. Set it
+	m.SetDescription(name, args[1])
+	if err := m.Save(storagePath); err != nil {
+		return fmt.Errorf("saving manifest: %w", err)
+	}
+
+	if m.GetEntry(name).Description == "" {
+		fmt.Printf("Removed description of '%s'\n", name)
+	} else {
+		fmt.Printf("Described '%s'\n", name)
+	}
+	return nil
+}
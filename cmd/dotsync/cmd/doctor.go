@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/backup"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose and repair problems with tracked files",
+	Long: `Check every tracked file and report problems that dotsync can fix:
+
+  repoint  symlinks pointing into an old storage location
+  relink   broken, incorrect or missing symlinks
+  prune    files missing from both cloud storage and this machine
+  backups  leftover backups from earlier operations
+
+Regular files where a symlink is expected are reported but not repaired,
+as they may hold newer edits. Use 'dotsync reattach' for those. Symlinks
+pointing outside dotsync storage are reported but left alone too, since
+another tool (like GNU Stow) probably manages them. Files
+that map to the same path as another tracked file are reported too; fix
+those by editing the manifest.
+Entries disabled with 'dotsync disable' are not checked.
+
+With --repair, all fixes are applied in the order above. Incorrect
+symlinks and pruning ask for confirmation. Use the --skip-* flags to
+leave individual kinds of problems alone.`,
+	Example: `  dotsync doctor                # Report problems
+  dotsync doctor --repair       # Fix everything
+  dotsync doctor --repair --skip-prune --skip-backups`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+var (
+	doctorRepair      bool
+	doctorSkipRepoint bool
+	doctorSkipRelink  bool
+	doctorSkipPrune   bool
+	doctorSkipBackups bool
+)
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorRepair, "repair", false, "Fix the problems found")
+	doctorCmd.Flags().BoolVar(&doctorSkipRepoint, "skip-repoint", false, "Don't repoint symlinks into an old storage location")
+	doctorCmd.Flags().BoolVar(&doctorSkipRelink, "skip-relink", false, "Don't recreate broken, incorrect or missing symlinks")
+	doctorCmd.Flags().BoolVar(&doctorSkipPrune, "skip-prune", false, "Don't stop tracking files missing everywhere")
+	doctorCmd.Flags().BoolVar(&doctorSkipBackups, "skip-backups", false, "Don't remove leftover backups")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorReport groups the problems found by diagnose by how they are fixed.
+type doctorReport struct {
+	repoint   []fileCheck // symlinks into an old storage location
+	relink    []fileCheck // broken, incorrect or missing symlinks
+	notLinked []fileCheck // regular files where a symlink is expected
+	external  []fileCheck // symlinks pointing outside dotsync storage
+	prune     []fileCheck // missing from storage and this machine
+	backups   []string    // leftover backup files
+	// duplicates are original paths tracked by several files
+	duplicates []manifest.DuplicateTarget
+}
+
+// empty returns true if no problems were found.
+func (r doctorReport) empty() bool {
+	return len(r.repoint) == 0 && len(r.relink) == 0 && len(r.notLinked) == 0 &&
+		len(r.external) == 0 && len(r.prune) == 0 && len(r.backups) == 0 && len(r.duplicates) == 0
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	warnInterruptedAdd(manifest.DotsyncDir(storagePath, cfg.StorageSubpath))
+
+	// 2. Load manifest
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		if strings.Contains(err.Error(), "manifest not found") {
+			if err := checkPopulated(storagePath); err != nil {
+				return err
+			}
+			fmt.Println("No entries tracked yet.")
+			return nil
+		}
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+
+	// 3. Diagnose
+	localDir, err := backup.BackupDir()
+	if err != nil {
+		return err
+	}
+	report, err := diagnose(m, storagePath, []string{localDir, backup.StorageBackupDir(m.DotsyncDir(storagePath))})
+	if err != nil {
+		return err
+	}
+
+	printDoctorReport(report)
+	if report.empty() {
+		return nil
+	}
+	if !doctorRepair {
+		fmt.Println("\nRun 'dotsync doctor --repair' to fix these.")
+		return nil
+	}
+
+	// 4. Repair
+	var failed int
+	if !doctorSkipRepoint && len(report.repoint) > 0 {
+		fmt.Println("\nRepointing symlinks:")
+		for _, c := range report.repoint {
+			if _, err := repointFile(c.originalPath, c.cloudPath, storageRelPath(m, c.name, c.relPath)); err != nil {
+				fmt.Printf("  [failed]  %s/%s: %v\n", c.name, c.relPath, err)
+				failed++
+				continue
+			}
+			fmt.Printf("  [repointed] %s/%s\n", c.name, c.relPath)
+		}
+	}
+
+	if !doctorSkipRelink && len(report.relink) > 0 {
+		fmt.Println("\nRelinking:")
+		opts := linkOptions{backupDir: backupDirFor(cfg, storagePath)}
+		for _, c := range report.relink {
+			opts.parentMode, _ = m.Entries[c.name].ParentMode(c.relPath)
+			result, err := linkFile(c.originalPath, c.cloudPath, opts)
+			switch result {
+			case linkResultLinked, linkResultAlreadyLinked:
+				fmt.Printf("  [linked]  %s/%s\n", c.name, c.relPath)
+			case linkResultSkipped:
+				fmt.Printf("  [skipped] %s/%s\n", c.name, c.relPath)
+			case linkResultAborted:
+				return ErrAborted
+			case linkResultFailed:
+				err = unsupportedHint(err, "Run 'dotsync link --as-copy-if-symlink-unsupported'")
+				fmt.Printf("  [failed]  %s/%s: %v\n", c.name, c.relPath, err)
+				failed++
+			}
+		}
+	}
+
+	if !doctorSkipPrune && len(report.prune) > 0 {
+		if err := pruneFiles(m, storagePath, report.prune); err != nil {
+			return err
+		}
+	}
+
+	if !doctorSkipBackups && len(report.backups) > 0 {
+		fmt.Println("\nLeftover backups:")
+		if err := removeBackups(report.backups, false); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return markAs(ErrPartialFailure, fmt.Errorf("some files could not be repaired"))
+	}
+	return nil
+}
+
+// diagnose checks every file in m and the backups in backupDirs.
+func diagnose(m *manifest.Manifest, storagePath string, backupDirs []string) (doctorReport, error) {
+	var report doctorReport
+	report.duplicates = m.DuplicateTargets()
+	duplicates := duplicateFiles(m)
+	dotsyncDir := m.DotsyncDir(storagePath)
+
+	names := m.Names()
+
+	for _, name := range names {
+		entry := m.Entries[name]
+		if !entry.Enabled() {
+			// Not linked on purpose, nothing to repair
+			continue
+		}
+		for _, c := range checkEntry(name, entry, dotsyncDir) {
+			if c.mode == manifest.ModeCopy || c.status == symlink.StatusLinked {
+				continue
+			}
+			if duplicates[manifest.FileRef{Entry: c.name, RelPath: c.relPath}] {
+				// Relinking would flip between the files
+				continue
+			}
+			if c.cloudMissing {
+				// Nothing to link to; only prunable if nothing is left locally
+				if c.status == symlink.StatusNotExist || c.status == symlink.StatusParentMissing {
+					report.prune = append(report.prune, c)
+				}
+				continue
+			}
+
+			switch c.status {
+			case symlink.StatusNotLinked:
+				report.notLinked = append(report.notLinked, c)
+			case symlink.StatusBroken, symlink.StatusIncorrect:
+				target, err := symlink.ReadTarget(c.originalPath)
+				if err == nil && isStorageTarget(target, storageRelPath(m, c.name, c.relPath)) {
+					report.repoint = append(report.repoint, c)
+				} else if within, err := symlink.TargetWithin(c.originalPath, dotsyncDir); err == nil && !within {
+					report.external = append(report.external, c)
+				} else {
+					report.relink = append(report.relink, c)
+				}
+			case symlink.StatusNotExist, symlink.StatusParentMissing:
+				report.relink = append(report.relink, c)
+			}
+		}
+	}
+
+	backups, err := findBackups(backupDirs)
+	if err != nil {
+		return doctorReport{}, err
+	}
+	report.backups = backups
+
+	return report, nil
+}
+
+// printDoctorReport prints the problems found by diagnose.
+func printDoctorReport(r doctorReport) {
+	if r.empty() {
+		fmt.Println("No problems found.")
+		return
+	}
+
+	for _, c := range r.repoint {
+		fmt.Printf("  [repoint] %s/%s (points into an old storage location)\n", c.name, c.relPath)
+	}
+	for _, c := range r.relink {
+		fmt.Printf("  [relink]  %s/%s (%s)\n", c.name, c.relPath, c.status)
+	}
+	for _, c := range r.notLinked {
+		fmt.Printf("  [manual]  %s/%s (regular file, use 'dotsync reattach %s')\n", c.name, c.relPath, pathutil.ContractHome(c.originalPath))
+	}
+	for _, c := range r.external {
+		target, _ := symlink.ReadTarget(c.originalPath)
+		fmt.Printf("  [manual]  %s/%s (symlink to %s, outside dotsync storage; another tool may manage it)\n", c.name, c.relPath, pathutil.ContractHome(target))
+	}
+	for _, c := range r.prune {
+		fmt.Printf("  [prune]   %s/%s (missing from storage and this machine)\n", c.name, c.relPath)
+	}
+	for _, d := range r.duplicates {
+		refs := make([]string, 0, len(d.Files))
+		for _, f := range d.Files {
+			refs = append(refs, f.String())
+		}
+		fmt.Printf("  [manual]  %s is tracked as %s (edit the manifest)\n", d.Path, strings.Join(refs, ", "))
+	}
+	if len(r.backups) > 0 {
+		fmt.Printf("  [backups] %d leftover backup(s)\n", len(r.backups))
+	}
+}
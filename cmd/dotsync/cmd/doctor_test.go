@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+// setupDoctor tracks one file per kind of problem under a temporary home.
+// Returns the storage path and the manifest.
+func setupDoctor(t *testing.T) (string, *manifest.Manifest) {
+	t.Helper()
+	home, _, _ := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+
+	cloud := func(relPath string) string {
+		path := filepath.Join(storagePath, "dotsync", "app", relPath)
+		os.WriteFile(path, []byte(relPath), 0644)
+		return path
+	}
+	local := filepath.Join(home, ".config", "app")
+
+	// Symlink into an old storage location
+	cloud("moved.json")
+	os.Symlink(filepath.Join(home, "old", "dotsync", "app", "moved.json"), filepath.Join(local, "moved.json"))
+	m.AddFile("app", "~/.config/app", "moved.json")
+
+	// Missing symlink
+	cloud("unlinked.json")
+	m.AddFile("app", "~/.config/app", "unlinked.json")
+
+	// Regular file in place of the symlink
+	cloud("replaced.json")
+	os.WriteFile(filepath.Join(local, "replaced.json"), []byte("edited"), 0644)
+	m.AddFile("app", "~/.config/app", "replaced.json")
+
+	// Symlink managed by another tool
+	cloud("stowed.json")
+	stowed := filepath.Join(home, "dotfiles", "app", "stowed.json")
+	os.MkdirAll(filepath.Dir(stowed), 0755)
+	os.WriteFile(stowed, []byte("stow"), 0644)
+	os.Symlink(stowed, filepath.Join(local, "stowed.json"))
+	m.AddFile("app", "~/.config/app", "stowed.json")
+
+	// Gone everywhere
+	m.AddFile("app", "~/.config/app", "gone.json")
+
+	if err := m.Save(storagePath); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+	return storagePath, m
+}
+
+// TestDiagnose tests that each problem is put in the right category
+func TestDiagnose(t *testing.T) {
+	storagePath, m := setupDoctor(t)
+
+	backupDir := t.TempDir()
+	os.WriteFile(filepath.Join(backupDir, "config.json.20260101-000000.bak"), []byte("x"), 0644)
+
+	report, err := diagnose(m, storagePath, []string{backupDir})
+	if err != nil {
+		t.Fatalf("diagnose() failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		checks []fileCheck
+		want   []string
+	}{
+		{"repoint", report.repoint, []string{"moved.json"}},
+		{"relink", report.relink, []string{"unlinked.json"}},
+		{"notLinked", report.notLinked, []string{"replaced.json"}},
+		{"external", report.external, []string{"stowed.json"}},
+		{"prune", report.prune, []string{"gone.json"}},
+	}
+	for _, tt := range tests {
+		if len(tt.checks) != len(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.checks, tt.want)
+			continue
+		}
+		for i, c := range tt.checks {
+			if c.relPath != tt.want[i] {
+				t.Errorf("%s[%d] = %q, want %q", tt.name, i, c.relPath, tt.want[i])
+			}
+		}
+	}
+	if len(report.backups) != 1 {
+		t.Errorf("backups = %v, want 1 backup", report.backups)
+	}
+}
+
+// TestRunDoctor_Repair tests repointing and relinking, with pruning and
+// backups skipped
+func TestRunDoctor_Repair(t *testing.T) {
+	storagePath, m := setupDoctor(t)
+
+	doctorRepair, doctorSkipPrune, doctorSkipBackups = true, true, true
+	defer func() { doctorRepair, doctorSkipPrune, doctorSkipBackups = false, false, false }()
+
+	if err := runDoctor(doctorCmd, nil); err != nil {
+		t.Fatalf("runDoctor() failed: %v", err)
+	}
+
+	entry := m.GetEntry("app")
+	for _, relPath := range []string{"moved.json", "unlinked.json"} {
+		originalPath := filepath.Join(os.Getenv("HOME"), ".config", "app", relPath)
+		target, err := os.Readlink(originalPath)
+		if err != nil {
+			t.Errorf("%s: not a symlink: %v", relPath, err)
+			continue
+		}
+		if want := filepath.Join(storagePath, "dotsync", "app", relPath); target != want {
+			t.Errorf("%s: target = %q, want %q", relPath, target, want)
+		}
+	}
+
+	// Untouched: the replaced file, the externally managed symlink and the
+	// pruned file
+	data, _ := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".config", "app", "replaced.json"))
+	if string(data) != "edited" {
+		t.Errorf("replaced.json content = %q, want %q", data, "edited")
+	}
+	if data, _ := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".config", "app", "stowed.json")); string(data) != "stow" {
+		t.Errorf("stowed.json content = %q, want %q", data, "stow")
+	}
+	loaded, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if got := len(loaded.GetEntry("app").Files); got != len(entry.Files) {
+		t.Errorf("manifest has %d files, want %d", got, len(entry.Files))
+	}
+}
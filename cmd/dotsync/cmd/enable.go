@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+var disableCmd = &cobra.Command{
+	Use:   "disable <entry>...",
+	Short: "Stop linking entries without untracking them",
+	Long: `Mark entries as disabled. Disabled entries stay in the manifest and in
+storage, but 'dotsync link' and 'dotsync doctor' skip them, and
+'dotsync list' shows them as [disabled].
+
+Existing symlinks are left in place; use 'dotsync unlink <entry>' to
+restore the files as plain files. Use 'dotsync enable' to link the
+entry again.`,
+	Example: `  dotsync disable work-secrets
+  dotsync disable cursor opencode`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setEntriesEnabled(args, false)
+	},
+}
+
+var enableCmd = &cobra.Command{
+	Use:   "enable <entry>...",
+	Short: "Link disabled entries again",
+	Long: `Re-enable entries disabled with 'dotsync disable', so 'dotsync link'
+links them again. Run 'dotsync link <entry>' afterwards to create the
+symlinks.`,
+	Example: `  dotsync enable work-secrets`,
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setEntriesEnabled(args, true)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(disableCmd)
+	rootCmd.AddCommand(enableCmd)
+}
+
+// setEntriesEnabled enables or disables the named entries and saves the
+// manifest. Nothing is changed if any of the entries doesn't exist.
+func setEntriesEnabled(names []string, enabled bool) error {
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// 2. Load manifest
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		if strings.Contains(err.Error(), "manifest not found") {
+			return fmt.Errorf("no manifest found. Use 'dotsync add' to start tracking files")
+		}
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+
+	for _, name := range names {
+		if !m.HasEntry(name) {
+			return fmt.Errorf("entry '%s' not found", name)
+		}
+	}
+
+	// 3. Update and save
+	var changed []string
+	for _, name := range names {
+		if m.GetEntry(name).Enabled() == enabled {
+			continue
+		}
+		m.SetEnabled(name, enabled)
+		changed = append(changed, name)
+	}
+
+	if len(changed) > 0 {
+		if err := m.Save(storagePath); err != nil {
+			return fmt.Errorf("saving manifest: %w", err)
+		}
+	}
+
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	for _, name := range names {
+		fmt.Printf("Entry '%s' is %s\n", name, state)
+	}
+	switch {
+	case len(changed) == 0:
+	case enabled:
+		fmt.Println("\nRun 'dotsync link' to link the enabled entries.")
+	default:
+		fmt.Println("\nExisting symlinks were left in place. Run 'dotsync unlink <entry>' to restore plain files.")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// TestDisable_SkipsLink tests that link leaves disabled entries alone until
+// they are enabled again
+func TestDisable_SkipsLink(t *testing.T) {
+	home, originalPath, cloudPath := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	os.Remove(originalPath)
+
+	if err := setEntriesEnabled([]string{"app"}, false); err != nil {
+		t.Fatalf("disable failed: %v", err)
+	}
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.GetEntry("app").Enabled() {
+		t.Fatal("entry still enabled after disable")
+	}
+
+	if err := runLink(linkCmd, nil); err != nil {
+		t.Fatalf("runLink() failed: %v", err)
+	}
+	if _, err := os.Lstat(originalPath); !os.IsNotExist(err) {
+		t.Errorf("disabled entry was linked (err: %v)", err)
+	}
+
+	if err := setEntriesEnabled([]string{"app"}, true); err != nil {
+		t.Fatalf("enable failed: %v", err)
+	}
+	if err := runLink(linkCmd, nil); err != nil {
+		t.Fatalf("runLink() failed: %v", err)
+	}
+	if status, _, _ := symlink.Check(originalPath, cloudPath); status != symlink.StatusLinked {
+		t.Errorf("status = %v, want %v", status, symlink.StatusLinked)
+	}
+
+	if err := setEntriesEnabled([]string{"app", "missing"}, false); err == nil {
+		t.Error("disable with an unknown entry succeeded, want error")
+	}
+	m, _ = manifest.Load(storagePath, "")
+	if !m.GetEntry("app").Enabled() {
+		t.Error("entry disabled although another name was unknown")
+	}
+}
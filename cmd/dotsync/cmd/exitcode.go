@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+)
+
+// Exit codes returned by the dotsync binary.
+const (
+	ExitOK                 = 0   // Success
+	ExitError              = 1   // Any other error
+	ExitNotInitialized     = 2   // dotsync init hasn't been run
+	ExitStorageUnavailable = 3   // Cloud storage isn't mounted/syncing
+	ExitPartialFailure     = 4   // Some files failed, others succeeded
+	ExitAborted            = 5   // Conflict, or aborted by the user
+	ExitLinksBroken        = 6   // list --exit-code: broken or incorrect symlinks
+	ExitCloudMissing       = 7   // list --exit-code: missing cloud files or untracked files in storage
+	ExitInterrupted        = 130 // Stopped by Ctrl-C (128 + SIGINT)
+)
+
+// Error kinds that map to exit codes. Use errors.Is to check for them.
+var (
+	ErrNotInitialized     = errors.New("dotsync not initialized. Run 'dotsync init <provider>' first")
+	ErrStorageUnavailable = errors.New("storage unavailable")
+	ErrPartialFailure     = errors.New("partial failure")
+	ErrConflict           = errors.New("conflict")
+	ErrAborted            = errors.New("aborted")
+	ErrLinksBroken        = errors.New("broken or incorrect symlinks")
+	ErrCloudMissing       = errors.New("missing or untracked cloud files")
+	ErrInterrupted        = errors.New("interrupted")
+)
+
+// errReported marks an error whose details the command already printed,
+// like a failed list probe. It keeps its exit code but isn't printed again.
+var errReported = errors.New("already reported")
+
+// kindError tags an error with an error kind without changing its message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// markAs tags err with an error kind so ExitCode can classify it.
+func markAs(kind, err error) error {
+	return kindError{kind: kind, err: err}
+}
+
+// Reported reports whether err was already printed by the command, so the
+// caller of Execute should only exit with its code.
+func Reported(err error) bool {
+	return errors.Is(err, errReported)
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrNotInitialized):
+		return ExitNotInitialized
+	case errors.Is(err, ErrStorageUnavailable):
+		return ExitStorageUnavailable
+	case errors.Is(err, ErrPartialFailure):
+		return ExitPartialFailure
+	case errors.Is(err, ErrConflict), errors.Is(err, ErrAborted):
+		return ExitAborted
+	case errors.Is(err, ErrCloudMissing):
+		return ExitCloudMissing
+	case errors.Is(err, ErrLinksBroken):
+		return ExitLinksBroken
+	case errors.Is(err, ErrInterrupted):
+		return ExitInterrupted
+	default:
+		return ExitError
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitOK},
+		{"generic error", errors.New("boom"), ExitError},
+		{"not initialized", ErrNotInitialized, ExitNotInitialized},
+		{"storage unavailable", markAs(ErrStorageUnavailable, fmt.Errorf("storage unavailable: /mnt")), ExitStorageUnavailable},
+		{"partial failure", markAs(ErrPartialFailure, fmt.Errorf("some files failed to link")), ExitPartialFailure},
+		{"conflict", markAs(ErrConflict, fmt.Errorf("entry exists")), ExitAborted},
+		{"aborted", ErrAborted, ExitAborted},
+		{"links broken", markAs(ErrLinksBroken, fmt.Errorf("1 broken")), ExitLinksBroken},
+		{"cloud missing", markAs(ErrCloudMissing, fmt.Errorf("1 missing")), ExitCloudMissing},
+		{"interrupted", ErrInterrupted, ExitInterrupted},
+		{"wrapped", fmt.Errorf("context: %w", ErrNotInitialized), ExitNotInitialized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkAs_KeepsMessage(t *testing.T) {
+	err := markAs(ErrConflict, fmt.Errorf("file already exists"))
+	if err.Error() != "file already exists" {
+		t.Errorf("Error() = %q, want %q", err.Error(), "file already exists")
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+var fixPermsCmd = &cobra.Command{
+	Use:   "fix-permissions",
+	Short: "Apply safe modes to sensitive tracked files",
+	Long: `Set known-correct permissions on sensitive tracked files, whatever the
+cloud provider left them as. ssh and gpg refuse to work with keys that
+other users can read.
+
+Built-in rules:
+  ~/.ssh/*.pub, ~/.ssh/known_hosts   0644
+  ~/.ssh/*, ~/.gnupg/*               0600
+  ~/.aws/credentials, ~/.netrc, ~/.pgpass  0600
+and private directories (~/.ssh, ~/.gnupg) get 0700.
+
+The cloud copy is fixed, plus the local copy for copy-mode files. Add
+rules in the config, checked before the built-in ones:
+  "permissions": [{"pattern": ".ssh/config", "mode": "0644"}]
+
+Use --dry-run to see what would change.`,
+	Example: `  dotsync fix-permissions
+  dotsync fix-permissions --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runFixPerms,
+}
+
+var fixPermsDryRun bool
+
+func init() {
+	fixPermsCmd.Flags().BoolVar(&fixPermsDryRun, "dry-run", false, "Show what would change without changing anything")
+	rootCmd.AddCommand(fixPermsCmd)
+}
+
+func runFixPerms(cmd *cobra.Command, args []string) error {
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	rules, err := permRules(cfg)
+	if err != nil {
+		return err
+	}
+
+	// 2. Load manifest
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		if strings.Contains(err.Error(), "manifest not found") {
+			return fmt.Errorf("no manifest found. Nothing to fix")
+		}
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+
+	home, err := pathutil.HomeDir()
+	if err != nil {
+		return err
+	}
+
+	// 3. Fix each sensitive file, and the private directory around it
+	fixed, failed := fixPermissions(m, storagePath, home, rules, fixPermsDryRun)
+
+	fmt.Println()
+	switch {
+	case fixed == 0 && failed == 0:
+		fmt.Println("All permissions are correct.")
+	case fixPermsDryRun:
+		fmt.Printf("%d path(s) would be changed\n", fixed)
+	default:
+		fmt.Printf("Summary: %d fixed, %d failed\n", fixed, failed)
+	}
+
+	if failed > 0 {
+		return markAs(ErrPartialFailure, fmt.Errorf("some permissions could not be fixed"))
+	}
+	return nil
+}
+
+// permRules returns the config's permission rules followed by the built-in ones.
+func permRules(cfg *config.Config) ([]symlink.PermRule, error) {
+	rules := make([]symlink.PermRule, 0, len(cfg.Permissions)+len(symlink.DefaultPermRules))
+	for _, r := range cfg.Permissions {
+		mode, err := strconv.ParseUint(r.Mode, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, fmt.Errorf("invalid mode %q for permissions pattern %q in config (use octal, e.g. \"0600\")", r.Mode, r.Pattern)
+		}
+		rules = append(rules, symlink.PermRule{Pattern: r.Pattern, Mode: os.FileMode(mode)})
+	}
+	return append(rules, symlink.DefaultPermRules...), nil
+}
+
+// fixPermissions applies rules to the tracked files of m, in entry order.
+// Returns how many paths were (or, with dryRun, would be) changed and how
+// many failed.
+func fixPermissions(m *manifest.Manifest, storagePath, home string, rules []symlink.PermRule, dryRun bool) (fixed, failed int) {
+	names := m.Names()
+
+	fix := func(label, path string, want os.FileMode) {
+		old, changed, err := fixMode(path, want, dryRun)
+		switch {
+		case err != nil:
+			fmt.Printf("  [failed] %s: %v\n", label, err)
+			failed++
+		case changed:
+			fmt.Printf("  [chmod]  %s (%04o -> %04o)\n", label, old, want)
+			fixed++
+		}
+	}
+
+	seenDirs := make(map[string]bool)
+	for _, name := range names {
+		entry := m.Entries[name]
+		entryRoot := pathutil.ExpandHome(entry.Root)
+		for _, relPath := range entry.Files {
+			originalPath := filepath.Join(entryRoot, manifest.FromStorageSlash(relPath))
+			homeRel, err := pathutil.SafeRel(home, originalPath)
+			if err != nil {
+				continue
+			}
+			want, ok := symlink.WantedMode(homeRel, rules)
+			if !ok {
+				continue
+			}
+
+			label := name + "/" + relPath
+			if cloudPath := m.CloudPath(storagePath, name, relPath); !cloudMissing(cloudPath) {
+				fix(label, cloudPath, want)
+			}
+			if entry.FileMode(relPath) == manifest.ModeCopy {
+				if info, err := os.Lstat(originalPath); err == nil && info.Mode().IsRegular() {
+					fix(pathutil.ContractHome(originalPath), originalPath, want)
+				}
+			}
+
+			if dir := symlink.PrivateDir(originalPath); dir != "" && !seenDirs[dir] {
+				seenDirs[dir] = true
+				// A symlinked ~/.ssh is the user's own setup, leave it alone
+				if info, err := os.Lstat(dir); err == nil && info.IsDir() {
+					fix(pathutil.ContractHome(dir), dir, symlink.PrivateDirMode)
+				}
+			}
+		}
+	}
+	return fixed, failed
+}
+
+// fixMode sets the permission bits of path to want. Returns the previous
+// bits and whether they differed; with dryRun nothing is changed.
+func fixMode(path string, want os.FileMode, dryRun bool) (os.FileMode, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false, err
+	}
+	old := info.Mode().Perm()
+	if old == want {
+		return old, false, nil
+	}
+	if dryRun {
+		return old, true, nil
+	}
+	if err := os.Chmod(path, want); err != nil {
+		return old, false, err
+	}
+	return old, true, nil
+}
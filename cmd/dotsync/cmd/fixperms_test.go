@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// TestFixPermissions tests applying modes to sensitive cloud files and
+// their private directory
+func TestFixPermissions(t *testing.T) {
+	home := t.TempDir()
+	storagePath := filepath.Join(home, "storage")
+	sshDir := filepath.Join(home, ".ssh")
+	cloudDir := filepath.Join(storagePath, "dotsync", "ssh")
+	os.MkdirAll(sshDir, 0755)
+	os.MkdirAll(cloudDir, 0755)
+
+	m := manifest.New()
+	for _, name := range []string{"id_ed25519", "id_ed25519.pub", "config"} {
+		cloudPath := filepath.Join(cloudDir, name)
+		if err := os.WriteFile(cloudPath, []byte("key"), 0644); err != nil {
+			t.Fatalf("failed to create cloud file: %v", err)
+		}
+		if err := os.Symlink(cloudPath, filepath.Join(sshDir, name)); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+		m.AddFile("ssh", sshDir, name)
+	}
+
+	cfg := config.New(storagePath)
+	cfg.Permissions = []config.PermissionRule{{Pattern: ".ssh/config", Mode: "0640"}}
+	rules, err := permRules(cfg)
+	if err != nil {
+		t.Fatalf("permRules() failed: %v", err)
+	}
+
+	// Dry run changes nothing
+	fixed, failed := fixPermissions(m, storagePath, home, rules, true)
+	if fixed != 3 || failed != 0 {
+		t.Errorf("dry run = %d fixed, %d failed, want 3, 0", fixed, failed)
+	}
+	if info, _ := os.Stat(filepath.Join(cloudDir, "id_ed25519")); info.Mode().Perm() != 0644 {
+		t.Errorf("dry run changed mode to %04o", info.Mode().Perm())
+	}
+
+	fixed, failed = fixPermissions(m, storagePath, home, rules, false)
+	if fixed != 3 || failed != 0 {
+		t.Errorf("fixPermissions() = %d fixed, %d failed, want 3, 0", fixed, failed)
+	}
+	want := map[string]os.FileMode{
+		filepath.Join(cloudDir, "id_ed25519"):     0600,
+		filepath.Join(cloudDir, "id_ed25519.pub"): 0644,
+		filepath.Join(cloudDir, "config"):         0640,
+		sshDir:                                    symlink.PrivateDirMode,
+	}
+	for path, mode := range want {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat %s: %v", path, err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("mode of %s = %04o, want %04o", path, info.Mode().Perm(), mode)
+		}
+	}
+
+	// Everything is correct now
+	if fixed, _ := fixPermissions(m, storagePath, home, rules, false); fixed != 0 {
+		t.Errorf("second run fixed %d, want 0", fixed)
+	}
+
+	// Invalid override modes are rejected
+	cfg.Permissions = []config.PermissionRule{{Pattern: ".netrc", Mode: "rw"}}
+	if _, err := permRules(cfg); err == nil {
+		t.Error("permRules() should reject an invalid mode")
+	}
+}
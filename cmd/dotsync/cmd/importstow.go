@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+var importStowCmd = &cobra.Command{
+	Use:   "import-from-stow <stow-dir> [package...]",
+	Short: "Import the packages of a GNU Stow directory",
+	Long: `Import a GNU Stow dotfiles directory, where each top-level directory is a
+package mirroring your home layout (e.g. ~/dotfiles/zsh/.zshrc).
+
+Each package becomes an entry of the same name, rooted at the deepest
+directory holding all of its files: ~/dotfiles/nvim/.config/nvim/init.lua
+becomes entry "nvim" rooted at ~/.config/nvim. Files stow ignores by
+default (VCS metadata, editor backups, README and LICENSE at the top of a
+package) are skipped. Pass package names to import only those.
+
+Files are copied into cloud storage and added to the manifest. Use --move
+to move them out of the stow directory instead.
+
+Use --link to replace the symlinks stow created with dotsync symlinks in
+the same step. Directories stow folded into a single symlink are unfolded
+first. Without --link, run 'dotsync link' afterwards.
+
+Use --dotfiles if you stow with --dotfiles, so "dot-zshrc" is imported
+as ".zshrc". Use --dry-run to preview the entries without changing
+anything.`,
+	Example: `  dotsync import-from-stow ~/dotfiles --dry-run
+  dotsync import-from-stow ~/dotfiles --link
+  dotsync import-from-stow ~/dotfiles zsh nvim --move --link`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runImportStow,
+}
+
+var (
+	importStowDryRun   bool
+	importStowMove     bool
+	importStowLink     bool
+	importStowDotfiles bool
+)
+
+func init() {
+	importStowCmd.Flags().BoolVar(&importStowDryRun, "dry-run", false, "Show the entries that would be created without changing anything")
+	importStowCmd.Flags().BoolVar(&importStowMove, "move", false, "Move files out of the stow directory instead of copying them")
+	importStowCmd.Flags().BoolVar(&importStowLink, "link", false, "Replace stow's symlinks with dotsync symlinks")
+	importStowCmd.Flags().BoolVar(&importStowDotfiles, "dotfiles", false, `Turn "dot-" prefixes into "." like stow --dotfiles`)
+	rootCmd.AddCommand(importStowCmd)
+}
+
+func runImportStow(cmd *cobra.Command, args []string) error {
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	stowDir, err := pathutil.AbsolutePath(args[0])
+	if err != nil {
+		return fmt.Errorf("resolving path: %w", err)
+	}
+	for _, pkg := range args[1:] {
+		if err := validateEntryName(pkg); err != nil {
+			return fmt.Errorf("invalid package %q: %w", pkg, err)
+		}
+	}
+
+	// 2. Scan the stow packages
+	found, err := pathutil.ScanStow(stowDir, args[1:], importStowDotfiles)
+	if err != nil {
+		return err
+	}
+
+	// 3. Load manifest
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		if strings.Contains(err.Error(), "manifest not found") {
+			m = manifest.New()
+			m.Subpath = cfg.StorageSubpath
+		} else {
+			return fmt.Errorf("loading manifest: %w", err)
+		}
+	}
+
+	// 4. Filter out files that can't be imported, grouped by entry
+	var toImport []pathutil.ScanResult
+	byEntry := make(map[string][]pathutil.ScanResult)
+	for _, r := range found {
+		label := pathutil.ContractHome(r.SourcePath)
+		if err := validateEntryName(r.Name); err != nil {
+			fmt.Printf("  [skipped] %s (package name: %v)\n", label, err)
+			continue
+		}
+		if existing := m.GetEntry(r.Name); existing != nil && manifest.NormalizeRoot(existing.Root) != manifest.NormalizeRoot(r.Root) {
+			fmt.Printf("  [skipped] %s (entry '%s' exists with root %s)\n", label, r.Name, existing.Root)
+			continue
+		}
+		originalPath := filepath.Join(pathutil.ExpandHome(r.Root), manifest.FromStorageSlash(r.RelPath))
+		if entryName, trackedPath := pathutil.CheckNesting(originalPath, m); entryName != "" {
+			fmt.Printf("  [skipped] %s (overlaps %s, already tracked in entry '%s')\n", label, pathutil.ContractHome(trackedPath), entryName)
+			continue
+		}
+		conflict, err := pathutil.CheckEntryConflict(originalPath, r.Name, m)
+		if err != nil {
+			return fmt.Errorf("checking conflicts: %w", err)
+		}
+		if conflict != "" && conflict != r.Name {
+			fmt.Printf("  [skipped] %s (%s is under entry '%s')\n", label, pathutil.ContractHome(originalPath), conflict)
+			continue
+		}
+		tree, err := subpathCollision(m, storagePath, r.Name)
+		if err != nil {
+			return err
+		}
+		if tree != "" {
+			fmt.Printf("  [skipped] %s (entry '%s' collides with storage subpath '%s')\n", label, r.Name, tree)
+			continue
+		}
+		if _, err := os.Stat(m.CloudPath(storagePath, r.Name, r.RelPath)); err == nil {
+			fmt.Printf("  [skipped] %s (already in cloud storage)\n", label)
+			continue
+		}
+		toImport = append(toImport, r)
+		byEntry[r.Name] = append(byEntry[r.Name], r)
+	}
+
+	if len(toImport) == 0 {
+		fmt.Printf("No files to import from %s\n", pathutil.ContractHome(stowDir))
+		return nil
+	}
+
+	names := make([]string, 0, len(byEntry))
+	for name := range byEntry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("\nStow packages in %s:\n", pathutil.ContractHome(stowDir))
+	for _, name := range names {
+		results := byEntry[name]
+		fmt.Printf("  %s (%s)\n", name, results[0].Root)
+		for _, r := range results {
+			fmt.Printf("    %s\n", r.RelPath)
+		}
+	}
+
+	if importStowDryRun {
+		fmt.Printf("\n%d file(s) in %d entr(ies) would be imported\n", len(toImport), len(names))
+		return nil
+	}
+
+	verb := "Copy"
+	if importStowMove {
+		verb = "Move"
+	}
+	if !confirmPrompt(fmt.Sprintf("%s %d file(s) into %d entr(ies)?", verb, len(toImport), len(names))) {
+		return ErrAborted
+	}
+
+	// 5. Import each file
+	var imported, failed int
+	var done []pathutil.ScanResult
+	for _, r := range toImport {
+		cloudPath := m.CloudPath(storagePath, r.Name, r.RelPath)
+		if importStowMove {
+			err = symlink.MoveFile(r.SourcePath, cloudPath)
+		} else {
+			err = symlink.CopyFile(r.SourcePath, cloudPath)
+		}
+		if err != nil {
+			fmt.Printf("  [failed]  %s: %v\n", pathutil.ContractHome(r.SourcePath), err)
+			failed++
+			continue
+		}
+		m.AddFile(r.Name, r.Root, r.RelPath)
+		done = append(done, r)
+		imported++
+	}
+
+	if err := m.Save(storagePath); err != nil {
+		return fmt.Errorf("saving manifest: %w", err)
+	}
+
+	// 6. Replace stow's symlinks
+	var linked, kept int
+	if importStowLink {
+		fmt.Println()
+		for _, r := range done {
+			originalPath := filepath.Join(pathutil.ExpandHome(r.Root), manifest.FromStorageSlash(r.RelPath))
+			ok, err := relinkFromStow(originalPath, m.CloudPath(storagePath, r.Name, r.RelPath), stowDir)
+			label := pathutil.ContractHome(originalPath)
+			switch {
+			case err != nil:
+				fmt.Printf("  [failed]  %s: %v\n", label, err)
+				failed++
+			case ok:
+				fmt.Printf("  [linked]  %s\n", label)
+				linked++
+			default:
+				fmt.Printf("  [kept]    %s (not a stow symlink, use 'dotsync link')\n", label)
+				kept++
+			}
+		}
+	}
+
+	if importStowLink {
+		fmt.Printf("\nSummary: %d imported, %d linked, %d failed\n", imported, linked, failed)
+	} else {
+		fmt.Printf("\nSummary: %d imported, %d failed\n", imported, failed)
+	}
+	switch {
+	case importStowLink && kept > 0:
+		fmt.Printf("%d file(s) were left in place. Run 'dotsync link --backup' to replace them.\n", kept)
+	case !importStowLink && importStowMove:
+		fmt.Println("Stow's symlinks now point to moved files. Run 'dotsync link' to replace them.")
+	case !importStowLink:
+		fmt.Println("Run 'dotsync link' to replace stow's symlinks with dotsync ones.")
+	}
+
+	if failed > 0 {
+		return markAs(ErrPartialFailure, fmt.Errorf("some files failed to import"))
+	}
+	return nil
+}
+
+// relinkFromStow points originalPath at cloudPath if it is missing or a
+// symlink into stowDir, unfolding stow directory symlinks above it first.
+// Returns false if something else is in the way, which is left alone.
+func relinkFromStow(originalPath, cloudPath, stowDir string) (bool, error) {
+	if err := unfoldStowDirs(originalPath, stowDir); err != nil {
+		return false, err
+	}
+
+	info, err := os.Lstat(originalPath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return false, err
+	case info.Mode()&os.ModeSymlink == 0:
+		return false, nil
+	default:
+		if within, err := symlink.TargetWithin(originalPath, stowDir); err != nil || !within {
+			return false, err
+		}
+		if err := symlink.Remove(originalPath); err != nil {
+			return false, err
+		}
+	}
+
+	if err := symlink.Create(originalPath, cloudPath); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// unfoldStowDirs replaces parent directories of path that stow folded into
+// a single symlink into stowDir with real directories, holding a symlink
+// for each child like stow does when unfolding, so the files inside can be
+// linked one by one.
+func unfoldStowDirs(path, stowDir string) error {
+	home, err := pathutil.HomeDir()
+	if err != nil {
+		return err
+	}
+	rel, err := pathutil.SafeRel(home, filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+
+	dir := home
+	for _, part := range strings.Split(rel, "/") {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if err != nil {
+			// Missing directories are created when linking
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if within, err := symlink.TargetWithin(dir, stowDir); err != nil || !within {
+			continue
+		}
+
+		target, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", dir, err)
+		}
+		children, err := os.ReadDir(target)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", target, err)
+		}
+		if err := symlink.Remove(dir); err != nil {
+			return err
+		}
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return fmt.Errorf("unfolding %s: %w", dir, err)
+		}
+		for _, c := range children {
+			if err := symlink.Create(filepath.Join(dir, c.Name()), filepath.Join(target, c.Name())); err != nil {
+				return fmt.Errorf("unfolding %s: %w", dir, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// TestRunImportStow_Link tests importing stow packages and replacing the
+// stow symlinks, including a directory stow folded into one symlink
+func TestRunImportStow_Link(t *testing.T) {
+	home, _, _ := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	stowDir := filepath.Join(home, "dotfiles")
+
+	for _, f := range []string{
+		filepath.Join("zsh", ".zshrc"),
+		filepath.Join("nvim", ".config", "nvim", "init.lua"),
+		filepath.Join("nvim", ".config", "nvim", "lua", "plugins.lua"),
+	} {
+		path := filepath.Join(stowDir, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// What 'stow zsh nvim' leaves behind: ~/.config exists, so ~/.config/nvim
+	// is folded into a single directory symlink
+	zshrc := filepath.Join(home, ".zshrc")
+	nvimDir := filepath.Join(home, ".config", "nvim")
+	os.Symlink(filepath.Join(stowDir, "zsh", ".zshrc"), zshrc)
+	os.Symlink(filepath.Join(stowDir, "nvim", ".config", "nvim"), nvimDir)
+
+	useScript(t, "y")
+	importStowLink = true
+	defer func() { importStowLink = false }()
+	if err := runImportStow(importStowCmd, []string{stowDir}); err != nil {
+		t.Fatalf("runImportStow() failed: %v", err)
+	}
+
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := m.GetEntry("nvim"); e == nil || e.Root != "~/.config/nvim" || len(e.Files) != 2 {
+		t.Fatalf("nvim entry = %+v, want root ~/.config/nvim with 2 files", e)
+	}
+	if e := m.GetEntry("zsh"); e == nil || e.Root != "~" {
+		t.Fatalf("zsh entry = %+v, want root ~", e)
+	}
+
+	if info, err := os.Lstat(nvimDir); err != nil || !info.IsDir() {
+		t.Errorf("%s should be unfolded into a directory (err: %v)", nvimDir, err)
+	}
+	for _, f := range []struct{ original, cloud string }{
+		{zshrc, m.CloudPath(storagePath, "zsh", ".zshrc")},
+		{filepath.Join(nvimDir, "init.lua"), m.CloudPath(storagePath, "nvim", "init.lua")},
+		{filepath.Join(nvimDir, "lua", "plugins.lua"), m.CloudPath(storagePath, "nvim", "lua/plugins.lua")},
+	} {
+		if status, _, _ := symlink.Check(f.original, f.cloud); status != symlink.StatusLinked {
+			t.Errorf("%s status = %v, want %v", f.original, status, symlink.StatusLinked)
+		}
+	}
+}
+
+// TestRunImportStow_DryRun tests that a preview changes nothing
+func TestRunImportStow_DryRun(t *testing.T) {
+	home, _, _ := setupLinkedFile(t)
+	stowDir := filepath.Join(home, "dotfiles")
+	os.MkdirAll(filepath.Join(stowDir, "zsh"), 0755)
+	os.WriteFile(filepath.Join(stowDir, "zsh", ".zshrc"), []byte("x"), 0644)
+
+	importStowDryRun = true
+	defer func() { importStowDryRun = false }()
+	if err := runImportStow(importStowCmd, []string{stowDir}); err != nil {
+		t.Fatalf("runImportStow() failed: %v", err)
+	}
+
+	m, err := manifest.Load(filepath.Join(home, "storage"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.HasEntry("zsh") {
+		t.Error("dry run added entry 'zsh'")
+	}
+}
+
+// TestRunImportStow_AlreadyTracked tests that files already under another
+// entry are skipped instead of being tracked twice
+func TestRunImportStow_AlreadyTracked(t *testing.T) {
+	home, _, _ := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	stowDir := filepath.Join(home, "dotfiles")
+
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddFile("editor", "~/.config/nvim", "init.lua")
+	if err := m.Save(storagePath); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []string{
+		filepath.Join("zsh", ".zshrc"),
+		filepath.Join("nvim", ".config", "nvim", "init.lua"),
+		filepath.Join("nvim", ".config", "nvim", "lua", "plugins.lua"),
+	} {
+		path := filepath.Join(stowDir, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(f), 0644)
+	}
+
+	useScript(t, "y")
+	if err := runImportStow(importStowCmd, []string{stowDir}); err != nil {
+		t.Fatalf("runImportStow() failed: %v", err)
+	}
+
+	m, err = manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.HasEntry("nvim") {
+		t.Errorf("nvim entry = %+v, want files under 'editor' skipped", m.GetEntry("nvim"))
+	}
+	if e := m.GetEntry("editor"); len(e.Files) != 1 {
+		t.Errorf("editor files = %v, want unchanged", e.Files)
+	}
+	if !m.HasEntry("zsh") {
+		t.Error("zsh should be imported")
+	}
+}
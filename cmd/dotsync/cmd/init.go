@@ -1,19 +1,25 @@
 package cmd
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
+	"sort"
+	"strconv"
 
 	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/backup"
 	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/journal"
 	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
 	"github.com/wtfzambo/dotsync/internal/storage"
+	"github.com/wtfzambo/dotsync/internal/symlink"
 )
 
 var initCmd = &cobra.Command{
-	Use:   "init <provider>",
+	Use:   "init [provider]",
 	Short: "Initialize dotsync with a cloud storage provider",
 	Long: `Initialize dotsync by specifying which cloud storage provider to use.
 
@@ -22,21 +28,65 @@ Supported providers:
   dropbox  - Dropbox
   icloud   - iCloud Drive
 
+Without a provider or --path, the providers found on this machine are
+listed to choose from (with --non-interactive, a provider is required).
+
 The command will attempt to auto-detect the storage location.
+Set DOTSYNC_<PROVIDER>_PATH (e.g. DOTSYNC_GDRIVE_PATH) to override the
+detected location for a provider, e.g. for non-standard mounts. If it
+points to a missing path, dotsync warns instead of falling back to the
+known locations.
 If not found, you'll be prompted to enter the path manually.
 
-You can also specify an explicit path using the --path flag.`,
+You can also specify an explicit path using the --path flag.
+
+Use --migrate-from to import an existing dotfiles directory that mirrors
+your home layout (e.g. ~/dotfiles/.zshrc, ~/dotfiles/.config/nvim/init.lua).
+Files are copied into the dotsync layout (or moved with --move) and added
+to the manifest. Run "dotsync link" afterwards to create the symlinks.
+
+Use --link on a new machine to link all entries from an existing
+manifest right after initializing.
+
+Use --storage-subpath when several machines or users share one cloud
+folder: this machine's manifest and files are kept in
+<storage>/dotsync/<subpath>/ instead of <storage>/dotsync/, so each
+subpath is tracked separately without clobbering the others.
+
+Use --git-friendly when the cloud folder is also a git repository: a
+.gitignore is written to <storage>/dotsync/ that excludes local-only
+files like the add journal and backups. An existing .gitignore is kept.`,
 	Example: `  dotsync init gdrive
   dotsync init dropbox
-  dotsync init --path ~/my-cloud-folder`,
+  dotsync init --path ~/my-cloud-folder
+  dotsync init gdrive --migrate-from ~/dotfiles
+  dotsync init gdrive --link
+  dotsync init --path ~/dotfiles-repo --git-friendly
+  dotsync init gdrive --storage-subpath alice`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runInit,
 }
 
-var initPath string
+var (
+	initPath        string
+	initMigrateFrom string
+	initMove        bool
+	initBackupStore bool
+	initLink        bool
+	initForce       bool
+	initGitFriendly bool
+	initSubpath     string
+)
 
 func init() {
 	initCmd.Flags().StringVarP(&initPath, "path", "p", "", "Explicit storage path (skips provider detection)")
+	initCmd.Flags().StringVar(&initMigrateFrom, "migrate-from", "", "Import files from an existing dotfiles directory")
+	initCmd.Flags().BoolVar(&initMove, "move", false, "Move migrated files into storage instead of copying them")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Use the storage path even if it overlaps config locations")
+	initCmd.Flags().BoolVar(&initLink, "link", false, "Link all entries from the manifest after initializing")
+	initCmd.Flags().StringVar(&initSubpath, "storage-subpath", "", "Keep this machine's files in <storage>/dotsync/<subpath>/ (for shared cloud folders)")
+	initCmd.Flags().BoolVar(&initGitFriendly, "git-friendly", false, "Write a .gitignore excluding local-only files into <storage>/dotsync/")
+	initCmd.Flags().BoolVar(&initBackupStore, "backup-to-storage", false, "Keep conflict backups in cloud storage instead of ~/.cache")
 	rootCmd.AddCommand(initCmd)
 }
 
@@ -59,19 +109,38 @@ func runInit(cmd *cobra.Command, args []string) error {
 	// If explicit path provided, use it
 	if initPath != "" {
 		storagePath = initPath
-	} else {
-		// Need a provider argument
-		if len(args) == 0 {
+	} else if len(args) == 0 {
+		// No provider given: pick one of the providers found on this machine
+		for _, p := range storage.SupportedProviders() {
+			if err := storage.CheckPathEnv(p); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+		detected, timedOut := storage.DetectAll(storage.DetectTimeout)
+		for _, p := range timedOut {
+			fmt.Printf("Checking %s timed out (network mount offline?), skipping it\n", p.DisplayName())
+		}
+		if nonInteractive || len(detected) == 0 {
 			return fmt.Errorf("please specify a provider (gdrive, dropbox, icloud) or use --path")
 		}
-
+		storagePath, err = chooseProvider(detected)
+		if err != nil {
+			return err
+		}
+	} else {
 		provider := storage.ParseProvider(args[0])
 		if provider == "" {
 			return fmt.Errorf("unknown provider: %s. Supported: gdrive, dropbox, icloud", args[0])
 		}
 
-		// Try to detect the storage path
-		storagePath = storage.DetectPath(provider)
+		// Try to detect the storage path, without hanging on an offline mount
+		if err := storage.CheckPathEnv(provider); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		storagePath, err = storage.DetectPathTimeout(provider, storage.DetectTimeout)
+		if errors.Is(err, storage.ErrDetectTimeout) {
+			fmt.Printf("Checking %s timed out (network mount offline?)\n", provider.DisplayName())
+		}
 		if storagePath == "" {
 			// Prompt for manual entry
 			var err error
@@ -88,60 +157,290 @@ func runInit(cmd *cobra.Command, args []string) error {
 	if err := storage.ValidatePath(storagePath); err != nil {
 		return err
 	}
+	subpath, err := manifest.CleanSubpath(initSubpath)
+	if err != nil {
+		return err
+	}
+	if err := checkStorageOverlap(storage.CheckLocation(storagePath)); err != nil {
+		return err
+	}
+	if err := manifest.CheckSubpath(storage.ExpandPath(storagePath), subpath); err != nil {
+		return err
+	}
+
+	// Refuse storage overlapping the roots it already tracks, before
+	// anything is written to it
+	expandedPath := storage.ExpandPath(storagePath)
+	var existing *manifest.Manifest
+	if manifest.Exists(expandedPath, subpath) {
+		existing, err = manifest.Load(expandedPath, subpath)
+		if err != nil {
+			return fmt.Errorf("loading manifest: %w", err)
+		}
+		roots := make([]string, 0, len(existing.Entries))
+		for _, entry := range existing.Entries {
+			roots = append(roots, entry.Root)
+		}
+		if err := checkStorageOverlap(storage.CheckRootOverlap(storagePath, roots)); err != nil {
+			return err
+		}
+	}
 
 	// Ensure dotsync directory exists
-	_, err = storage.EnsureDotsyncDir(storagePath)
+	dotsyncDir, err := storage.EnsureDotsyncDir(storagePath, subpath)
 	if err != nil {
 		return err
 	}
+	if initGitFriendly {
+		written, err := writeGitignore(dotsyncDir)
+		if err != nil {
+			return err
+		}
+		if written {
+			fmt.Println("Created .gitignore for local-only dotsync files.")
+		} else {
+			fmt.Println("Keeping existing .gitignore.")
+		}
+	}
 
 	// Create manifest if it doesn't exist
-	expandedPath := storage.ExpandPath(storagePath)
-	if !manifest.Exists(expandedPath) {
+	existingEntries := 0
+	if existing == nil {
 		m := manifest.New()
+		m.Subpath = subpath
 		if err := m.Save(expandedPath); err != nil {
 			return fmt.Errorf("creating manifest: %w", err)
 		}
 		fmt.Println("Created new manifest.")
 	} else {
-		fmt.Println("Using existing manifest.")
+		existingEntries = len(existing.Entries)
+		fmt.Printf("Using existing manifest (%d entries).\n", existingEntries)
 	}
 
 	// Save config
 	cfg := config.New(storagePath)
+	cfg.BackupToStorage = initBackupStore
+	cfg.StorageSubpath = subpath
 	if err := cfg.Save(); err != nil {
 		return fmt.Errorf("saving config: %w", err)
 	}
 
 	fmt.Printf("dotsync initialized! Storage: %s\n", storagePath)
+
+	// Import an existing dotfiles layout if requested
+	if initMigrateFrom != "" {
+		if err := runMigrate(initMigrateFrom, expandedPath, subpath, initMove); err != nil {
+			return err
+		}
+	}
+
+	// Link existing entries if requested
+	if initLink {
+		return runLink(cmd, nil)
+	}
+	if existingEntries > 0 {
+		fmt.Println("Run 'dotsync link' to create symlinks for the existing entries.")
+	}
+
 	return nil
 }
 
-func confirmReinit() bool {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("dotsync is already initialized. Reinitialize? [y/N] ")
-	response, err := reader.ReadString('\n')
+// gitignoreContent excludes the files dotsync keeps in storage that only
+// matter to one machine or one operation.
+var gitignoreContent = fmt.Sprintf(`# Written by 'dotsync init --git-friendly'.
+# Local-only dotsync files that shouldn't be committed.
+%[1]s
+%[1]s.tmp
+%[2]s/
+%[3]s/
+`, journal.FileName, backup.StorageBackupDirName, backup.ReplacedDirName)
+
+// writeGitignore writes a .gitignore for local-only files into dotsyncDir.
+// An existing .gitignore is left alone; returns false in that case.
+func writeGitignore(dotsyncDir string) (bool, error) {
+	path := filepath.Join(dotsyncDir, ".gitignore")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return false, nil
+	}
 	if err != nil {
-		return false
+		return false, fmt.Errorf("creating .gitignore: %w", err)
+	}
+	if _, err := f.WriteString(gitignoreContent); err != nil {
+		f.Close()
+		return false, fmt.Errorf("writing .gitignore: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("writing .gitignore: %w", err)
 	}
-	response = strings.TrimSpace(strings.ToLower(response))
-	return response == "y" || response == "yes"
+	return true, nil
+}
+
+// checkStorageOverlap turns a storage overlap problem into an error,
+// or into a warning when --force is set.
+func checkStorageOverlap(err error) error {
+	if err == nil {
+		return nil
+	}
+	if initForce {
+		fmt.Printf("Warning: %v\n", err)
+		return nil
+	}
+	return fmt.Errorf("%w\nUse --force to use it anyway", err)
+}
+
+func confirmReinit() bool {
+	return prompter.Confirm("dotsync is already initialized. Reinitialize?")
 }
 
 func promptForPath(provider storage.Provider) (string, error) {
-	reader := bufio.NewReader(os.Stdin)
 	fmt.Printf("%s not found at known locations.\n", provider.DisplayName())
-	fmt.Print("Enter path (or 'q' to quit): ")
-
-	response, err := reader.ReadString('\n')
+	response, err := prompter.Ask("Enter path (or 'q' to quit): ")
 	if err != nil {
 		return "", fmt.Errorf("reading input: %w", err)
 	}
 
-	response = strings.TrimSpace(response)
 	if response == "q" || response == "" {
-		return "", fmt.Errorf("aborted")
+		return "", ErrAborted
 	}
 
 	return response, nil
 }
+
+// chooseProvider lists the detected providers and asks which one to use.
+// Returns the chosen provider's path.
+func chooseProvider(detected []storage.Detected) (string, error) {
+	fmt.Println("Found cloud storage:")
+	for i, d := range detected {
+		fmt.Printf("  %d) %-13s %s\n", i+1, d.Provider.DisplayName(), pathutil.ContractHome(d.Path))
+	}
+
+	response, err := prompter.Ask(fmt.Sprintf("Choose a provider [1-%d] (or 'q' to quit): ", len(detected)))
+	if err != nil {
+		return "", fmt.Errorf("reading input: %w", err)
+	}
+	if response == "q" || response == "" {
+		return "", ErrAborted
+	}
+
+	n, err := strconv.Atoi(response)
+	if err != nil || n < 1 || n > len(detected) {
+		return "", fmt.Errorf("invalid choice: %s", response)
+	}
+	fmt.Printf("Using %s at: %s\n", detected[n-1].Provider.DisplayName(), detected[n-1].Path)
+	return detected[n-1].Path, nil
+}
+
+// runMigrate imports files from an existing dotfiles directory into the
+// dotsync layout and records them in the manifest under the given storage
+// subpath.
+func runMigrate(oldPath, storagePath, subpath string, move bool) error {
+	absOld, err := pathutil.AbsolutePath(oldPath)
+	if err != nil {
+		return fmt.Errorf("resolving path: %w", err)
+	}
+
+	found, unmatched, err := pathutil.ScanLayout(absOld)
+	if err != nil {
+		return err
+	}
+
+	m, err := manifest.Load(storagePath, subpath)
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+
+	// Filter out files that can't be imported
+	var toImport []pathutil.ScanResult
+	for _, r := range found {
+		if existing := m.GetEntry(r.Name); existing != nil && manifest.NormalizeRoot(existing.Root) != manifest.NormalizeRoot(r.Root) {
+			fmt.Printf("  [skipped] %s (entry '%s' exists with root %s)\n", pathutil.ContractHome(r.SourcePath), r.Name, existing.Root)
+			continue
+		}
+		tree, err := subpathCollision(m, storagePath, r.Name)
+		if err != nil {
+			return err
+		}
+		if tree != "" {
+			fmt.Printf("  [skipped] %s (entry '%s' collides with storage subpath '%s')\n", pathutil.ContractHome(r.SourcePath), r.Name, tree)
+			continue
+		}
+		destPath := m.CloudPath(storagePath, r.Name, r.RelPath)
+		if _, err := os.Stat(destPath); err == nil {
+			fmt.Printf("  [skipped] %s (already in cloud storage)\n", pathutil.ContractHome(r.SourcePath))
+			continue
+		}
+		toImport = append(toImport, r)
+	}
+
+	for _, path := range unmatched {
+		fmt.Printf("  [skipped] %s (cannot infer entry)\n", pathutil.ContractHome(path))
+	}
+
+	if len(toImport) == 0 {
+		fmt.Printf("No files to import from %s\n", pathutil.ContractHome(absOld))
+		return nil
+	}
+
+	// Report what was found, grouped by entry
+	byEntry := make(map[string][]pathutil.ScanResult)
+	for _, r := range toImport {
+		byEntry[r.Name] = append(byEntry[r.Name], r)
+	}
+	names := make([]string, 0, len(byEntry))
+	for name := range byEntry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("\nFound in %s:\n", pathutil.ContractHome(absOld))
+	for _, name := range names {
+		results := byEntry[name]
+		fmt.Printf("  %s (%s)\n", name, results[0].Root)
+		for _, r := range results {
+			fmt.Printf("    %s\n", r.RelPath)
+		}
+	}
+
+	verb := "Copy"
+	if move {
+		verb = "Move"
+	}
+	if !confirmPrompt(fmt.Sprintf("%s %d file(s) into %d entr(ies)?", verb, len(toImport), len(names))) {
+		fmt.Println("Migration skipped.")
+		return nil
+	}
+
+	// Import each file
+	var imported, failed int
+	for _, r := range toImport {
+		destPath := m.CloudPath(storagePath, r.Name, r.RelPath)
+
+		if move {
+			err = symlink.MoveFile(r.SourcePath, destPath)
+		} else {
+			err = symlink.CopyFile(r.SourcePath, destPath)
+		}
+		if err != nil {
+			fmt.Printf("  [failed]  %s: %v\n", pathutil.ContractHome(r.SourcePath), err)
+			failed++
+			continue
+		}
+
+		m.AddFile(r.Name, r.Root, r.RelPath)
+		imported++
+	}
+
+	if err := m.Save(storagePath); err != nil {
+		return fmt.Errorf("saving manifest: %w", err)
+	}
+
+	fmt.Printf("\nSummary: %d imported, %d failed\n", imported, failed)
+	fmt.Println("Run 'dotsync link --backup' to replace the existing files with symlinks.")
+
+	if failed > 0 {
+		return markAs(ErrPartialFailure, fmt.Errorf("some files failed to import"))
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// TestWriteGitignore tests writing the storage .gitignore without replacing
+// one the user already has
+func TestWriteGitignore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".gitignore")
+
+	written, err := writeGitignore(dir)
+	if err != nil || !written {
+		t.Fatalf("writeGitignore() = %v, %v, want true, nil", written, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{".journal\n", ".backups/\n", ".replaced/\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf(".gitignore missing %q:\n%s", want, data)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("custom\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	written, err = writeGitignore(dir)
+	if err != nil || written {
+		t.Fatalf("writeGitignore() on existing = %v, %v, want false, nil", written, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "custom\n" {
+		t.Errorf("existing .gitignore was overwritten: %q", data)
+	}
+}
+
+// TestRunInit_Link tests that --link links the entries of an existing
+// manifest right after initializing
+func TestRunInit_Link(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	// Outside home: the "zsh" entry is rooted at ~
+	storagePath := t.TempDir()
+
+	m := manifest.New()
+	m.AddFile("app", "~/.config/app", "config.json")
+	m.AddFile("zsh", "~", ".zshrc")
+	if err := m.Save(storagePath); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"app", "zsh"} {
+		for _, relPath := range m.Entries[name].Files {
+			cloudPath := m.CloudPath(storagePath, name, relPath)
+			os.MkdirAll(filepath.Dir(cloudPath), 0755)
+			os.WriteFile(cloudPath, []byte(relPath), 0644)
+		}
+	}
+
+	initPath, initLink = storagePath, true
+	defer func() { initPath, initLink = "", false }()
+	if err := runInit(initCmd, nil); err != nil {
+		t.Fatalf("runInit() failed: %v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil || cfg == nil || cfg.StoragePath != storagePath {
+		t.Fatalf("config = %+v (%v), want storage %s", cfg, err, storagePath)
+	}
+	for _, f := range []struct{ original, cloud string }{
+		{filepath.Join(home, ".config", "app", "config.json"), m.CloudPath(storagePath, "app", "config.json")},
+		{filepath.Join(home, ".zshrc"), m.CloudPath(storagePath, "zsh", ".zshrc")},
+	} {
+		if status, _, _ := symlink.Check(f.original, f.cloud); status != symlink.StatusLinked {
+			t.Errorf("%s status = %v, want %v", f.original, status, symlink.StatusLinked)
+		}
+	}
+}
+
+// TestRunInit_RootOverlap tests that storage overlapping an entry root is
+// refused before anything is written to it
+func TestRunInit_RootOverlap(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	storagePath := filepath.Join(home, "storage")
+
+	m := manifest.New()
+	m.AddFile("zsh", "~", ".zshrc")
+	if err := m.Save(storagePath); err != nil {
+		t.Fatal(err)
+	}
+
+	initPath, initGitFriendly = storagePath, true
+	defer func() { initPath, initGitFriendly = "", false }()
+	err := runInit(initCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "overlaps entry root") {
+		t.Fatalf("runInit() error = %v, want overlap error", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(manifest.DotsyncDir(storagePath, ""), ".gitignore")); !os.IsNotExist(err) {
+		t.Errorf(".gitignore was written (err: %v)", err)
+	}
+	if exists, _ := config.Exists(); exists {
+		t.Error("config was saved")
+	}
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,7 +8,6 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/wtfzambo/dotsync/internal/backup"
-	"github.com/wtfzambo/dotsync/internal/config"
 	"github.com/wtfzambo/dotsync/internal/manifest"
 	"github.com/wtfzambo/dotsync/internal/pathutil"
 	"github.com/wtfzambo/dotsync/internal/symlink"
@@ -24,43 +22,114 @@ Use this command on a new machine to set up symlinks for entries
 that were added on another machine.
 
 If no entry name is provided, all entries will be linked.
+Use --only or --except with comma-separated entry names to link a subset.
+Entries disabled with 'dotsync disable' are skipped.
 If a file already exists at the target location, you'll be prompted
-to backup, skip, or abort.`,
+to backup, skip, or abort.
+
+Use --restore-permissions to make files in private directories like
+~/.ssh accessible only by their owner again, in case the cloud provider
+reset their permissions. For symlinks the cloud copy is fixed, for
+copy-mode files the local copy.
+
+Use --repoint after moving cloud storage (e.g. switching providers or
+renaming the sync folder): symlinks that still point into an old dotsync
+storage folder are recreated against the current one without prompting.
+
+Use --create-missing-source when a tracked file never made it to cloud
+storage (e.g. it hasn't synced yet): an empty file is created in storage
+and linked, so apps that regenerate their config have a file to start
+from. The previous content is not recovered.
+
+On Windows, creating symlinks requires Developer Mode or Administrator.
+Use --windows-fallback (or set "windowsFallback": true in the config) to
+switch files that can't be symlinked to copy mode instead. The switch is
+recorded in the manifest. Use --as-copy-if-symlink-unsupported (or
+"copyIfSymlinkUnsupported": true) for the same fallback on any system,
+including FAT drives and network mounts without symlink support.
+
+Use --backup-existing-into-storage on a machine with existing configs you
+want to keep: files replaced by link are backed up without prompting into
+<storage>/dotsync/.replaced/<hostname>/<entry>/, timestamped, so they
+are preserved via cloud sync instead of in the local cache.
+
+Use --skip-missing-parents to skip files whose directory doesn't exist
+yet, which usually means the app isn't installed on this machine, instead
+of creating the directory. Use --skip-missing-root to skip whole entries
+whose root directory (e.g. ~/.config/opencode) doesn't exist, so only
+configs of installed apps are linked.
+
+Use --prune-broken for day-to-day cleanup: besides linking, symlinks whose
+cloud file is gone are removed instead of failing, and files in storage
+that no manifest entry tracks are listed. Broken symlinks pointing
+anywhere but a dotsync storage folder are left alone. Use 'dotsync unlink
+--prune-missing' to also stop tracking the pruned files.
+
+Use --report <file> to write a JSON summary of the run, listing the
+outcome of every file, for auditing across machines.`,
 	Example: `  dotsync link           # Link all entries
   dotsync link opencode  # Link only the "opencode" entry
-  dotsync link --backup  # Auto-backup existing files`,
+  dotsync link --except work-secrets
+  dotsync link --backup  # Auto-backup existing files
+  dotsync link --restore-permissions
+  dotsync link --backup-existing-into-storage
+  dotsync link --repoint # Fix symlinks into an old storage location
+  dotsync link --prune-broken  # Relink, drop dead symlinks, report orphans`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runLink,
 }
 
-var linkBackup bool
+var (
+	linkBackup              bool
+	linkRestorePermissions  bool
+	linkRepoint             bool
+	linkCreateMissingSource bool
+	linkWindowsFallback     bool
+	linkCopyIfNoSymlink     bool
+	linkBackupIntoStorage   bool
+	linkSkipMissingParents  bool
+	linkSkipMissingRoot     bool
+	linkPruneBroken         bool
+	linkOnly                []string
+	linkExcept              []string
+)
 
 func init() {
 	linkCmd.Flags().BoolVarP(&linkBackup, "backup", "b", false, "Automatically backup existing files without prompting")
+	linkCmd.Flags().BoolVar(&linkRestorePermissions, "restore-permissions", false, "Remove group/other access from files in private directories like ~/.ssh")
+	linkCmd.Flags().BoolVar(&linkCreateMissingSource, "create-missing-source", false, "Create an empty cloud file for tracked files missing from storage")
+	linkCmd.Flags().BoolVar(&linkWindowsFallback, "windows-fallback", false, "Switch files to copy mode when symlinks aren't allowed (Windows)")
+	linkCmd.Flags().BoolVar(&linkCopyIfNoSymlink, "as-copy-if-symlink-unsupported", false, "Switch files to copy mode when the symlink can't be created (any platform or filesystem)")
+	linkCmd.Flags().BoolVar(&linkBackupIntoStorage, "backup-existing-into-storage", false, "Back up existing files into <storage>/dotsync/.replaced/<hostname>/ without prompting")
+	linkCmd.Flags().BoolVar(&linkSkipMissingParents, "skip-missing-parents", false, "Skip files whose parent directory doesn't exist (e.g. the app isn't installed)")
+	linkCmd.Flags().BoolVar(&linkSkipMissingRoot, "skip-missing-root", false, "Skip entries whose root directory doesn't exist (e.g. the app isn't installed)")
+	linkCmd.Flags().BoolVar(&linkPruneBroken, "prune-broken", false, "Remove symlinks whose cloud file is gone and report untracked files in storage")
+	linkCmd.Flags().BoolVar(&linkRepoint, "repoint", false, "Recreate symlinks that point into an old storage location")
+	linkCmd.Flags().StringSliceVar(&linkOnly, "only", nil, "Link only these entries (comma-separated)")
+	linkCmd.Flags().StringSliceVar(&linkExcept, "except", nil, "Link all entries but these (comma-separated)")
+	addReportFlag(linkCmd)
 	rootCmd.AddCommand(linkCmd)
 }
 
 func runLink(cmd *cobra.Command, args []string) error {
-	// 1. Load config (must be initialized)
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
+	if linkPruneBroken && linkCreateMissingSource {
+		return fmt.Errorf("--prune-broken can't be combined with --create-missing-source")
 	}
-	if cfg == nil {
-		return fmt.Errorf("dotsync not initialized. Run 'dotsync init <provider>' first")
-	}
-
-	storagePath := pathutil.ExpandHome(cfg.StoragePath)
 
-	// Verify storage is available
-	if _, err := os.Stat(storagePath); os.IsNotExist(err) {
-		return fmt.Errorf("storage unavailable: %s\nMake sure your cloud storage is mounted/syncing", storagePath)
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
 	}
+	warnInterruptedAdd(manifest.DotsyncDir(storagePath, cfg.StorageSubpath))
 
 	// 2. Load manifest
-	m, err := manifest.Load(storagePath)
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
 	if err != nil {
 		if strings.Contains(err.Error(), "manifest not found") {
+			if err := checkPopulated(storagePath); err != nil {
+				return err
+			}
 			return fmt.Errorf("no manifest found. Nothing to link.\nUse 'dotsync add' to start tracking files")
 		}
 		return fmt.Errorf("loading manifest: %w", err)
@@ -72,78 +141,381 @@ func runLink(cmd *cobra.Command, args []string) error {
 	}
 
 	// 3. Determine which entries to link
-	var entriesToLink map[string]manifest.Entry
-	if len(args) > 0 {
-		entryName := args[0]
-		entry := m.GetEntry(entryName)
-		if entry == nil {
-			return fmt.Errorf("entry '%s' not found", entryName)
+	entriesToLink, err := selectEntries(m, args, linkOnly, linkExcept)
+	if err != nil {
+		return err
+	}
+
+	opts := linkOptions{
+		autoBackup:          linkBackup || linkBackupIntoStorage,
+		backupDir:           backupDirFor(cfg, storagePath),
+		createMissingSource: linkCreateMissingSource,
+		skipMissingParent:   linkSkipMissingParents,
+		pruneBroken:         linkPruneBroken,
+	}
+
+	var replacedDir string
+	if linkBackupIntoStorage {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("getting hostname: %w", err)
 		}
-		entriesToLink = map[string]manifest.Entry{entryName: *entry}
-	} else {
-		entriesToLink = m.Entries
+		replacedDir = backup.ReplacedDir(m.DotsyncDir(storagePath), hostname)
+	}
+
+	windowsFallback := linkWindowsFallback || cfg.WindowsFallback
+	copyIfUnsupported := linkCopyIfNoSymlink || cfg.CopyIfSymlinkUnsupported
+
+	// Files sharing an original path would overwrite each other's symlink
+	duplicates := duplicateFiles(m)
+	if err := m.Validate(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
 	}
 
-	// 4. Link each entry
-	var linked, skipped, failed int
+	// 4. Link each entry, stopping between files on Ctrl-C
+	ctx := commandContext(cmd)
+	var linked, pruned, skipped, failed int
+	var switched int
+	managedDir := m.DotsyncDir(storagePath)
 
-	for name, entry := range entriesToLink {
+entries:
+	for _, name := range sortedNames(entriesToLink) {
+		entry := entriesToLink[name]
 		fmt.Printf("\nLinking entry '%s':\n", name)
 
+		if !entry.Enabled() {
+			fmt.Printf("  [skipped] %d file(s) (entry is disabled, use 'dotsync enable %s')\n", len(entry.Files), name)
+			for _, relPath := range entry.Files {
+				opReport.file(name, relPath, "skipped", nil, "entry is disabled")
+			}
+			skipped += len(entry.Files)
+			continue
+		}
+
 		entryRoot := pathutil.ExpandHome(entry.Root)
+		if linkSkipMissingRoot && rootMissing(entryRoot) {
+			fmt.Printf("  [skipped] %d file(s) (root %s doesn't exist, app not installed?)\n", len(entry.Files), pathutil.ContractHome(entryRoot))
+			for _, relPath := range entry.Files {
+				opReport.file(name, relPath, "skipped", nil, "root doesn't exist")
+			}
+			skipped += len(entry.Files)
+			continue
+		}
+
 		for _, relPath := range entry.Files {
-			originalPath := filepath.Join(entryRoot, relPath)
-			cloudPath := filepath.Join(storagePath, "dotsync", name, relPath)
+			if ctx.Err() != nil {
+				fmt.Println("\nInterrupted, stopping.")
+				break entries
+			}
+
+			originalPath := filepath.Join(entryRoot, manifest.FromStorageSlash(relPath))
+			cloudPath := entry.CloudPath(managedDir, name, relPath)
 
-			result, err := linkFile(originalPath, cloudPath, linkBackup)
+			if duplicates[manifest.FileRef{Entry: name, RelPath: relPath}] {
+				fmt.Printf("  [failed]  %s (another tracked file maps to the same path)\n", relPath)
+				opReport.file(name, relPath, "failed", nil, "another tracked file maps to the same path")
+				failed++
+				continue
+			}
+
+			// Don't link inside a directory that is itself a dotsync symlink
+			if ancestor := symlink.ManagedAncestor(originalPath, managedDir); ancestor != "" {
+				fmt.Printf("  [skipped] %s (parent %s is a dotsync symlink)\n", relPath, pathutil.ContractHome(ancestor))
+				opReport.file(name, relPath, "skipped", nil, "parent is a dotsync symlink")
+				skipped++
+				continue
+			}
+
+			// A hand-edited root could point into storage
+			if err := symlink.CheckLoop(originalPath, cloudPath, managedDir); err != nil {
+				fmt.Printf("  [failed]  %s: %v\n", relPath, err)
+				opReport.file(name, relPath, "failed", err, "")
+				failed++
+				continue
+			}
+
+			if linkRepoint && entry.FileMode(relPath) != manifest.ModeCopy {
+				oldTarget, err := repointFile(originalPath, cloudPath, storageRelPath(m, name, relPath))
+				if err != nil {
+					fmt.Printf("  [failed]  %s: %v\n", relPath, err)
+					opReport.file(name, relPath, "failed", err, "")
+					failed++
+					continue
+				}
+				if oldTarget != "" {
+					fmt.Printf("  [repointed] %s (was %s)\n", relPath, pathutil.ContractHome(oldTarget))
+					opReport.file(name, relPath, "repointed", nil, "was "+oldTarget)
+					linked++
+					continue
+				}
+			}
+
+			opts := opts
+			if replacedDir != "" {
+				opts.backupDir = replacedBackupDir(replacedDir, entry.StorageRelPath(name, relPath))
+			}
+			opts.parentMode, _ = entry.ParentMode(relPath)
+			opts.storageRel = storageRelPath(m, name, relPath)
+
+			var result linkResult
+			var err error
+			if entry.FileMode(relPath) == manifest.ModeCopy {
+				result, err = linkCopyFile(originalPath, cloudPath, opts)
+			} else {
+				result, err = linkFile(originalPath, cloudPath, opts)
+				if copyFallback(windowsFallback, copyIfUnsupported, err) {
+					fmt.Printf("  [copy]    %s (symlinks not supported, switching to copy mode)\n", relPath)
+					m.SetFileMode(name, relPath, manifest.ModeCopy)
+					entry = *m.GetEntry(name)
+					switched++
+					result, err = linkCopyFile(originalPath, cloudPath, opts)
+				}
+			}
 			switch result {
 			case linkResultLinked:
 				fmt.Printf("  [linked]  %s\n", relPath)
+				opReport.file(name, relPath, "linked", nil, "")
 				linked++
+			case linkResultPruned:
+				fmt.Printf("  [pruned]  %s (broken symlink, cloud file is gone)\n", relPath)
+				opReport.file(name, relPath, "pruned", nil, "broken symlink, cloud file is gone")
+				pruned++
 			case linkResultSkipped:
 				fmt.Printf("  [skipped] %s\n", relPath)
+				opReport.file(name, relPath, "skipped", err, "")
 				skipped++
 			case linkResultAlreadyLinked:
 				fmt.Printf("  [ok]      %s (already linked)\n", relPath)
+				opReport.file(name, relPath, "ok", nil, "already linked")
 				// Don't count as linked or skipped
+			case linkResultUnchanged:
+				fmt.Printf("  [unchanged] %s (copy is up to date)\n", relPath)
+				opReport.file(name, relPath, "unchanged", nil, "copy is up to date")
 			case linkResultAborted:
-				return fmt.Errorf("aborted")
+				opReport.file(name, relPath, "aborted", nil, "")
+				if linked > 0 {
+					fmt.Println("\nFiles replaced before aborting were backed up. Use 'dotsync rm-backup' to review them.")
+				}
+				return ErrAborted
 			case linkResultFailed:
+				err = unsupportedHint(err, "Use --as-copy-if-symlink-unsupported")
 				fmt.Printf("  [failed]  %s: %v\n", relPath, err)
+				opReport.file(name, relPath, "failed", err, "")
 				failed++
 			}
+
+			if linkRestorePermissions && (result == linkResultLinked || result == linkResultAlreadyLinked || result == linkResultUnchanged) {
+				// The mode that matters is the one of the file actually read
+				target := cloudPath
+				if entry.FileMode(relPath) == manifest.ModeCopy {
+					target = originalPath
+				}
+				mode, err := restorePermissions(target, originalPath)
+				if err != nil {
+					fmt.Printf("  Warning: restoring permissions of %s: %v\n", relPath, err)
+				} else if mode != 0 {
+					fmt.Printf("  [chmod]   %s (%04o)\n", relPath, mode)
+				}
+			}
+		}
+	}
+
+	// 5. Record files switched to copy mode
+	if switched > 0 {
+		if err := m.Save(storagePath); err != nil {
+			return fmt.Errorf("saving manifest: %w", err)
 		}
 	}
 
-	// 5. Print summary
+	// 6. Print summary
 	fmt.Println()
-	if linked > 0 || skipped > 0 || failed > 0 {
+	if linkPruneBroken {
+		fmt.Printf("Summary: %d linked, %d pruned, %d skipped, %d failed\n", linked, pruned, skipped, failed)
+		if ctx.Err() == nil {
+			if err := reportOrphans(storagePath, m); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+	} else if linked > 0 || skipped > 0 || failed > 0 {
 		fmt.Printf("Summary: %d linked, %d skipped, %d failed\n", linked, skipped, failed)
 	}
 
+	if ctx.Err() != nil {
+		return ErrInterrupted
+	}
 	if failed > 0 {
-		return fmt.Errorf("some files failed to link")
+		return markAs(ErrPartialFailure, fmt.Errorf("some files failed to link"))
 	}
 
 	return nil
 }
 
+// duplicateFiles returns the files that share their original path with
+// another tracked file (see Manifest.DuplicateTargets).
+func duplicateFiles(m *manifest.Manifest) map[manifest.FileRef]bool {
+	files := make(map[manifest.FileRef]bool)
+	for _, d := range m.DuplicateTargets() {
+		for _, f := range d.Files {
+			files[f] = true
+		}
+	}
+	return files
+}
+
+// replacedBackupDir returns the directory a file replaced by link is backed
+// up into, mirroring its location in storage: <replacedDir>/<dir of storageRel>,
+// where storageRel is the file's path relative to <storage>/dotsync.
+func replacedBackupDir(replacedDir, storageRel string) string {
+	return filepath.Join(replacedDir, filepath.Dir(storageRel))
+}
+
+// restorePermissions removes group and other access from path when
+// originalPath is inside a private directory like ~/.ssh, e.g. 0644 becomes
+// 0600. Returns the new mode, or 0 if nothing changed.
+func restorePermissions(path, originalPath string) (os.FileMode, error) {
+	if !symlink.IsPrivatePath(originalPath) {
+		return 0, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	perm := info.Mode().Perm()
+	want := perm &^ 0077
+	if want == perm {
+		return 0, nil
+	}
+	if err := os.Chmod(path, want); err != nil {
+		return 0, err
+	}
+	return want, nil
+}
+
+// repointFile recreates the symlink at originalPath if it points to the
+// same file in a different dotsync storage location, i.e. its target ends
+// in /<storageRel> but isn't cloudPath. storageRel is the file's path
+// relative to the storage folder (see storageRelPath). Returns the old
+// target, or "" if the symlink wasn't repointed.
+func repointFile(originalPath, cloudPath, storageRel string) (string, error) {
+	status, _, err := symlink.Check(originalPath, cloudPath)
+	if err != nil {
+		return "", err
+	}
+	if status != symlink.StatusIncorrect && status != symlink.StatusBroken {
+		return "", nil
+	}
+
+	target, err := symlink.ReadTarget(originalPath)
+	if err != nil {
+		return "", err
+	}
+	if !isStorageTarget(target, storageRel) {
+		return "", nil
+	}
+
+	if _, err := os.Stat(cloudPath); err != nil {
+		return "", fmt.Errorf("source file not found in cloud storage: %s", cloudPath)
+	}
+	if err := symlink.Remove(originalPath); err != nil {
+		return "", fmt.Errorf("removing old symlink: %w", err)
+	}
+	if err := symlink.Create(originalPath, cloudPath); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// isStorageTarget reports whether a symlink target looks like the cloud
+// copy of a file under some dotsync storage folder, where storageRel is the
+// file's path relative to the storage folder (see storageRelPath).
+func isStorageTarget(target, storageRel string) bool {
+	return strings.HasSuffix(manifest.ToStorageSlash(target), "/"+manifest.ToStorageSlash(storageRel))
+}
+
 type linkResult int
 
 const (
 	linkResultLinked linkResult = iota
 	linkResultSkipped
 	linkResultAlreadyLinked
+	linkResultUnchanged // copy mode: local copy already matches the cloud file
 	linkResultAborted
 	linkResultFailed
+	linkResultPruned // broken symlink removed, its cloud file is gone
 )
 
+// linkOptions controls how linkFile handles existing files.
+type linkOptions struct {
+	// autoBackup backs up conflicting files without prompting
+	autoBackup bool
+	// backupDir is where conflict backups go (empty for the default location)
+	backupDir string
+	// createMissingSource creates an empty cloud file when it's missing
+	createMissingSource bool
+	// skipMissingParent skips files whose parent directory doesn't exist
+	// instead of creating it
+	skipMissingParent bool
+	// pruneBroken removes broken symlinks whose cloud file is missing
+	pruneBroken bool
+	// storageRel is the file's path relative to the storage folder (see
+	// storageRelPath), used to recognize links into another storage
+	storageRel string
+	// parentMode is the recorded mode of the file's parent directory, used
+	// if it has to be created (0 for the default)
+	parentMode os.FileMode
+}
+
+// rootMissing reports whether an entry's root directory doesn't exist.
+func rootMissing(entryRoot string) bool {
+	_, err := os.Stat(entryRoot)
+	return os.IsNotExist(err)
+}
+
+// skipParent reports whether a file whose parent directory is missing
+// should be skipped, and warns about it if so.
+func skipParent(originalPath string, opts linkOptions) bool {
+	if !opts.skipMissingParent {
+		return false
+	}
+	fmt.Printf("  Directory doesn't exist: %s (app not installed?)\n", pathutil.ContractHome(filepath.Dir(originalPath)))
+	return true
+}
+
+// ensureSource checks that the cloud file exists. With createMissingSource
+// a missing cloud file is created empty, so the app has a file to start from.
+func ensureSource(originalPath, cloudPath string, opts linkOptions) error {
+	if _, err := os.Stat(cloudPath); !os.IsNotExist(err) {
+		return nil
+	}
+	if !opts.createMissingSource {
+		return fmt.Errorf("source file not found in cloud storage: %s", cloudPath)
+	}
+
+	perm := os.FileMode(0644)
+	if symlink.IsPrivatePath(originalPath) {
+		perm = 0600
+	}
+	if err := os.MkdirAll(filepath.Dir(cloudPath), 0755); err != nil {
+		return fmt.Errorf("creating cloud directory: %w", err)
+	}
+	if err := os.WriteFile(cloudPath, nil, perm); err != nil {
+		return fmt.Errorf("creating empty source file: %w", err)
+	}
+	fmt.Printf("  WARNING: %s was missing from cloud storage, created it EMPTY\n", pathutil.ContractHome(cloudPath))
+	return nil
+}
+
 // linkFile creates a symlink at originalPath pointing to cloudPath.
-// Handles existing files based on autoBackup flag or user prompt.
-func linkFile(originalPath, cloudPath string, autoBackup bool) (linkResult, error) {
-	// Check if cloud file exists
-	if _, err := os.Stat(cloudPath); os.IsNotExist(err) {
-		return linkResultFailed, fmt.Errorf("source file not found in cloud storage: %s", cloudPath)
+// Handles existing files based on the autoBackup option or user prompt.
+func linkFile(originalPath, cloudPath string, opts linkOptions) (linkResult, error) {
+	if opts.pruneBroken && cloudMissing(cloudPath) {
+		return pruneBrokenLink(originalPath, cloudPath, opts.storageRel)
+	}
+
+	if err := ensureSource(originalPath, cloudPath, opts); err != nil {
+		return linkResultFailed, err
 	}
 
 	// Check current state of original path
@@ -157,9 +529,12 @@ func linkFile(originalPath, cloudPath string, autoBackup bool) (linkResult, erro
 		// Already correctly linked
 		return linkResultAlreadyLinked, nil
 
-	case symlink.StatusNotExist:
-		// Path doesn't exist, safe to create symlink
-		if err := symlink.Create(originalPath, cloudPath); err != nil {
+	case symlink.StatusNotExist, symlink.StatusParentMissing:
+		if status == symlink.StatusParentMissing && skipParent(originalPath, opts) {
+			return linkResultSkipped, nil
+		}
+		// Path doesn't exist, safe to create symlink (and its parent)
+		if err := symlink.CreateWithParentMode(originalPath, cloudPath, opts.parentMode); err != nil {
 			return linkResultFailed, err
 		}
 		return linkResultLinked, nil
@@ -178,19 +553,112 @@ func linkFile(originalPath, cloudPath string, autoBackup bool) (linkResult, erro
 		// Symlink exists but points elsewhere
 		fmt.Printf("  Symlink exists but points to: %s\n", actualTarget)
 		fmt.Printf("  Expected: %s\n", cloudPath)
-		action := promptConflictAction(originalPath, autoBackup)
-		return handleConflict(originalPath, cloudPath, action)
+		action := promptConflictAction(originalPath, opts.autoBackup)
+		return handleConflict(originalPath, cloudPath, action, opts.backupDir, manifest.ModeSymlink)
 
 	case symlink.StatusNotLinked:
 		// Regular file exists - need to handle conflict
-		action := promptConflictAction(originalPath, autoBackup)
-		return handleConflict(originalPath, cloudPath, action)
+		action := promptConflictAction(originalPath, opts.autoBackup)
+		return handleConflict(originalPath, cloudPath, action, opts.backupDir, manifest.ModeSymlink)
 
 	default:
 		return linkResultFailed, fmt.Errorf("unexpected symlink status: %v", status)
 	}
 }
 
+// pruneBrokenLink removes originalPath if it's a broken symlink to the
+// file's cloud copy, in this or another storage folder (see
+// isStorageTarget). cloudPath is known to be missing, so other states fail
+// like a missing source does, and broken links made by something else are
+// left alone.
+func pruneBrokenLink(originalPath, cloudPath, storageRel string) (linkResult, error) {
+	status, target, err := symlink.Check(originalPath, cloudPath)
+	if err != nil {
+		return linkResultFailed, err
+	}
+	if status != symlink.StatusBroken {
+		return linkResultFailed, fmt.Errorf("source file not found in cloud storage: %s", cloudPath)
+	}
+	if target != cloudPath && (storageRel == "" || !isStorageTarget(target, storageRel)) {
+		return linkResultFailed, fmt.Errorf("broken symlink points outside storage (%s), not removing it", target)
+	}
+	if err := symlink.Remove(originalPath); err != nil {
+		return linkResultFailed, fmt.Errorf("removing broken symlink: %w", err)
+	}
+	return linkResultPruned, nil
+}
+
+// reportOrphans lists files in storage that no manifest entry tracks.
+func reportOrphans(storagePath string, m *manifest.Manifest) error {
+	orphans, err := findOrphans(storagePath, m)
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		fmt.Println("No untracked files in storage.")
+		return nil
+	}
+	fmt.Println("\nUntracked files in storage:")
+	for _, o := range orphans {
+		fmt.Printf("  %s\n", o)
+	}
+	return nil
+}
+
+// linkCopyFile places a regular copy of cloudPath at originalPath (copy mode).
+// An existing file with the same content is left alone (unchanged), so
+// re-running link doesn't rewrite it or cause sync churn.
+func linkCopyFile(originalPath, cloudPath string, opts linkOptions) (linkResult, error) {
+	if err := ensureSource(originalPath, cloudPath, opts); err != nil {
+		return linkResultFailed, err
+	}
+
+	status, _, err := symlink.Check(originalPath, cloudPath)
+	if err != nil {
+		return linkResultFailed, err
+	}
+
+	switch status {
+	case symlink.StatusNotExist, symlink.StatusParentMissing:
+		if status == symlink.StatusParentMissing && skipParent(originalPath, opts) {
+			return linkResultSkipped, nil
+		}
+		if err := symlink.CopyFile(cloudPath, originalPath); err != nil {
+			return linkResultFailed, err
+		}
+		return linkResultLinked, nil
+
+	case symlink.StatusNotLinked:
+		same, err := symlink.SameContent(originalPath, cloudPath)
+		if err != nil {
+			return linkResultFailed, err
+		}
+		if same {
+			return linkResultUnchanged, nil
+		}
+		action := promptConflictAction(originalPath, opts.autoBackup)
+		return handleConflict(originalPath, cloudPath, action, opts.backupDir, manifest.ModeCopy)
+
+	default:
+		// A symlink is in place (e.g. the file was switched to copy mode) - replace it
+		if err := symlink.Remove(originalPath); err != nil {
+			return linkResultFailed, fmt.Errorf("removing symlink: %w", err)
+		}
+		if err := symlink.CopyFile(cloudPath, originalPath); err != nil {
+			return linkResultFailed, err
+		}
+		return linkResultLinked, nil
+	}
+}
+
+// placeFile puts the cloud file at originalPath according to the link mode.
+func placeFile(originalPath, cloudPath string, mode manifest.LinkMode) error {
+	if mode == manifest.ModeCopy {
+		return symlink.CopyFile(cloudPath, originalPath)
+	}
+	return symlink.Create(originalPath, cloudPath)
+}
+
 type conflictAction int
 
 const (
@@ -206,11 +674,12 @@ func promptConflictAction(path string, autoBackup bool) conflictAction {
 	}
 
 	fmt.Printf("  File exists: %s\n", pathutil.ContractHome(path))
-	fmt.Printf("  [b]ackup and link, [s]kip, [a]bort? ")
-
-	reader := bufio.NewReader(os.Stdin)
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
+	response, err := prompter.Ask("  [b]ackup and link, [s]kip, [a]bort? ")
+	if err != nil {
+		fmt.Println("  No answer, skipping")
+		return conflictSkip
+	}
+	response = strings.ToLower(response)
 
 	switch response {
 	case "b", "backup":
@@ -227,11 +696,11 @@ func promptConflictAction(path string, autoBackup bool) conflictAction {
 }
 
 // handleConflict handles a file conflict based on the chosen action.
-func handleConflict(originalPath, cloudPath string, action conflictAction) (linkResult, error) {
+func handleConflict(originalPath, cloudPath string, action conflictAction, backupDir string, mode manifest.LinkMode) (linkResult, error) {
 	switch action {
 	case conflictBackup:
 		// Backup existing file
-		bk, err := backup.Create(originalPath)
+		bk, err := createBackup(backupDir, originalPath)
 		if err != nil {
 			return linkResultFailed, fmt.Errorf("creating backup: %w", err)
 		}
@@ -239,13 +708,13 @@ func handleConflict(originalPath, cloudPath string, action conflictAction) (link
 
 		// Remove existing file/symlink
 		if err := os.Remove(originalPath); err != nil {
-			bk.Restore()
+			restoreBackup(bk)
 			return linkResultFailed, fmt.Errorf("removing existing file: %w", err)
 		}
 
-		// Create symlink
-		if err := symlink.Create(originalPath, cloudPath); err != nil {
-			bk.Restore()
+		// Create symlink (or copy)
+		if err := placeFile(originalPath, cloudPath, mode); err != nil {
+			restoreBackup(bk)
 			return linkResultFailed, err
 		}
 		return linkResultLinked, nil
@@ -0,0 +1,600 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wtfzambo/dotsync/internal/backup"
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// setupConflict creates an existing original file and a cloud file.
+func setupConflict(t *testing.T) (originalPath, cloudPath, backupDir string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	originalPath = filepath.Join(tmpDir, "home", "config.json")
+	cloudPath = filepath.Join(tmpDir, "storage", "dotsync", "app", "config.json")
+	backupDir = filepath.Join(tmpDir, "backups")
+
+	os.MkdirAll(filepath.Dir(originalPath), 0755)
+	os.MkdirAll(filepath.Dir(cloudPath), 0755)
+	if err := os.WriteFile(originalPath, []byte("local"), 0644); err != nil {
+		t.Fatalf("failed to create original: %v", err)
+	}
+	if err := os.WriteFile(cloudPath, []byte("cloud"), 0644); err != nil {
+		t.Fatalf("failed to create cloud file: %v", err)
+	}
+	return originalPath, cloudPath, backupDir
+}
+
+func listBackups(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := backup.List(dir)
+	if err != nil {
+		t.Fatalf("listing backups: %v", err)
+	}
+	return paths
+}
+
+func TestHandleConflict_Abort(t *testing.T) {
+	originalPath, cloudPath, backupDir := setupConflict(t)
+
+	result, err := handleConflict(originalPath, cloudPath, conflictAbort, backupDir, manifest.ModeSymlink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != linkResultAborted {
+		t.Errorf("result = %v, want %v", result, linkResultAborted)
+	}
+
+	if backups := listBackups(t, backupDir); len(backups) != 0 {
+		t.Errorf("abort should not leave backups, found %v", backups)
+	}
+	content, _ := os.ReadFile(originalPath)
+	if string(content) != "local" {
+		t.Errorf("original content = %q, want %q", content, "local")
+	}
+}
+
+func TestHandleConflict_BackupKeptOnSuccess(t *testing.T) {
+	originalPath, cloudPath, backupDir := setupConflict(t)
+
+	result, err := handleConflict(originalPath, cloudPath, conflictBackup, backupDir, manifest.ModeSymlink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != linkResultLinked {
+		t.Errorf("result = %v, want %v", result, linkResultLinked)
+	}
+
+	status, _, _ := symlink.Check(originalPath, cloudPath)
+	if status != symlink.StatusLinked {
+		t.Errorf("status = %v, want %v", status, symlink.StatusLinked)
+	}
+
+	// The replaced file only survives in the backup, so it must be kept
+	backups := listBackups(t, backupDir)
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, found %v", backups)
+	}
+	content, _ := os.ReadFile(backups[0])
+	if string(content) != "local" {
+		t.Errorf("backup content = %q, want %q", content, "local")
+	}
+}
+
+func TestHandleConflict_BackupRestoredOnFailure(t *testing.T) {
+	originalPath, cloudPath, backupDir := setupConflict(t)
+
+	// Copying a missing cloud file fails after the original was removed
+	os.Remove(cloudPath)
+
+	result, err := handleConflict(originalPath, cloudPath, conflictBackup, backupDir, manifest.ModeCopy)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if result != linkResultFailed {
+		t.Errorf("result = %v, want %v", result, linkResultFailed)
+	}
+
+	content, readErr := os.ReadFile(originalPath)
+	if readErr != nil {
+		t.Fatalf("original not restored: %v", readErr)
+	}
+	if string(content) != "local" {
+		t.Errorf("original content = %q, want %q", content, "local")
+	}
+	if backups := listBackups(t, backupDir); len(backups) != 0 {
+		t.Errorf("restored backup should be removed, found %v", backups)
+	}
+}
+
+// TestRunLink_StorageIsFile tests that a storage path pointing at a file
+// fails with a clear error before any linking
+func TestRunLink_StorageIsFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	storageFile := filepath.Join(home, "storage")
+	if err := os.WriteFile(storageFile, []byte("not a dir"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := config.New(storageFile).Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	err := runLink(linkCmd, nil)
+	if err == nil {
+		t.Fatal("runLink() should fail when storage is a file")
+	}
+	if !strings.Contains(err.Error(), "storage path is not a directory") {
+		t.Errorf("error = %q, want 'storage path is not a directory'", err)
+	}
+	if code := ExitCode(err); code != ExitStorageUnavailable {
+		t.Errorf("ExitCode() = %d, want %d", code, ExitStorageUnavailable)
+	}
+}
+
+// TestRestorePermissions tests that files in private directories lose
+// group/other access and other files are left alone
+func TestRestorePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping permission test on Windows - permissions work differently")
+	}
+
+	tmpDir := t.TempDir()
+	cloudKey := filepath.Join(tmpDir, "storage", "dotsync", "ssh", "id_ed25519")
+	cloudConfig := filepath.Join(tmpDir, "storage", "dotsync", "app", "config.json")
+	for _, path := range []string{cloudKey, cloudConfig} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		// Make sure the mode isn't reduced by the umask
+		os.Chmod(path, 0644)
+	}
+
+	mode, err := restorePermissions(cloudKey, filepath.Join(tmpDir, "home", ".ssh", "id_ed25519"))
+	if err != nil {
+		t.Fatalf("restorePermissions() failed: %v", err)
+	}
+	if mode != 0600 {
+		t.Errorf("returned mode = %04o, want 0600", mode)
+	}
+	info, _ := os.Stat(cloudKey)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key permissions = %04o, want 0600", info.Mode().Perm())
+	}
+
+	// Already restricted: nothing to do
+	if mode, err := restorePermissions(cloudKey, filepath.Join(tmpDir, "home", ".ssh", "id_ed25519")); err != nil || mode != 0 {
+		t.Errorf("second restorePermissions() = %04o, %v, want 0, nil", mode, err)
+	}
+
+	// Not in a private directory: left alone
+	mode, err = restorePermissions(cloudConfig, filepath.Join(tmpDir, "home", ".config", "app", "config.json"))
+	if err != nil || mode != 0 {
+		t.Errorf("restorePermissions() on public file = %04o, %v, want 0, nil", mode, err)
+	}
+	info, _ = os.Stat(cloudConfig)
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("config permissions = %04o, want 0644", info.Mode().Perm())
+	}
+}
+
+// TestRunLink_Repoint tests that symlinks into an old storage root are
+// recreated against the current one
+func TestRunLink_Repoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepOldFS bool // old storage still exists (incorrect vs broken symlink)
+	}{
+		{name: "old storage still present", keepOldFS: true},
+		{name: "old storage gone", keepOldFS: false},
+	}
+
+	linkRepoint = true
+	defer func() { linkRepoint = false }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, originalPath, cloudPath := setupLinkedFile(t)
+
+			oldCloudPath := filepath.Join(home, "old-storage", "dotsync", "app", "config.json")
+			if tt.keepOldFS {
+				os.MkdirAll(filepath.Dir(oldCloudPath), 0755)
+				os.WriteFile(oldCloudPath, []byte("old"), 0644)
+			}
+			os.Remove(originalPath)
+			if err := os.Symlink(oldCloudPath, originalPath); err != nil {
+				t.Fatalf("failed to create symlink: %v", err)
+			}
+
+			if err := runLink(linkCmd, nil); err != nil {
+				t.Fatalf("runLink() failed: %v", err)
+			}
+
+			target, err := os.Readlink(originalPath)
+			if err != nil {
+				t.Fatalf("Readlink() failed: %v", err)
+			}
+			if target != cloudPath {
+				t.Errorf("symlink target = %q, want %q", target, cloudPath)
+			}
+		})
+	}
+}
+
+// TestRunLink_SkipMissingRoot tests that entries whose root doesn't exist
+// are skipped with --skip-missing-root, and linked (creating it) otherwise
+func TestRunLink_SkipMissingRoot(t *testing.T) {
+	for _, skip := range []bool{true, false} {
+		t.Run(fmt.Sprintf("skip=%v", skip), func(t *testing.T) {
+			_, originalPath, cloudPath := setupLinkedFile(t)
+			entryRoot := filepath.Dir(originalPath)
+			os.RemoveAll(entryRoot)
+
+			linkSkipMissingRoot = skip
+			defer func() { linkSkipMissingRoot = false }()
+
+			if err := runLink(linkCmd, nil); err != nil {
+				t.Fatalf("runLink() failed: %v", err)
+			}
+
+			_, err := os.Stat(entryRoot)
+			if skip && !os.IsNotExist(err) {
+				t.Errorf("root %s should not be created (err: %v)", entryRoot, err)
+			}
+			if !skip {
+				if status, _, _ := symlink.Check(originalPath, cloudPath); status != symlink.StatusLinked {
+					t.Errorf("status = %v, want %v", status, symlink.StatusLinked)
+				}
+			}
+		})
+	}
+}
+
+// TestRunLink_RootInsideStorage tests that a root pointing into storage
+// fails instead of linking storage to itself
+func TestRunLink_RootInsideStorage(t *testing.T) {
+	home, _, _ := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := m.Entries["app"]
+	entry.Root = filepath.Join(storagePath, "dotsync", "other")
+	m.Entries["app"] = entry
+	if err := m.Save(storagePath); err != nil {
+		t.Fatal(err)
+	}
+
+	err = runLink(linkCmd, nil)
+	if !errors.Is(err, ErrPartialFailure) {
+		t.Fatalf("runLink() = %v, want ErrPartialFailure", err)
+	}
+	if _, err := os.Lstat(filepath.Join(storagePath, "dotsync", "other", "config.json")); !os.IsNotExist(err) {
+		t.Errorf("a symlink was created inside storage (err: %v)", err)
+	}
+}
+
+// TestIsStorageTarget tests recognizing targets in other storage roots
+func TestIsStorageTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		relPath string
+		want    bool
+	}{
+		{"/mnt/gdrive/dotsync/app/config.json", "config.json", true},
+		{"/mnt/gdrive/dotsync/app/agents/review.md", "agents/review.md", true},
+		{`C:\Users\me\Dropbox\dotsync\app\config.json`, "config.json", true},
+		{"/mnt/gdrive/dotsync/other/config.json", "config.json", false},
+		{"/mnt/gdrive/app/config.json", "config.json", false},
+		{"/mnt/gdrive/dotsync/app/backup-config.json", "config.json", false},
+	}
+
+	for _, tt := range tests {
+		if got := isStorageTarget(tt.target, path.Join("dotsync", "app", tt.relPath)); got != tt.want {
+			t.Errorf("isStorageTarget(%q, dotsync/app/%s) = %v, want %v", tt.target, tt.relPath, got, tt.want)
+		}
+	}
+}
+
+// TestIsStorageTarget_Subpath tests that the storage subpath is part of
+// the expected target
+func TestIsStorageTarget_Subpath(t *testing.T) {
+	m := manifest.New()
+	m.Subpath = "alice"
+	m.AddFile("app", "~/.config/app", "config.json")
+	storageRel := storageRelPath(m, "app", "config.json")
+
+	if !isStorageTarget("/mnt/gdrive/dotsync/alice/app/config.json", storageRel) {
+		t.Error("target under the subpath should match")
+	}
+	if isStorageTarget("/mnt/gdrive/dotsync/app/config.json", storageRel) {
+		t.Error("target outside the subpath should not match")
+	}
+}
+
+// TestLinkFile_PruneBroken tests that broken symlinks to a missing cloud
+// file are removed, and other files are left alone
+func TestLinkFile_PruneBroken(t *testing.T) {
+	tmpDir := t.TempDir()
+	cloudDir := filepath.Join(tmpDir, "storage", "dotsync", "app")
+	homeDir := filepath.Join(tmpDir, "home")
+	os.MkdirAll(cloudDir, 0755)
+	os.MkdirAll(homeDir, 0755)
+	opts := linkOptions{pruneBroken: true}
+
+	// Broken symlink, cloud file gone: pruned
+	broken := filepath.Join(homeDir, "broken.json")
+	os.Symlink(filepath.Join(cloudDir, "broken.json"), broken)
+	result, err := linkFile(broken, filepath.Join(cloudDir, "broken.json"), opts)
+	if result != linkResultPruned {
+		t.Fatalf("broken: linkFile() = %v, want pruned (err: %v)", result, err)
+	}
+	if _, err := os.Lstat(broken); !os.IsNotExist(err) {
+		t.Error("broken symlink should be removed")
+	}
+
+	// Broken symlink into an old storage folder: pruned
+	moved := filepath.Join(homeDir, "moved.json")
+	os.Symlink(filepath.Join(tmpDir, "old-storage", "dotsync", "app", "moved.json"), moved)
+	movedOpts := opts
+	movedOpts.storageRel = filepath.Join("dotsync", "app", "moved.json")
+	if result, err := linkFile(moved, filepath.Join(cloudDir, "moved.json"), movedOpts); result != linkResultPruned {
+		t.Errorf("old storage: linkFile() = %v, want pruned (err: %v)", result, err)
+	}
+
+	// Broken symlink dotsync didn't make: kept, fails
+	foreign := filepath.Join(homeDir, "foreign.json")
+	os.Symlink(filepath.Join(tmpDir, "elsewhere", "foreign.json"), foreign)
+	foreignOpts := opts
+	foreignOpts.storageRel = filepath.Join("dotsync", "app", "foreign.json")
+	result, err = linkFile(foreign, filepath.Join(cloudDir, "foreign.json"), foreignOpts)
+	if result != linkResultFailed || err == nil || !strings.Contains(err.Error(), "outside storage") {
+		t.Errorf("foreign: linkFile() = %v, %v, want failed", result, err)
+	}
+	if _, err := os.Lstat(foreign); err != nil {
+		t.Error("foreign broken symlink should be kept")
+	}
+
+	// Regular file, cloud file gone: kept, fails like a missing source
+	regular := filepath.Join(homeDir, "regular.json")
+	os.WriteFile(regular, []byte("local"), 0644)
+	result, _ = linkFile(regular, filepath.Join(cloudDir, "regular.json"), opts)
+	if result != linkResultFailed {
+		t.Errorf("regular: linkFile() = %v, want failed", result)
+	}
+	if _, err := os.Stat(regular); err != nil {
+		t.Error("regular file should be kept")
+	}
+
+	// Broken symlink, cloud file present: relinked as usual
+	cloudPath := filepath.Join(cloudDir, "config.json")
+	os.WriteFile(cloudPath, []byte("cloud"), 0644)
+	relink := filepath.Join(homeDir, "config.json")
+	os.Symlink(filepath.Join(tmpDir, "old", "config.json"), relink)
+	if result, err := linkFile(relink, cloudPath, opts); result != linkResultLinked {
+		t.Errorf("relink: linkFile() = %v, want linked (err: %v)", result, err)
+	}
+}
+
+// TestLinkFile_MissingSource tests linking when the cloud file is missing
+func TestLinkFile_MissingSource(t *testing.T) {
+	tests := []struct {
+		name                string
+		createMissingSource bool
+		wantResult          linkResult
+	}{
+		{name: "fails by default", createMissingSource: false, wantResult: linkResultFailed},
+		{name: "creates empty source", createMissingSource: true, wantResult: linkResultLinked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			originalPath := filepath.Join(tmpDir, "home", "config.json")
+			cloudPath := filepath.Join(tmpDir, "storage", "dotsync", "app", "config.json")
+
+			result, err := linkFile(originalPath, cloudPath, linkOptions{createMissingSource: tt.createMissingSource})
+			if result != tt.wantResult {
+				t.Fatalf("linkFile() = %v, want %v (err: %v)", result, tt.wantResult, err)
+			}
+
+			if !tt.createMissingSource {
+				if err == nil || !strings.Contains(err.Error(), "source file not found") {
+					t.Errorf("error = %v, want 'source file not found'", err)
+				}
+				if _, err := os.Lstat(cloudPath); !os.IsNotExist(err) {
+					t.Error("cloud file should not be created")
+				}
+				return
+			}
+
+			info, err := os.Stat(cloudPath)
+			if err != nil {
+				t.Fatalf("cloud file not created: %v", err)
+			}
+			if info.Size() != 0 {
+				t.Errorf("cloud file size = %d, want 0", info.Size())
+			}
+			target, err := os.Readlink(originalPath)
+			if err != nil || target != cloudPath {
+				t.Errorf("symlink target = %q (err: %v), want %q", target, err, cloudPath)
+			}
+		})
+	}
+}
+
+// TestLinkFile_ParentMissing tests that a file whose directory doesn't exist
+// is skipped with skipMissingParent, and linked (creating it) otherwise
+func TestLinkFile_ParentMissing(t *testing.T) {
+	tests := []struct {
+		name              string
+		mode              manifest.LinkMode
+		skipMissingParent bool
+		wantResult        linkResult
+	}{
+		{name: "created by default", mode: manifest.ModeSymlink, wantResult: linkResultLinked},
+		{name: "skipped", mode: manifest.ModeSymlink, skipMissingParent: true, wantResult: linkResultSkipped},
+		{name: "copy mode skipped", mode: manifest.ModeCopy, skipMissingParent: true, wantResult: linkResultSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			originalPath := filepath.Join(tmpDir, "home", ".config", "app", "config.json")
+			cloudPath := filepath.Join(tmpDir, "storage", "dotsync", "app", "config.json")
+			os.MkdirAll(filepath.Dir(cloudPath), 0755)
+			os.WriteFile(cloudPath, []byte("cloud"), 0644)
+
+			opts := linkOptions{skipMissingParent: tt.skipMissingParent}
+			var result linkResult
+			var err error
+			if tt.mode == manifest.ModeCopy {
+				result, err = linkCopyFile(originalPath, cloudPath, opts)
+			} else {
+				result, err = linkFile(originalPath, cloudPath, opts)
+			}
+			if result != tt.wantResult {
+				t.Fatalf("result = %v, want %v (err: %v)", result, tt.wantResult, err)
+			}
+
+			_, statErr := os.Stat(filepath.Dir(originalPath))
+			if tt.skipMissingParent && !os.IsNotExist(statErr) {
+				t.Errorf("directory should not be created, stat error: %v", statErr)
+			}
+			if !tt.skipMissingParent && statErr != nil {
+				t.Errorf("directory should be created: %v", statErr)
+			}
+		})
+	}
+}
+
+// TestLinkCopyFile_Unchanged tests that an identical copy isn't rewritten
+func TestLinkCopyFile_Unchanged(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalPath := filepath.Join(tmpDir, "home", "app.plist")
+	cloudPath := filepath.Join(tmpDir, "storage", "dotsync", "app", "app.plist")
+	os.MkdirAll(filepath.Dir(originalPath), 0755)
+	os.MkdirAll(filepath.Dir(cloudPath), 0755)
+	os.WriteFile(originalPath, []byte("same"), 0644)
+	os.WriteFile(cloudPath, []byte("same"), 0644)
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(originalPath, old, old)
+
+	result, err := linkCopyFile(originalPath, cloudPath, linkOptions{})
+	if err != nil {
+		t.Fatalf("linkCopyFile() failed: %v", err)
+	}
+	if result != linkResultUnchanged {
+		t.Errorf("result = %v, want %v", result, linkResultUnchanged)
+	}
+
+	info, err := os.Stat(originalPath)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("local copy was rewritten: mtime %v, want %v", info.ModTime(), old)
+	}
+}
+
+// TestRunLink_DuplicateTargets tests that files sharing an original path
+// are skipped instead of overwriting each other
+func TestRunLink_DuplicateTargets(t *testing.T) {
+	home, originalPath, cloudPath := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	os.Remove(originalPath)
+
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	m.AddFile("home", "~", ".config/app/config.json")
+	otherCloud := filepath.Join(storagePath, "dotsync", "home", ".config", "app", "config.json")
+	os.MkdirAll(filepath.Dir(otherCloud), 0755)
+	os.WriteFile(otherCloud, []byte("other"), 0644)
+	if err := m.Save(storagePath); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	err = runLink(linkCmd, nil)
+	if code := ExitCode(err); code != ExitPartialFailure {
+		t.Errorf("ExitCode(runLink()) = %d, want %d (err: %v)", code, ExitPartialFailure, err)
+	}
+	if _, err := os.Lstat(originalPath); !os.IsNotExist(err) {
+		t.Errorf("%s should not be linked to either file", originalPath)
+	}
+	if _, err := os.Stat(cloudPath); err != nil {
+		t.Errorf("cloud file should be untouched: %v", err)
+	}
+}
+
+// TestRunLink_BackupExistingIntoStorage tests that a replaced file is backed
+// up into the synced .replaced directory without prompting
+func TestRunLink_BackupExistingIntoStorage(t *testing.T) {
+	home, originalPath, cloudPath := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	os.Remove(originalPath)
+	if err := os.WriteFile(originalPath, []byte("existing"), 0644); err != nil {
+		t.Fatalf("failed to create existing file: %v", err)
+	}
+
+	linkBackupIntoStorage = true
+	defer func() { linkBackupIntoStorage = false }()
+	useScript(t)
+
+	if err := runLink(linkCmd, nil); err != nil {
+		t.Fatalf("runLink() failed: %v", err)
+	}
+
+	status, _, _ := symlink.Check(originalPath, cloudPath)
+	if status != symlink.StatusLinked {
+		t.Errorf("status = %v, want %v", status, symlink.StatusLinked)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("Hostname() failed: %v", err)
+	}
+	dir := filepath.Join(backup.ReplacedDir(manifest.DotsyncDir(storagePath, ""), hostname), "app")
+	backups := listBackups(t, dir)
+	if len(backups) != 1 {
+		t.Fatalf("backups in %s = %v, want 1", dir, backups)
+	}
+	if !strings.HasSuffix(backups[0], "-config.json") {
+		t.Errorf("backup name = %q, want timestamped config.json", filepath.Base(backups[0]))
+	}
+	content, _ := os.ReadFile(backups[0])
+	if string(content) != "existing" {
+		t.Errorf("backup content = %q, want %q", content, "existing")
+	}
+
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	orphans, err := findOrphans(storagePath, m)
+	if err != nil {
+		t.Fatalf("findOrphans() failed: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("replaced files reported as untracked: %v", orphans)
+	}
+}
@@ -2,13 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/backup"
+	"github.com/wtfzambo/dotsync/internal/journal"
 	"github.com/wtfzambo/dotsync/internal/manifest"
 	"github.com/wtfzambo/dotsync/internal/pathutil"
 	"github.com/wtfzambo/dotsync/internal/symlink"
@@ -20,41 +24,112 @@ var listCmd = &cobra.Command{
 	Long: `List all tracked entries from the manifest.
 
 Shows entry names, file counts, and link status on this machine.
-Use --details to see individual files within each entry.`,
+Use --details to see individual files within each entry.
+Use --relative-to-storage to also show where each file lives inside
+the storage folder (dotsync/<entry>/<file>).
+Use --expand to print fully resolved absolute paths instead of ~ paths,
+including the original and cloud path of every file.
+Use --only or --except with comma-separated entry names to list a subset.
+
+Use --exit-code to use list as a health probe. It also reports files in
+storage that aren't tracked, and exits with:
+  0  everything is linked and consistent
+  6  some symlinks are broken or incorrect
+  7  some cloud files are missing, or storage has untracked files
+The most severe problem wins. Symlinks of disabled entries aren't checked.
+
+Use --stale <age> to also list files whose cloud copy hasn't been modified
+for at least that long, oldest first. These often belong to apps you no
+longer use. Ages are in days (180d) or Go durations (72h).
+
+Use --size to show how much cloud storage each entry takes (and each
+file, with --details), plus a grand total. This stats every cloud file,
+so it's off by default.
+
+Use --dereference to print the target each symlink actually points to
+under every file (implies --details), e.g. to check links after moving
+storage or to see where an incorrect symlink goes.
+
+Use --changed to compare regular files sitting where a symlink is expected
+with their cloud copy (implies --details). They are marked [modified] if
+relinking would replace local edits, or [matches cloud] if nothing would
+be lost.
+
+Use --broken-only to print just the broken and incorrect symlinks of
+enabled entries, one per line, and exit with 6 if there are any. When
+everything is fine it prints nothing and exits with 0, for cron jobs.
+Other display flags are ignored.`,
 	Example: `  dotsync list           # Show entries overview
-  dotsync list --details # Show all files in each entry`,
+  dotsync list --details # Show all files in each entry
+  dotsync list --details --relative-to-storage
+  dotsync list --expand  # Show absolute original and cloud paths
+  dotsync list --only zsh,git --details
+  dotsync list --exit-code || notify-send "dotsync needs attention"
+  dotsync list --stale 180d # Files untouched for half a year
+  dotsync list --size --details  # Find bloated entries
+  dotsync list --details --dereference  # Show where each symlink points
+  dotsync list --changed  # Check unlinked files for local edits
+  dotsync list --broken-only || notify-send "dotsync links are broken"`,
 	Args: cobra.NoArgs,
 	RunE: runList,
 }
 
-var listDetails bool
+var (
+	listDetails           bool
+	listRelativeToStorage bool
+	listExpand            bool
+	listExitCode          bool
+	listStale             string
+	listSize              bool
+	listDereference       bool
+	listChanged           bool
+	listBrokenOnly        bool
+	listOnly              []string
+	listExcept            []string
+)
 
 func init() {
 	listCmd.Flags().BoolVarP(&listDetails, "details", "d", false, "Show detailed file list for each entry")
+	listCmd.Flags().BoolVarP(&listRelativeToStorage, "relative-to-storage", "s", false, "Show paths relative to the storage folder")
+	listCmd.Flags().BoolVarP(&listExpand, "expand", "e", false, "Show absolute original and cloud paths for each file")
+	listCmd.Flags().BoolVar(&listExitCode, "exit-code", false, "Exit with a non-zero code if files are broken, missing or untracked")
+	listCmd.Flags().BoolVar(&listSize, "size", false, "Show the size of each entry in cloud storage and the total")
+	listCmd.Flags().BoolVar(&listDereference, "dereference", false, "Show the actual target of each symlink (implies --details)")
+	listCmd.Flags().BoolVar(&listChanged, "changed", false, "Mark unlinked files whose content differs from the cloud copy (implies --details)")
+	listCmd.Flags().BoolVar(&listBrokenOnly, "broken-only", false, "Print only broken or incorrect symlinks, and exit non-zero if there are any")
+	listCmd.Flags().StringVar(&listStale, "stale", "", "Also list files not modified for at least this long (e.g. 180d)")
+	listCmd.Flags().StringSliceVar(&listOnly, "only", nil, "List only these entries (comma-separated)")
+	listCmd.Flags().StringSliceVar(&listExcept, "except", nil, "List all entries but these (comma-separated)")
 	rootCmd.AddCommand(listCmd)
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	// 1. Load config (must be initialized)
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
-	}
-	if cfg == nil {
-		return fmt.Errorf("dotsync not initialized. Run 'dotsync init <provider>' first")
+	var staleAge time.Duration
+	if listStale != "" {
+		age, err := parseAge(listStale)
+		if err != nil {
+			return err
+		}
+		staleAge = age
 	}
 
-	storagePath := pathutil.ExpandHome(cfg.StoragePath)
-
-	// Verify storage is available
-	if _, err := os.Stat(storagePath); os.IsNotExist(err) {
-		return fmt.Errorf("storage unavailable: %s\nMake sure your cloud storage is mounted/syncing", storagePath)
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
 	}
+	warnInterruptedAdd(manifest.DotsyncDir(storagePath, cfg.StorageSubpath))
 
 	// 2. Load manifest
-	m, err := manifest.Load(storagePath)
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
 	if err != nil {
 		if strings.Contains(err.Error(), "manifest not found") {
+			if err := checkPopulated(storagePath); err != nil {
+				return err
+			}
+			if listBrokenOnly {
+				return nil
+			}
 			fmt.Println("No entries tracked yet.")
 			fmt.Println("Use 'dotsync add <path>' to start tracking files.")
 			return nil
@@ -63,49 +138,368 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 
 	if len(m.Entries) == 0 {
+		if listBrokenOnly {
+			return nil
+		}
 		fmt.Println("No entries tracked yet.")
 		fmt.Println("Use 'dotsync add <path>' to start tracking files.")
 		return nil
 	}
 
 	// 3. Sort entry names for consistent output
-	names := make([]string, 0, len(m.Entries))
-	for name := range m.Entries {
-		names = append(names, name)
+	selected, err := selectEntries(m, nil, listOnly, listExcept)
+	if err != nil {
+		return err
+	}
+	names := sortedNames(selected)
+
+	if listBrokenOnly {
+		return probeResult(cmd, listBroken(m, names, m.DotsyncDir(storagePath)))
 	}
-	sort.Strings(names)
 
 	// 4. Display entries
+	var total listCounts
+	for _, name := range names {
+		counts := displayEntry(m, name, storagePath, listDisplayOptions{
+			details:           listDetails,
+			relativeToStorage: listRelativeToStorage,
+			expand:            listExpand,
+			size:              listSize,
+			dereference:       listDereference,
+			changed:           listChanged,
+		})
+		if !m.Entries[name].Enabled() {
+			// Disabled entries aren't linked here, so like --broken-only
+			// don't count their symlinks
+			counts.broken, counts.incorrect = 0, 0
+		}
+		total.add(counts)
+	}
+
+	if listSize {
+		fmt.Printf("Total: %s in storage\n\n", pathutil.FormatSize(total.bytes))
+	}
+
+	if listStale != "" {
+		stale, err := findStale(m.DotsyncDir(storagePath), m, time.Now().Add(-staleAge))
+		if err != nil {
+			return err
+		}
+		displayStale(stale, listStale)
+	}
+
+	if !listExitCode {
+		return nil
+	}
+
+	// 5. Report untracked files and pick the exit code
+	orphans, err := findOrphans(storagePath, m)
+	if err != nil {
+		return err
+	}
+	if len(orphans) > 0 {
+		fmt.Println("Untracked files in storage:")
+		for _, o := range orphans {
+			fmt.Printf("  %s\n", o)
+		}
+		fmt.Println()
+	}
+
+	return probeResult(cmd, listHealth(total, len(orphans)))
+}
+
+// listBroken prints the broken and incorrect symlinks of the named entries,
+// one per line, and returns ErrLinksBroken if there are any. Disabled
+// entries are skipped, like link does. Nothing is printed if all is well.
+func listBroken(m *manifest.Manifest, names []string, dotsyncDir string) error {
+	var broken, incorrect int
 	for _, name := range names {
 		entry := m.Entries[name]
-		displayEntry(name, entry, storagePath, listDetails)
+		if !entry.Enabled() {
+			continue
+		}
+		for _, c := range checkEntry(name, entry, dotsyncDir) {
+			label := fmt.Sprintf("%s/%s (%s)", name, c.relPath, pathutil.ContractHome(c.originalPath))
+			switch c.status {
+			case symlink.StatusBroken:
+				fmt.Printf("[broken]    %s\n", label)
+				broken++
+			case symlink.StatusIncorrect:
+				fmt.Printf("[incorrect] %s -> %s\n", label, pathutil.ContractHome(c.target))
+				incorrect++
+			}
+		}
+	}
+
+	if broken > 0 || incorrect > 0 {
+		return markAs(ErrLinksBroken, fmt.Errorf("%d broken, %d incorrect symlink(s)", broken, incorrect))
+	}
+	return nil
+}
+
+// probeResult returns the result of --exit-code or --broken-only. A failed
+// probe is an expected outcome whose details are already printed, so
+// cobra's usage and the error line are left out and only the exit code
+// tells it apart.
+func probeResult(cmd *cobra.Command, err error) error {
+	if err == nil {
+		return nil
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return markAs(errReported, err)
+}
+
+// parseAge parses a --stale age: a number of days ("180d") or a Go
+// duration ("72h").
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid age %q: use days (e.g. 180d) or a duration (e.g. 72h)", s)
+}
+
+// staleFile is a tracked file whose cloud copy hasn't changed in a while.
+type staleFile struct {
+	entry   string
+	relPath string
+	modTime time.Time
+}
+
+// findStale returns the tracked files whose cloud copy was last modified
+// before cutoff, oldest first. Files missing from storage are skipped.
+func findStale(dotsyncDir string, m *manifest.Manifest, cutoff time.Time) ([]staleFile, error) {
+	var stale []staleFile
+	for _, name := range m.Names() {
+		entry := m.Entries[name]
+		for _, relPath := range entry.Files {
+			cloudPath := entry.CloudPath(dotsyncDir, name, relPath)
+			info, err := os.Stat(cloudPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, fmt.Errorf("checking %s: %w", cloudPath, err)
+			}
+			if info.ModTime().Before(cutoff) {
+				stale = append(stale, staleFile{entry: name, relPath: relPath, modTime: info.ModTime()})
+			}
+		}
+	}
+
+	// Stable, so files with the same time stay in entry order
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].modTime.Before(stale[j].modTime)
+	})
+	return stale, nil
+}
+
+// displayStale prints the stale files found by findStale.
+func displayStale(stale []staleFile, age string) {
+	if len(stale) == 0 {
+		fmt.Printf("No files older than %s.\n\n", age)
+		return
+	}
+
+	fmt.Printf("Not modified in %s:\n", age)
+	for _, f := range stale {
+		days := int(time.Since(f.modTime).Hours() / 24)
+		fmt.Printf("  %s/%s (%s, %d days ago)\n", f.entry, f.relPath, f.modTime.Format("2006-01-02"), days)
 	}
+	fmt.Println("\nUse 'dotsync unlink <entry>' to stop using an entry on this machine.")
+	fmt.Println()
+}
+
+// listCounts accumulates file problems found while listing.
+type listCounts struct {
+	broken       int
+	incorrect    int
+	missingCloud int
+	// bytes is the size of the cloud files, only counted with --size
+	bytes int64
+}
 
+func (c *listCounts) add(o listCounts) {
+	c.broken += o.broken
+	c.incorrect += o.incorrect
+	c.missingCloud += o.missingCloud
+	c.bytes += o.bytes
+}
+
+// listHealth returns the error for the most severe problem, or nil if
+// everything is linked and consistent.
+func listHealth(c listCounts, orphans int) error {
+	if c.missingCloud > 0 || orphans > 0 {
+		return markAs(ErrCloudMissing, fmt.Errorf("%d file(s) missing from storage, %d untracked file(s) in storage", c.missingCloud, orphans))
+	}
+	if c.broken > 0 || c.incorrect > 0 {
+		return markAs(ErrLinksBroken, fmt.Errorf("%d broken, %d incorrect symlink(s)", c.broken, c.incorrect))
+	}
 	return nil
 }
 
-// displayEntry prints information about a single entry.
-func displayEntry(name string, entry manifest.Entry, storagePath string, showDetails bool) {
+// orphanExempt lists files dotsync itself keeps at the top of
+// <storage>/dotsync, including the .gitignore from 'init --git-friendly'.
+var orphanExempt = map[string]bool{
+	manifest.ManifestFileName: true,
+	journal.FileName:          true,
+	journal.FileName + ".tmp": true,
+	".gitignore":              true,
+}
+
+// findOrphans returns files inside <storage>/dotsync that aren't tracked in
+// the manifest, relative to <storage>/dotsync and sorted. The manifest, the
+// journal, the .gitignore and the storage backup directory are ignored, and
+// so are the folders of storage subpaths nested inside it.
+func findOrphans(storagePath string, m *manifest.Manifest) ([]string, error) {
+	dotsyncDir := m.DotsyncDir(storagePath)
+
+	tracked := make(map[string]bool)
+	for name, entry := range m.Entries {
+		for _, relPath := range entry.Files {
+			tracked[entry.StorageRelPath(name, relPath)] = true
+		}
+	}
+
+	// Other machines' subpaths nested in this folder aren't ours to report
+	trees, err := manifest.NestedTrees(dotsyncDir)
+	if err != nil {
+		return nil, err
+	}
+	nested := make(map[string]bool, len(trees))
+	for _, tree := range trees {
+		nested[tree] = true
+	}
+
+	var orphans []string
+	err = filepath.WalkDir(dotsyncDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dotsyncDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, err := filepath.Rel(dotsyncDir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == backup.StorageBackupDirName || rel == backup.ReplacedDirName || nested[manifest.ToStorageSlash(rel)] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if orphanExempt[rel] || tracked[rel] {
+			return nil
+		}
+		orphans = append(orphans, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning storage: %w", err)
+	}
+
+	sort.Strings(orphans)
+	return orphans, nil
+}
+
+// listDisplayOptions controls how entries are printed by displayEntry.
+type listDisplayOptions struct {
+	// details prints each file within the entry
+	details bool
+	// relativeToStorage prints paths relative to the storage folder
+	relativeToStorage bool
+	// expand prints absolute paths and implies details
+	expand bool
+	// size prints the size of the cloud files
+	size bool
+	// dereference prints the actual target of each symlink and implies details
+	dereference bool
+	// changed compares unlinked files with their cloud copy and implies details
+	changed bool
+}
+
+// fileCheck is the state of a single tracked file on this machine.
+type fileCheck struct {
+	name         string
+	relPath      string
+	mode         manifest.LinkMode
+	originalPath string
+	cloudPath    string
+	// status is the link status, with a regular file counting as linked
+	// for copy-mode files if it matches the cloud copy
+	status       symlink.Status
+	cloudMissing bool
+	// target is what the symlink at originalPath points to, or empty if it
+	// isn't a symlink
+	target string
+}
+
+// checkEntry returns the state of every file in an entry, in manifest order.
+// dotsyncDir is the folder holding the entry folders (see m.DotsyncDir).
+func checkEntry(name string, entry manifest.Entry, dotsyncDir string) []fileCheck {
+	entryRoot := pathutil.ExpandHome(entry.Root)
+
+	checks := make([]fileCheck, 0, len(entry.Files))
+	for _, relPath := range entry.Files {
+		c := fileCheck{
+			name:         name,
+			relPath:      relPath,
+			mode:         entry.FileMode(relPath),
+			originalPath: filepath.Join(entryRoot, manifest.FromStorageSlash(relPath)),
+			cloudPath:    entry.CloudPath(dotsyncDir, name, relPath),
+		}
+		c.cloudMissing = cloudMissing(c.cloudPath)
+		c.status, c.target, _ = symlink.Check(c.originalPath, c.cloudPath)
+		if c.mode == manifest.ModeCopy && c.status == symlink.StatusNotLinked && copyCurrent(c) {
+			// A regular file is the expected state in copy mode
+			c.status = symlink.StatusLinked
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// copyCurrent reports whether the copy-mode file at c.originalPath matches
+// its cloud copy. Without a cloud copy there is nothing to compare, and the
+// missing cloud file is reported on its own.
+func copyCurrent(c fileCheck) bool {
+	if c.cloudMissing {
+		return true
+	}
+	same, err := symlink.SameContent(c.originalPath, c.cloudPath)
+	return err == nil && same
+}
+
+// displayEntry prints information about a single entry and returns the
+// problems found in it.
+func displayEntry(m *manifest.Manifest, name, storagePath string, opts listDisplayOptions) listCounts {
+	entry := m.Entries[name]
 	entryRoot := pathutil.ExpandHome(entry.Root)
+	dotsyncDir := m.DotsyncDir(storagePath)
 
 	// Count file statuses
 	var linked, notLinked, broken, incorrect int
-	fileStatuses := make([]struct {
-		file   string
-		status symlink.Status
-	}, 0, len(entry.Files))
-
-	for _, relPath := range entry.Files {
-		originalPath := filepath.Join(entryRoot, relPath)
-		cloudPath := filepath.Join(storagePath, "dotsync", name, relPath)
+	var counts listCounts
+	checks := checkEntry(name, entry, dotsyncDir)
+	sizes := make([]int64, len(checks))
 
-		status, _, _ := symlink.Check(originalPath, cloudPath)
-		fileStatuses = append(fileStatuses, struct {
-			file   string
-			status symlink.Status
-		}{relPath, status})
+	for i, c := range checks {
+		if c.cloudMissing {
+			counts.missingCloud++
+		} else if opts.size {
+			if info, err := os.Stat(c.cloudPath); err == nil {
+				sizes[i] = info.Size()
+				counts.bytes += sizes[i]
+			}
+		}
 
-		switch status {
+		switch c.status {
 		case symlink.StatusLinked:
 			linked++
 		case symlink.StatusNotLinked:
@@ -114,7 +508,7 @@ func displayEntry(name string, entry manifest.Entry, storagePath string, showDet
 			broken++
 		case symlink.StatusIncorrect:
 			incorrect++
-		case symlink.StatusNotExist:
+		case symlink.StatusNotExist, symlink.StatusParentMissing:
 			notLinked++
 		}
 	}
@@ -122,18 +516,111 @@ func displayEntry(name string, entry manifest.Entry, storagePath string, showDet
 	// Print entry header
 	totalFiles := len(entry.Files)
 	statusSummary := formatStatusSummary(linked, notLinked, broken, incorrect, totalFiles)
-	fmt.Printf("%s (%s)\n", name, entry.Root)
-	fmt.Printf("  %d file(s) - %s\n", totalFiles, statusSummary)
+	var marker string
+	if !entry.Enabled() {
+		marker = " [disabled]"
+	}
+	if opts.expand {
+		fmt.Printf("%s (%s -> %s)%s\n", name, entryRoot, entry.CloudPath(dotsyncDir, name, ""), marker)
+	} else if opts.relativeToStorage {
+		fmt.Printf("%s (%s -> %s)%s\n", name, entry.Root, storageRelPath(m, name, ""), marker)
+	} else {
+		fmt.Printf("%s (%s)%s\n", name, entry.Root, marker)
+	}
+	if entry.Description != "" {
+		fmt.Printf("  %s\n", entry.Description)
+	}
+	if opts.size {
+		fmt.Printf("  %d file(s), %s - %s\n", totalFiles, pathutil.FormatSize(counts.bytes), statusSummary)
+	} else {
+		fmt.Printf("  %d file(s) - %s\n", totalFiles, statusSummary)
+	}
 
 	// Print file details if requested
-	if showDetails {
-		for _, fs := range fileStatuses {
+	if opts.details || opts.expand || opts.dereference || opts.changed {
+		for i, fs := range checks {
 			statusIcon := statusIcon(fs.status)
-			fmt.Printf("    %s %s\n", statusIcon, fs.file)
+			file := fs.relPath
+			if opts.expand {
+				file = fs.originalPath
+			}
+			if fs.mode == manifest.ModeCopy && fs.status == symlink.StatusNotLinked {
+				file += " (copy, differs from cloud)"
+			} else if fs.mode == manifest.ModeCopy {
+				file += " (copy)"
+			}
+			if opts.size && !fs.cloudMissing {
+				file += fmt.Sprintf(" [%s]", pathutil.FormatSize(sizes[i]))
+			}
+			if opts.changed {
+				if note := changedNote(fs); note != "" {
+					file += " " + note
+				}
+			}
+			if opts.expand {
+				fmt.Printf("    %s %s -> %s\n", statusIcon, file, fs.cloudPath)
+			} else if opts.relativeToStorage {
+				fmt.Printf("    %s %s -> %s\n", statusIcon, file, storageRelPath(m, name, fs.relPath))
+			} else {
+				fmt.Printf("    %s %s\n", statusIcon, file)
+			}
+			if opts.dereference {
+				fmt.Printf("        %s\n", targetNote(fs))
+			}
 		}
 	}
 
 	fmt.Println()
+
+	counts.broken = broken
+	counts.incorrect = incorrect
+	return counts
+}
+
+// targetNote describes where the file at c.originalPath points, for
+// --dereference.
+func targetNote(c fileCheck) string {
+	switch {
+	case c.status == symlink.StatusIncorrect:
+		return fmt.Sprintf("=> %s (expected %s)", c.target, c.cloudPath)
+	case c.status == symlink.StatusBroken:
+		return fmt.Sprintf("=> %s (missing)", c.target)
+	case c.target != "":
+		return "=> " + c.target
+	case c.mode == manifest.ModeCopy && c.status == symlink.StatusNotLinked:
+		return "(copy mode, differs from cloud)"
+	case c.mode == manifest.ModeCopy:
+		return "(copy mode, no symlink)"
+	case c.status == symlink.StatusNotLinked:
+		return "(regular file, not a symlink)"
+	default:
+		return "(no symlink)"
+	}
+}
+
+// changedNote tells whether a regular file where a symlink (or a current
+// copy) is expected differs from its cloud copy, for --changed. Returns ""
+// for other files and when there is no cloud copy to compare with.
+func changedNote(c fileCheck) string {
+	if c.status != symlink.StatusNotLinked || c.cloudMissing {
+		return ""
+	}
+	same, err := symlink.SameContent(c.originalPath, c.cloudPath)
+	switch {
+	case err != nil:
+		return "[unreadable]"
+	case same:
+		return "[matches cloud]"
+	default:
+		return "[modified]"
+	}
+}
+
+// storageRelPath returns the path of a file of entry name relative to the
+// storage folder.
+// Structure: dotsync/[<subpath>/]<storage dir>/<relPath>
+func storageRelPath(m *manifest.Manifest, name, relPath string) string {
+	return filepath.Join("dotsync", manifest.FromStorageSlash(m.Subpath), m.Entries[name].StorageRelPath(name, relPath))
 }
 
 // formatStatusSummary creates a summary string of file statuses.
@@ -169,7 +656,7 @@ func statusIcon(status symlink.Status) string {
 		return "[ok]     "
 	case symlink.StatusNotLinked:
 		return "[not lnk]"
-	case symlink.StatusNotExist:
+	case symlink.StatusNotExist, symlink.StatusParentMissing:
 		return "[missing]"
 	case symlink.StatusBroken:
 		return "[broken] "
@@ -0,0 +1,520 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/journal"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// setupLinkedFile initializes dotsync in a temporary home with one tracked
+// and linked file. Returns the original and cloud paths.
+func setupLinkedFile(t *testing.T) (home, originalPath, cloudPath string) {
+	t.Helper()
+	home = t.TempDir()
+	t.Setenv("HOME", home)
+	storagePath := filepath.Join(home, "storage")
+
+	originalPath = filepath.Join(home, ".config", "app", "config.json")
+	cloudPath = filepath.Join(storagePath, "dotsync", "app", "config.json")
+	os.MkdirAll(filepath.Dir(originalPath), 0755)
+	os.MkdirAll(filepath.Dir(cloudPath), 0755)
+	if err := os.WriteFile(cloudPath, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create cloud file: %v", err)
+	}
+	if err := os.Symlink(cloudPath, originalPath); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	m := manifest.New()
+	m.AddFile("app", "~/.config/app", "config.json")
+	if err := m.Save(storagePath); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+	if err := config.New(storagePath).Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	return home, originalPath, cloudPath
+}
+
+// TestRunList_ExitCode tests the --exit-code result for each health state
+func TestRunList_ExitCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, home, originalPath, cloudPath string)
+		want  int
+	}{
+		{
+			name:  "all linked",
+			setup: func(t *testing.T, home, originalPath, cloudPath string) {},
+			want:  ExitOK,
+		},
+		{
+			name: "incorrect symlink",
+			setup: func(t *testing.T, home, originalPath, cloudPath string) {
+				other := filepath.Join(home, "other.json")
+				os.WriteFile(other, []byte("other"), 0644)
+				os.Remove(originalPath)
+				os.Symlink(other, originalPath)
+			},
+			want: ExitLinksBroken,
+		},
+		{
+			name: "missing cloud file",
+			setup: func(t *testing.T, home, originalPath, cloudPath string) {
+				os.Remove(cloudPath)
+			},
+			want: ExitCloudMissing,
+		},
+		{
+			name: "untracked file in storage",
+			setup: func(t *testing.T, home, originalPath, cloudPath string) {
+				os.WriteFile(filepath.Join(filepath.Dir(cloudPath), "stray.json"), []byte("stray"), 0644)
+			},
+			want: ExitCloudMissing,
+		},
+		{
+			name: "missing cloud wins over incorrect symlink",
+			setup: func(t *testing.T, home, originalPath, cloudPath string) {
+				other := filepath.Join(home, "other.json")
+				os.WriteFile(other, []byte("other"), 0644)
+				os.Remove(originalPath)
+				os.Symlink(other, originalPath)
+				os.Remove(cloudPath)
+			},
+			want: ExitCloudMissing,
+		},
+		{
+			name: "incorrect symlink of disabled entry",
+			setup: func(t *testing.T, home, originalPath, cloudPath string) {
+				other := filepath.Join(home, "other.json")
+				os.WriteFile(other, []byte("other"), 0644)
+				os.Remove(originalPath)
+				os.Symlink(other, originalPath)
+
+				storagePath := filepath.Join(home, "storage")
+				m, err := manifest.Load(storagePath, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				m.SetEnabled("app", false)
+				if err := m.Save(storagePath); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: ExitOK,
+		},
+	}
+
+	listExitCode = true
+	defer func() { listExitCode = false }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, originalPath, cloudPath := setupLinkedFile(t)
+			tt.setup(t, home, originalPath, cloudPath)
+
+			err := runList(listCmd, nil)
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("ExitCode(runList()) = %d, want %d (err: %v)", got, tt.want, err)
+			}
+			if err != nil && !Reported(err) {
+				t.Errorf("Reported(%v) = false, want true", err)
+			}
+		})
+	}
+}
+
+// captureStdout returns what fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-out
+}
+
+// TestRunList_BrokenOnly tests that --broken-only prints only problems and
+// is silent when everything is linked
+func TestRunList_BrokenOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(home, originalPath, cloudPath string)
+		want     int
+		wantLine string
+	}{
+		{
+			name:  "all linked",
+			setup: func(home, originalPath, cloudPath string) {},
+			want:  ExitOK,
+		},
+		{
+			name: "unlinked file is not broken",
+			setup: func(home, originalPath, cloudPath string) {
+				os.Remove(originalPath)
+			},
+			want: ExitOK,
+		},
+		{
+			name: "broken symlink",
+			setup: func(home, originalPath, cloudPath string) {
+				os.Remove(cloudPath)
+			},
+			want:     ExitLinksBroken,
+			wantLine: "[broken]    app/config.json (~/.config/app/config.json)",
+		},
+		{
+			name: "incorrect symlink",
+			setup: func(home, originalPath, cloudPath string) {
+				other := filepath.Join(home, "other.json")
+				os.WriteFile(other, []byte("other"), 0644)
+				os.Remove(originalPath)
+				os.Symlink(other, originalPath)
+			},
+			want:     ExitLinksBroken,
+			wantLine: "[incorrect] app/config.json (~/.config/app/config.json) -> ~/other.json",
+		},
+	}
+
+	// Run through cobra, so its usage and error output are checked too
+	var stderr bytes.Buffer
+	rootCmd.SetArgs([]string{"list", "--broken-only"})
+	rootCmd.SetOut(&stderr)
+	rootCmd.SetErr(&stderr)
+	defer func() {
+		listBrokenOnly = false
+		listCmd.SilenceUsage, listCmd.SilenceErrors = false, false
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, originalPath, cloudPath := setupLinkedFile(t)
+			tt.setup(home, originalPath, cloudPath)
+			stderr.Reset()
+
+			var err error
+			out := captureStdout(t, func() { err = Execute() })
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("ExitCode(Execute()) = %d, want %d (err: %v)", got, tt.want, err)
+			}
+			if err != nil && !Reported(err) {
+				t.Errorf("Reported(%v) = false, want true", err)
+			}
+			if tt.wantLine == "" && out != "" {
+				t.Errorf("output = %q, want nothing", out)
+			}
+			if tt.wantLine != "" && strings.TrimSpace(out) != tt.wantLine {
+				t.Errorf("output = %q, want %q", out, tt.wantLine)
+			}
+			if stderr.Len() > 0 {
+				t.Errorf("cobra output = %q, want nothing", stderr.String())
+			}
+		})
+	}
+}
+
+// TestRunList_StorageNotMounted tests that storage whose mount point is
+// empty or gone is reported as unavailable, not as having nothing tracked
+func TestRunList_StorageNotMounted(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(storagePath string)
+		want  int
+	}{
+		{
+			name: "empty mount point",
+			setup: func(storagePath string) {
+				os.RemoveAll(storagePath)
+				os.Mkdir(storagePath, 0755)
+			},
+			want: ExitStorageUnavailable,
+		},
+		{
+			name:  "mount point gone",
+			setup: func(storagePath string) { os.RemoveAll(storagePath) },
+			want:  ExitStorageUnavailable,
+		},
+		{
+			name: "nothing tracked yet",
+			setup: func(storagePath string) {
+				os.RemoveAll(filepath.Join(storagePath, "dotsync"))
+				os.MkdirAll(filepath.Join(storagePath, "dotsync"), 0755)
+			},
+			want: ExitOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, _, _ := setupLinkedFile(t)
+			tt.setup(filepath.Join(home, "storage"))
+
+			err := runList(listCmd, nil)
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("ExitCode(runList()) = %d, want %d (err: %v)", got, tt.want, err)
+			}
+			if tt.want == ExitStorageUnavailable {
+				if got := ExitCode(runLink(linkCmd, nil)); got != tt.want {
+					t.Errorf("ExitCode(runLink()) = %d, want %d", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestDisplayEntry_Size tests that cloud file sizes are only summed with --size
+func TestDisplayEntry_Size(t *testing.T) {
+	home, _, _ := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	// A file missing from storage doesn't add to the size
+	m.AddFile("app", "~/.config/app", "missing.json")
+
+	if counts := displayEntry(m, "app", storagePath, listDisplayOptions{}); counts.bytes != 0 {
+		t.Errorf("bytes = %d without --size, want 0", counts.bytes)
+	}
+	counts := displayEntry(m, "app", storagePath, listDisplayOptions{size: true, details: true})
+	if counts.bytes != int64(len("content")) {
+		t.Errorf("bytes = %d, want %d", counts.bytes, len("content"))
+	}
+}
+
+// TestTargetNote tests describing symlink targets for --dereference
+func TestTargetNote(t *testing.T) {
+	home, originalPath, cloudPath := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	checks := checkEntry("app", m.Entries["app"], m.DotsyncDir(storagePath))
+	if got, want := targetNote(checks[0]), "=> "+cloudPath; got != want {
+		t.Errorf("targetNote() linked = %q, want %q", got, want)
+	}
+
+	// An incorrect symlink shows both where it points and where it should
+	other := filepath.Join(home, "elsewhere.json")
+	os.WriteFile(other, []byte("x"), 0644)
+	os.Remove(originalPath)
+	os.Symlink(other, originalPath)
+	checks = checkEntry("app", m.Entries["app"], m.DotsyncDir(storagePath))
+	if got, want := targetNote(checks[0]), fmt.Sprintf("=> %s (expected %s)", other, cloudPath); got != want {
+		t.Errorf("targetNote() incorrect = %q, want %q", got, want)
+	}
+
+	os.Remove(originalPath)
+	checks = checkEntry("app", m.Entries["app"], m.DotsyncDir(storagePath))
+	if got := targetNote(checks[0]); got != "(no symlink)" {
+		t.Errorf("targetNote() missing = %q, want %q", got, "(no symlink)")
+	}
+}
+
+// TestCheckEntry_CopyMode tests that a copy-mode file only counts as linked
+// while it matches the cloud copy
+func TestCheckEntry_CopyMode(t *testing.T) {
+	home, originalPath, cloudPath := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	m.SetFileMode("app", "config.json", manifest.ModeCopy)
+	os.Remove(originalPath)
+	if err := symlink.CopyFile(cloudPath, originalPath); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := checkEntry("app", m.Entries["app"], m.DotsyncDir(storagePath))
+	if checks[0].status != symlink.StatusLinked {
+		t.Errorf("current copy status = %v, want %v", checks[0].status, symlink.StatusLinked)
+	}
+
+	os.WriteFile(originalPath, []byte("edited"), 0644)
+	checks = checkEntry("app", m.Entries["app"], m.DotsyncDir(storagePath))
+	if checks[0].status != symlink.StatusNotLinked {
+		t.Errorf("diverged copy status = %v, want %v", checks[0].status, symlink.StatusNotLinked)
+	}
+	if got, want := targetNote(checks[0]), "(copy mode, differs from cloud)"; got != want {
+		t.Errorf("targetNote() diverged copy = %q, want %q", got, want)
+	}
+}
+
+// TestChangedNote tests marking unlinked files that differ from the cloud copy
+func TestChangedNote(t *testing.T) {
+	home, originalPath, _ := setupLinkedFile(t)
+	storagePath := filepath.Join(home, "storage")
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	checks := checkEntry("app", m.Entries["app"], m.DotsyncDir(storagePath))
+	if got := changedNote(checks[0]); got != "" {
+		t.Errorf("changedNote() linked = %q, want empty", got)
+	}
+
+	os.Remove(originalPath)
+	os.WriteFile(originalPath, []byte("content"), 0644)
+	checks = checkEntry("app", m.Entries["app"], m.DotsyncDir(storagePath))
+	if got := changedNote(checks[0]); got != "[matches cloud]" {
+		t.Errorf("changedNote() same content = %q, want %q", got, "[matches cloud]")
+	}
+
+	os.WriteFile(originalPath, []byte("local edit"), 0644)
+	checks = checkEntry("app", m.Entries["app"], m.DotsyncDir(storagePath))
+	if got := changedNote(checks[0]); got != "[modified]" {
+		t.Errorf("changedNote() edited = %q, want %q", got, "[modified]")
+	}
+}
+
+// TestFindOrphans tests that the manifest and backups aren't reported
+func TestFindOrphans(t *testing.T) {
+	storagePath := t.TempDir()
+	dotsyncDir := filepath.Join(storagePath, "dotsync")
+
+	files := []string{
+		manifest.ManifestFileName,
+		journal.FileName,
+		journal.FileName + ".tmp",
+		".gitignore",
+		filepath.Join(".backups", "config.json.20260101-000000.bak"),
+		filepath.Join("app", "config.json"),
+		filepath.Join("app", "old.json"),
+		filepath.Join("gone", "file"),
+		filepath.Join("editors", "nvim", "init.lua"),
+		filepath.Join("nvim", "init.lua"),
+		// Another machine's storage subpath
+		filepath.Join("team", "alice", manifest.ManifestFileName),
+		filepath.Join("team", "alice", "app", "config.json"),
+	}
+	for _, f := range files {
+		path := filepath.Join(dotsyncDir, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+	}
+
+	m := manifest.New()
+	m.AddFile("app", "~/.config/app", "config.json")
+	// Tracked through its storage folder, not its name
+	m.Entries["nvim"] = manifest.Entry{Root: "~/.config/nvim", Storage: "editors/nvim", Files: []string{"init.lua"}}
+
+	orphans, err := findOrphans(storagePath, m)
+	if err != nil {
+		t.Fatalf("findOrphans() failed: %v", err)
+	}
+
+	want := []string{filepath.Join("app", "old.json"), filepath.Join("gone", "file"), filepath.Join("nvim", "init.lua")}
+	if len(orphans) != len(want) {
+		t.Fatalf("orphans = %v, want %v", orphans, want)
+	}
+	for i := range want {
+		if orphans[i] != want[i] {
+			t.Errorf("orphans[%d] = %q, want %q", i, orphans[i], want[i])
+		}
+	}
+}
+
+// TestParseAge tests parsing --stale ages
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"180d", 180 * 24 * time.Hour, false},
+		{"0d", 0, false},
+		{"72h", 72 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"d", 0, true},
+		{"-5d", 0, true},
+		{"-1h", 0, true},
+		{"6mo", 0, true},
+		{"180", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAge(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAge(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestFindStale tests finding files not modified since a cutoff
+func TestFindStale(t *testing.T) {
+	storagePath := t.TempDir()
+	now := time.Now()
+
+	files := []struct {
+		entry, relPath string
+		age            time.Duration
+	}{
+		{"app", "config.json", 400 * 24 * time.Hour},
+		{"app", "recent.json", time.Hour},
+		{"old", "settings.toml", 200 * 24 * time.Hour},
+		// Same age: sorted by entry name
+		{"zsh", ".zshrc", 300 * 24 * time.Hour},
+		{"bash", ".bashrc", 300 * 24 * time.Hour},
+	}
+	m := manifest.New()
+	for _, f := range files {
+		path := filepath.Join(storagePath, "dotsync", f.entry, f.relPath)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+		mtime := now.Add(-f.age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Chtimes() failed: %v", err)
+		}
+		m.AddFile(f.entry, "~/.config/"+f.entry, f.relPath)
+	}
+	// Missing from storage: skipped
+	m.AddFile("app", "~/.config/app", "missing.json")
+
+	stale, err := findStale(m.DotsyncDir(storagePath), m, now.Add(-180*24*time.Hour))
+	if err != nil {
+		t.Fatalf("findStale() failed: %v", err)
+	}
+
+	want := []string{"app/config.json", "bash/.bashrc", "zsh/.zshrc", "old/settings.toml"}
+	if len(stale) != len(want) {
+		t.Fatalf("findStale() = %v, want %v", stale, want)
+	}
+	for i, f := range stale {
+		if got := f.entry + "/" + f.relPath; got != want[i] {
+			t.Errorf("stale[%d] = %q, want %q", i, got, want[i])
+		}
+	}
+}
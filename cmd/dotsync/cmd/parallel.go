@@ -0,0 +1,33 @@
+package cmd
+
+import "sync"
+
+// runParallel calls fn(i) for every i in [0, n) on at most workers
+// goroutines and waits for all calls to finish. With workers <= 1 the calls
+// run in order on the calling goroutine.
+//
+// fn must only write to state owned by index i (e.g. results[i]), so
+// callers can print results in order once runParallel returns.
+func runParallel(n, workers int, fn func(i int)) {
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, n) {
+		wg.Go(func() {
+			for i := range jobs {
+				fn(i)
+			}
+		})
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// TestRunParallel tests that every index is visited once and that no more
+// than the requested number of workers run at the same time
+func TestRunParallel(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 100} {
+		const n = 50
+		var visits [n]int32
+		var running, peak int32
+
+		runParallel(n, workers, func(i int) {
+			cur := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if cur <= p || atomic.CompareAndSwapInt32(&peak, p, cur) {
+					break
+				}
+			}
+			atomic.AddInt32(&visits[i], 1)
+			atomic.AddInt32(&running, -1)
+		})
+
+		for i, v := range visits {
+			if v != 1 {
+				t.Errorf("workers=%d: index %d visited %d times, want 1", workers, i, v)
+			}
+		}
+		if limit := int32(max(workers, 1)); peak > limit {
+			t.Errorf("workers=%d: %d calls ran at once", workers, peak)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Prompter asks the user questions. Commands never read stdin directly but
+// go through the package-level prompter, so interactive flows can be
+// driven by a script in tests, or answered without a TTY.
+type Prompter interface {
+	// Confirm asks a yes/no question. Anything but yes counts as no.
+	Confirm(question string) bool
+	// Ask prints prompt and returns the trimmed answer. Returns an error
+	// if no answer can be read (e.g. stdin is closed).
+	Ask(prompt string) (string, error)
+}
+
+// prompter is used by all prompts. Replaced by --non-interactive and tests.
+var prompter Prompter = &readerPrompter{r: stdinReader, w: os.Stdout}
+
+// errNonInteractive is returned by Ask when prompting is disabled.
+var errNonInteractive = errors.New("input required, but running with --non-interactive")
+
+// readerPrompter reads answers line by line from r. If ctx is set, a
+// prompt waiting for input returns ctx's error once it is cancelled.
+type readerPrompter struct {
+	r   *bufio.Reader
+	w   io.Writer
+	ctx context.Context
+}
+
+func (p *readerPrompter) Confirm(question string) bool {
+	answer, err := p.Ask(question + " [y/N]: ")
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+func (p *readerPrompter) Ask(prompt string) (string, error) {
+	fmt.Fprint(p.w, prompt)
+	if p.ctx == nil {
+		return p.readLine()
+	}
+	if err := p.ctx.Err(); err != nil {
+		fmt.Fprintln(p.w)
+		return "", err
+	}
+
+	type answer struct {
+		line string
+		err  error
+	}
+	answers := make(chan answer, 1)
+	go func() {
+		line, err := p.readLine()
+		answers <- answer{line, err}
+	}()
+
+	select {
+	case a := <-answers:
+		return a.line, a.err
+	case <-p.ctx.Done():
+		fmt.Fprintln(p.w)
+		return "", p.ctx.Err()
+	}
+}
+
+func (p *readerPrompter) readLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// nonInteractivePrompter answers no to every question and fails anything
+// that needs real input, for scripts and CI.
+type nonInteractivePrompter struct {
+	w io.Writer
+}
+
+func (p nonInteractivePrompter) Confirm(question string) bool {
+	fmt.Fprintf(p.w, "%s [y/N]: n (non-interactive)\n", question)
+	return false
+}
+
+func (p nonInteractivePrompter) Ask(prompt string) (string, error) {
+	fmt.Fprintln(p.w, prompt)
+	return "", errNonInteractive
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/storage"
+)
+
+// scriptedPrompter answers prompts from a fixed list, in order. Running
+// out of answers behaves like a closed stdin.
+type scriptedPrompter struct {
+	answers []string
+	asked   []string
+}
+
+func (p *scriptedPrompter) Confirm(question string) bool {
+	answer, err := p.Ask(question)
+	if err != nil {
+		return false
+	}
+	return answer == "y" || answer == "yes"
+}
+
+func (p *scriptedPrompter) Ask(prompt string) (string, error) {
+	p.asked = append(p.asked, prompt)
+	if len(p.answers) == 0 {
+		return "", io.EOF
+	}
+	answer := p.answers[0]
+	p.answers = p.answers[1:]
+	return answer, nil
+}
+
+// useScript replaces the prompter with a scriptedPrompter for the rest of
+// the test.
+func useScript(t *testing.T, answers ...string) *scriptedPrompter {
+	t.Helper()
+	orig := prompter
+	t.Cleanup(func() { prompter = orig })
+	p := &scriptedPrompter{answers: answers}
+	prompter = p
+	return p
+}
+
+// TestReaderPrompter tests reading answers line by line
+func TestReaderPrompter(t *testing.T) {
+	var out strings.Builder
+	p := &readerPrompter{r: bufio.NewReader(strings.NewReader("yes\n  name  \nlast")), w: &out}
+
+	if !p.Confirm("Continue?") {
+		t.Error("Confirm() = false, want true for 'yes'")
+	}
+	if got, err := p.Ask("Entry name: "); err != nil || got != "name" {
+		t.Errorf("Ask() = %q, %v, want %q", got, err, "name")
+	}
+	// A final line without a newline is still an answer
+	if got, err := p.Ask("Path: "); err != nil || got != "last" {
+		t.Errorf("Ask() = %q, %v, want %q", got, err, "last")
+	}
+	if _, err := p.Ask("More: "); err == nil {
+		t.Error("Ask() should fail once input is exhausted")
+	}
+	if p.Confirm("Again?") {
+		t.Error("Confirm() = true, want false once input is exhausted")
+	}
+	if !strings.Contains(out.String(), "Continue? [y/N]: ") {
+		t.Errorf("output = %q, want the question with [y/N]", out.String())
+	}
+}
+
+// TestReaderPrompter_Cancel tests that cancelling the context ends a prompt
+// waiting for input
+func TestReaderPrompter_Cancel(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &readerPrompter{r: bufio.NewReader(r), w: io.Discard, ctx: ctx}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.Ask("Entry name: ")
+		errs <- err
+	}()
+	cancel()
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Ask() error = %v, want context.Canceled", err)
+	}
+	if p.Confirm("Continue?") {
+		t.Error("Confirm() = true after cancel, want false")
+	}
+}
+
+// TestNonInteractivePrompter tests that nothing is read or accepted
+func TestNonInteractivePrompter(t *testing.T) {
+	p := nonInteractivePrompter{w: io.Discard}
+	if p.Confirm("Continue?") {
+		t.Error("Confirm() = true, want false")
+	}
+	if _, err := p.Ask("Entry name: "); err != errNonInteractive {
+		t.Errorf("Ask() error = %v, want %v", err, errNonInteractive)
+	}
+}
+
+// TestLinkFile_ConflictPrompt tests each answer to the link conflict prompt
+func TestLinkFile_ConflictPrompt(t *testing.T) {
+	tests := []struct {
+		answer      string
+		wantResult  linkResult
+		wantLinked  bool
+		wantBackups int
+	}{
+		{"b", linkResultLinked, true, 1},
+		{"s", linkResultSkipped, false, 0},
+		{"a", linkResultAborted, false, 0},
+		{"?", linkResultSkipped, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			originalPath, cloudPath, backupDir := setupConflict(t)
+			useScript(t, tt.answer)
+
+			result, err := linkFile(originalPath, cloudPath, linkOptions{backupDir: backupDir})
+			if err != nil {
+				t.Fatalf("linkFile() failed: %v", err)
+			}
+			if result != tt.wantResult {
+				t.Errorf("result = %v, want %v", result, tt.wantResult)
+			}
+
+			_, err = os.Readlink(originalPath)
+			if linked := err == nil; linked != tt.wantLinked {
+				t.Errorf("linked = %v, want %v", linked, tt.wantLinked)
+			}
+			if got := len(listBackups(t, backupDir)); got != tt.wantBackups {
+				t.Errorf("backups = %d, want %d", got, tt.wantBackups)
+			}
+		})
+	}
+}
+
+// TestConfirmAddPlan tests renaming and skipping an inferred entry
+func TestConfirmAddPlan(t *testing.T) {
+	storagePath := t.TempDir()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	absPath := filepath.Join(home, ".config", "app", "config.json")
+	m := manifest.New()
+
+	plan, err := planAdd(absPath, absPath, "", storagePath, m)
+	if err != nil {
+		t.Fatalf("planAdd() failed: %v", err)
+	}
+
+	// An invalid name is asked again, then the file is renamed and accepted
+	p := useScript(t, "r", "bad/name", "r", "renamed", "a")
+	got, err := confirmAddPlan(absPath, absPath, storagePath, m, plan)
+	if err != nil {
+		t.Fatalf("confirmAddPlan() failed: %v", err)
+	}
+	if got.entryName != "renamed" {
+		t.Errorf("entryName = %q, want %q", got.entryName, "renamed")
+	}
+	if len(p.answers) != 0 {
+		t.Errorf("unused answers: %v", p.answers)
+	}
+
+	useScript(t, "s")
+	if got, err := confirmAddPlan(absPath, absPath, storagePath, m, plan); err != nil || got.entryName != "" {
+		t.Errorf("skip: confirmAddPlan() = %q, %v, want empty entry", got.entryName, err)
+	}
+
+	useScript(t)
+	if _, err := confirmAddPlan(absPath, absPath, storagePath, m, plan); err != ErrAborted {
+		t.Errorf("closed input: error = %v, want %v", err, ErrAborted)
+	}
+}
+
+// TestChooseProvider tests picking one of the detected providers
+func TestChooseProvider(t *testing.T) {
+	detected := []storage.Detected{
+		{Provider: storage.ProviderDropbox, Path: "/home/u/Dropbox"},
+		{Provider: storage.ProviderGoogleDrive, Path: "/home/u/Google Drive"},
+	}
+
+	tests := []struct {
+		answer  string
+		want    string
+		wantErr bool
+	}{
+		{answer: "2", want: "/home/u/Google Drive"},
+		{answer: "1", want: "/home/u/Dropbox"},
+		{answer: "3", wantErr: true},
+		{answer: "dropbox", wantErr: true},
+		{answer: "q", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			useScript(t, tt.answer)
+			got, err := chooseProvider(detected)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("chooseProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("chooseProvider() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
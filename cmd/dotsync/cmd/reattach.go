@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/backup"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+var reattachCmd = &cobra.Command{
+	Use:   "reattach <path>",
+	Short: "Re-link a tracked file that was replaced by a regular file",
+	Long: `Re-establish the symlink for a tracked file that an editor or app
+replaced with a regular file (e.g. by saving atomically).
+
+The local file's content is moved into cloud storage, overwriting the
+cloud copy, and the symlink is recreated. Your latest edits are kept.
+The previous cloud copy is backed up until the operation succeeds.
+
+This is safe to run from an editor save hook: files that are already
+linked are left alone.`,
+	Example: `  dotsync reattach ~/.zshrc
+  dotsync reattach ~/.config/opencode/config.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReattach,
+}
+
+func init() {
+	rootCmd.AddCommand(reattachCmd)
+}
+
+func runReattach(cmd *cobra.Command, args []string) error {
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// 2. Load manifest
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		if strings.Contains(err.Error(), "manifest not found") {
+			return fmt.Errorf("no manifest found. Nothing to reattach")
+		}
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+
+	// 3. Find the tracked file
+	absPath, err := pathutil.AbsolutePath(args[0])
+	if err != nil {
+		return fmt.Errorf("resolving path: %w", err)
+	}
+
+	name, relPath := findTracked(absPath, m)
+	if name == "" {
+		return fmt.Errorf("not tracked: %s\nUse 'dotsync add' to start tracking it", pathutil.ContractHome(absPath))
+	}
+	entry := m.GetEntry(name)
+	if entry.FileMode(relPath) == manifest.ModeCopy {
+		return fmt.Errorf("%s is tracked in copy mode and is meant to be a regular file", pathutil.ContractHome(absPath))
+	}
+
+	cloudPath := m.CloudPath(storagePath, name, relPath)
+
+	// 4. Only regular files need reattaching
+	status, _, err := symlink.Check(absPath, cloudPath)
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", absPath, err)
+	}
+	switch status {
+	case symlink.StatusLinked:
+		fmt.Printf("Already linked: %s\n", pathutil.ContractHome(absPath))
+		return nil
+	case symlink.StatusNotExist, symlink.StatusParentMissing:
+		return fmt.Errorf("file doesn't exist: %s\nUse 'dotsync link %s' to restore the symlink", pathutil.ContractHome(absPath), name)
+	case symlink.StatusBroken, symlink.StatusIncorrect:
+		return markAs(ErrConflict, fmt.Errorf("%s is a symlink (%s), not a replaced file\nUse 'dotsync link %s' to fix it", pathutil.ContractHome(absPath), status, name))
+	}
+
+	// 5. Back up the current cloud copy, if any
+	var bk *backup.Backup
+	if !cloudMissing(cloudPath) {
+		bk, err = createBackup(backupDirFor(cfg, storagePath), cloudPath)
+		if err != nil {
+			return fmt.Errorf("creating backup: %w", err)
+		}
+	}
+
+	// 6. Move the new content into cloud storage and recreate the symlink
+	fmt.Printf("Moving to cloud storage and linking: %s -> %s\n", pathutil.ContractHome(absPath), pathutil.ContractHome(cloudPath))
+	if err := symlink.Reattach(absPath, cloudPath); err != nil {
+		// The edited file is back in place, restore the old cloud copy
+		if bk != nil {
+			restoreBackup(bk)
+		}
+		return err
+	}
+
+	if bk != nil {
+		discardBackup(bk)
+	}
+	fmt.Printf("Reattached '%s' in entry '%s'\n", relPath, name)
+	return nil
+}
+
+// findTracked returns the entry name and relative path of a tracked file,
+// or empty strings if absPath isn't tracked.
+func findTracked(absPath string, m *manifest.Manifest) (string, string) {
+	for _, name := range m.Names() {
+		entry := m.Entries[name]
+		entryRoot := pathutil.ExpandHome(entry.Root)
+		for _, relPath := range entry.Files {
+			if filepath.Join(entryRoot, manifest.FromStorageSlash(relPath)) == absPath {
+				return name, relPath
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// TestRunReattach tests that a tracked file replaced by a regular file is
+// moved into storage and linked again, keeping the new content
+func TestRunReattach(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	storagePath := filepath.Join(home, "storage")
+
+	originalPath := filepath.Join(home, ".config", "app", "config.json")
+	cloudPath := filepath.Join(storagePath, "dotsync", "app", "config.json")
+	if err := os.MkdirAll(filepath.Dir(originalPath), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cloudPath), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	os.WriteFile(cloudPath, []byte("old"), 0644)
+	// An editor replaced the symlink with a regular file
+	os.WriteFile(originalPath, []byte("new"), 0644)
+
+	m := manifest.New()
+	m.AddFile("app", "~/.config/app", "config.json")
+	if err := m.Save(storagePath); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+	if err := config.New(storagePath).Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	if err := runReattach(reattachCmd, []string{originalPath}); err != nil {
+		t.Fatalf("runReattach() failed: %v", err)
+	}
+
+	status, _, err := symlink.Check(originalPath, cloudPath)
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	if status != symlink.StatusLinked {
+		t.Errorf("status = %v, want %v", status, symlink.StatusLinked)
+	}
+	if content, _ := os.ReadFile(cloudPath); string(content) != "new" {
+		t.Errorf("cloud content = %q, want %q", content, "new")
+	}
+
+	// Running again on a linked file is a no-op
+	if err := runReattach(reattachCmd, []string{originalPath}); err != nil {
+		t.Errorf("runReattach() on linked file failed: %v", err)
+	}
+}
+
+// TestRunReattach_NotTracked tests that untracked files are refused
+func TestRunReattach_NotTracked(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	storagePath := filepath.Join(home, "storage")
+
+	if err := manifest.New().Save(storagePath); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+	if err := config.New(storagePath).Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	path := filepath.Join(home, ".zshrc")
+	os.WriteFile(path, []byte("content"), 0644)
+
+	if err := runReattach(reattachCmd, []string{path}); err == nil {
+		t.Error("runReattach() should fail for an untracked file")
+	}
+}
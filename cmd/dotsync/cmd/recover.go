@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/backup"
+	"github.com/wtfzambo/dotsync/internal/journal"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "Finish or roll back an interrupted add",
+	Long: `Recover from a 'dotsync add' that was interrupted (killed, power loss)
+after moving a file to cloud storage but before recording it in the manifest.
+
+add keeps a journal (<storage>/dotsync/.journal) of files it is moving.
+For each file in it:
+  - if the symlink is in place, the file is recorded in the manifest
+  - otherwise the file is put back at its original location
+The journal is synced with the storage, so files that another machine
+was adding are skipped: run 'dotsync recover' on that machine. Other
+commands warn when the journal holds files of this machine.`,
+	Example: `  dotsync recover`,
+	Args:    cobra.NoArgs,
+	RunE:    runRecover,
+}
+
+func init() {
+	rootCmd.AddCommand(recoverCmd)
+}
+
+func runRecover(cmd *cobra.Command, args []string) error {
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// 2. Read the journal
+	dotsyncDir := manifest.DotsyncDir(storagePath, cfg.StorageSubpath)
+	all, err := journal.Read(dotsyncDir)
+	if err != nil {
+		return err
+	}
+	ops, foreign := journal.Split(all)
+	for _, op := range foreign {
+		fmt.Printf("  [skipped]     %s (added on %s, run 'dotsync recover' there)\n", op.OriginalPath, op.Hostname)
+	}
+	if len(ops) == 0 {
+		fmt.Println("Nothing to recover")
+		return nil
+	}
+
+	// 3. Load manifest (the interrupted add may have been the first one)
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		if !strings.Contains(err.Error(), "manifest not found") {
+			return fmt.Errorf("loading manifest: %w", err)
+		}
+		m = manifest.New()
+		m.Subpath = cfg.StorageSubpath
+	}
+
+	// 4. Complete or roll back each file
+	var failedOps []journal.Op
+	var completed int
+	for _, op := range ops {
+		result, err := recoverOp(m, op)
+		switch result {
+		case recoverResultCompleted:
+			fmt.Printf("  [added]       %s (entry '%s')\n", pathutil.ContractHome(op.OriginalPath), op.Entry)
+			completed++
+		case recoverResultRecorded:
+			fmt.Printf("  [ok]          %s (already in the manifest)\n", pathutil.ContractHome(op.OriginalPath))
+		case recoverResultRolledBack:
+			fmt.Printf("  [rolled back] %s\n", pathutil.ContractHome(op.OriginalPath))
+		case recoverResultFailed:
+			fmt.Printf("  [failed]      %s: %v\n", pathutil.ContractHome(op.OriginalPath), err)
+			failedOps = append(failedOps, op)
+		}
+	}
+
+	// 5. Save the manifest before dropping the journal, so a crash here
+	// can be recovered again
+	if completed > 0 {
+		if err := m.Save(storagePath); err != nil {
+			return fmt.Errorf("saving manifest: %w", err)
+		}
+	}
+	if err := journal.Write(dotsyncDir, append(failedOps, foreign...)); err != nil {
+		return err
+	}
+
+	if len(failedOps) > 0 {
+		return markAs(ErrPartialFailure, fmt.Errorf("some files could not be recovered; they stay in the journal"))
+	}
+	return nil
+}
+
+type recoverResult int
+
+const (
+	recoverResultCompleted  recoverResult = iota // symlink in place, recorded in the manifest now
+	recoverResultRecorded                        // the manifest was saved before the interruption
+	recoverResultRolledBack                      // the file is back at its original location
+	recoverResultFailed
+)
+
+// recoverOp finishes an interrupted add of a single file. A file whose
+// symlink is in place is recorded in m; anything less is rolled back so
+// the original file is a regular file again. The caller saves m.
+func recoverOp(m *manifest.Manifest, op journal.Op) (recoverResult, error) {
+	bk := &backup.Backup{OriginalPath: op.OriginalPath, BackupPath: op.BackupPath}
+
+	if name, _ := findTracked(op.OriginalPath, m); name != "" {
+		discardRecoveredBackup(bk)
+		return recoverResultRecorded, nil
+	}
+
+	status, target, err := symlink.Check(op.OriginalPath, op.CloudPath)
+	if err != nil {
+		return recoverResultFailed, err
+	}
+
+	if status == symlink.StatusLinked {
+		m.AddFile(op.Entry, op.Root, op.RelPath)
+		discardRecoveredBackup(bk)
+		return recoverResultCompleted, nil
+	}
+
+	// Remove a symlink created by the add (e.g. the cloud file is gone)
+	if status == symlink.StatusBroken && target == op.CloudPath {
+		if err := symlink.Remove(op.OriginalPath); err != nil {
+			return recoverResultFailed, fmt.Errorf("removing symlink: %w", err)
+		}
+		status = symlink.StatusNotExist
+	}
+
+	switch status {
+	case symlink.StatusNotExist, symlink.StatusParentMissing:
+		// Interrupted after the move: put the file back
+		if !cloudMissing(op.CloudPath) {
+			if err := symlink.MoveFile(op.CloudPath, op.OriginalPath); err != nil {
+				return recoverResultFailed, fmt.Errorf("moving file back: %w", err)
+			}
+		} else if op.BackupPath != "" {
+			if err := bk.Restore(); err != nil {
+				return recoverResultFailed, err
+			}
+			return recoverResultRolledBack, nil
+		} else {
+			return recoverResultFailed, fmt.Errorf("file is missing from both its original location and cloud storage")
+		}
+
+	case symlink.StatusNotLinked:
+		// Interrupted before or during the move: the original is intact, a
+		// copy in cloud storage is a leftover of this add
+		if !cloudMissing(op.CloudPath) {
+			same, err := symlink.SameContent(op.OriginalPath, op.CloudPath)
+			if err != nil {
+				return recoverResultFailed, err
+			}
+			if !same {
+				return recoverResultFailed, fmt.Errorf("cloud file differs from the original, remove one of them by hand: %s", op.CloudPath)
+			}
+			if err := os.Remove(op.CloudPath); err != nil {
+				return recoverResultFailed, fmt.Errorf("removing cloud copy: %w", err)
+			}
+		}
+
+	default:
+		return recoverResultFailed, fmt.Errorf("unexpected file at original location (%s)", status)
+	}
+
+	discardRecoveredBackup(bk)
+	return recoverResultRolledBack, nil
+}
+
+// discardRecoveredBackup removes the backup of a recovered file, if any.
+func discardRecoveredBackup(bk *backup.Backup) {
+	if bk.BackupPath == "" {
+		return
+	}
+	if _, err := os.Stat(bk.BackupPath); err != nil {
+		return
+	}
+	discardBackup(bk)
+}
+
+// warnInterruptedAdd prints a warning if an add was interrupted and needs
+// 'dotsync recover'.
+func warnInterruptedAdd(dotsyncDir string) {
+	if journal.Exists(dotsyncDir) {
+		fmt.Println("Warning: an interrupted 'dotsync add' was found. Run 'dotsync recover' to finish it.")
+	}
+}
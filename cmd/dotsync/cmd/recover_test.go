@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/journal"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// TestRecoverOp tests finishing an add interrupted at each step
+func TestRecoverOp(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup leaves the files as the interrupted add did
+		setup      func(t *testing.T, op journal.Op, m *manifest.Manifest)
+		wantResult recoverResult
+		wantLinked bool
+	}{
+		{
+			name: "interrupted before the move",
+			setup: func(t *testing.T, op journal.Op, m *manifest.Manifest) {
+				os.WriteFile(op.OriginalPath, []byte("content"), 0644)
+			},
+			wantResult: recoverResultRolledBack,
+		},
+		{
+			name: "interrupted while copying to storage",
+			setup: func(t *testing.T, op journal.Op, m *manifest.Manifest) {
+				os.WriteFile(op.OriginalPath, []byte("content"), 0644)
+				os.WriteFile(op.CloudPath, []byte("content"), 0644)
+			},
+			wantResult: recoverResultRolledBack,
+		},
+		{
+			name: "interrupted after the move",
+			setup: func(t *testing.T, op journal.Op, m *manifest.Manifest) {
+				os.WriteFile(op.CloudPath, []byte("content"), 0644)
+			},
+			wantResult: recoverResultRolledBack,
+		},
+		{
+			name: "interrupted before saving the manifest",
+			setup: func(t *testing.T, op journal.Op, m *manifest.Manifest) {
+				os.WriteFile(op.CloudPath, []byte("content"), 0644)
+				os.Symlink(op.CloudPath, op.OriginalPath)
+			},
+			wantResult: recoverResultCompleted,
+			wantLinked: true,
+		},
+		{
+			name: "interrupted before clearing the journal",
+			setup: func(t *testing.T, op journal.Op, m *manifest.Manifest) {
+				os.WriteFile(op.CloudPath, []byte("content"), 0644)
+				os.Symlink(op.CloudPath, op.OriginalPath)
+				m.AddFile(op.Entry, op.Root, op.RelPath)
+			},
+			wantResult: recoverResultRecorded,
+			wantLinked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			op := journal.Op{
+				Entry:        "app",
+				Root:         "~/.config/app",
+				RelPath:      "config.json",
+				OriginalPath: filepath.Join(home, ".config", "app", "config.json"),
+				CloudPath:    filepath.Join(home, "storage", "dotsync", "app", "config.json"),
+			}
+			os.MkdirAll(filepath.Dir(op.OriginalPath), 0755)
+			os.MkdirAll(filepath.Dir(op.CloudPath), 0755)
+
+			m := manifest.New()
+			tt.setup(t, op, m)
+
+			result, err := recoverOp(m, op)
+			if result != tt.wantResult {
+				t.Fatalf("recoverOp() = %v, want %v (err: %v)", result, tt.wantResult, err)
+			}
+
+			if name, _ := findTracked(op.OriginalPath, m); (name != "") != tt.wantLinked {
+				t.Errorf("tracked in manifest = %v, want %v", name != "", tt.wantLinked)
+			}
+
+			status, _, _ := symlink.Check(op.OriginalPath, op.CloudPath)
+			if tt.wantLinked {
+				if status != symlink.StatusLinked {
+					t.Errorf("status = %v, want %v", status, symlink.StatusLinked)
+				}
+				return
+			}
+			if status != symlink.StatusNotLinked {
+				t.Errorf("status = %v, want a regular file", status)
+			}
+			if content, _ := os.ReadFile(op.OriginalPath); string(content) != "content" {
+				t.Errorf("original content = %q, want %q", content, "content")
+			}
+			if !cloudMissing(op.CloudPath) {
+				t.Error("cloud copy should be removed on rollback")
+			}
+		})
+	}
+}
+
+// TestAddPath_Journal tests that add journals a move until the manifest is
+// saved, and that recover completes it after an interruption
+func TestAddPath_Journal(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	storagePath := filepath.Join(home, "storage")
+	absPath := filepath.Join(home, ".config", "app", "config.json")
+	os.MkdirAll(filepath.Dir(absPath), 0755)
+	os.WriteFile(absPath, []byte("content"), 0644)
+
+	cfg := config.New(storagePath)
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	addYes = true
+	defer func() { addYes = false }()
+
+	// Staged but the manifest not saved yet, as if killed here
+	added, err := addPath(absPath, cfg, storagePath, manifest.New())
+	if err != nil || added == nil {
+		t.Fatalf("addPath() = %v, %v", added, err)
+	}
+	ops, err := journal.Read(manifest.DotsyncDir(storagePath, ""))
+	if err != nil || len(ops) != 1 || ops[0].OriginalPath != absPath {
+		t.Fatalf("journal = %+v (err: %v), want the staged file", ops, err)
+	}
+
+	if err := runRecover(recoverCmd, nil); err != nil {
+		t.Fatalf("runRecover() failed: %v", err)
+	}
+
+	if journal.Exists(manifest.DotsyncDir(storagePath, "")) {
+		t.Error("journal should be cleared after recovery")
+	}
+	m, err := manifest.Load(storagePath, "")
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if name, _ := findTracked(absPath, m); name != "app" {
+		t.Errorf("recovered file tracked in entry %q, want %q", name, "app")
+	}
+}
+
+// TestRecover_ForeignHost tests that recover leaves files another machine
+// was adding alone, and that they don't block add here
+func TestRecover_ForeignHost(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	storagePath := filepath.Join(home, "storage")
+	if err := config.New(storagePath).Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	// The other machine moved its file into storage and was interrupted;
+	// the same path doesn't exist on this machine
+	originalPath := filepath.Join(home, ".zshrc")
+	cloudPath := filepath.Join(storagePath, "dotsync", "zsh", ".zshrc")
+	os.MkdirAll(filepath.Dir(cloudPath), 0755)
+	os.WriteFile(cloudPath, []byte("content"), 0644)
+	op := journal.Op{Entry: "zsh", Root: "~", RelPath: ".zshrc", OriginalPath: originalPath, CloudPath: cloudPath, Hostname: "other-machine"}
+	if err := journal.Write(manifest.DotsyncDir(storagePath, ""), []journal.Op{op}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runRecover(recoverCmd, nil); err != nil {
+		t.Fatalf("runRecover() failed: %v", err)
+	}
+	if _, err := os.Stat(cloudPath); err != nil {
+		t.Errorf("cloud file of the other machine was moved: %v", err)
+	}
+	if _, err := os.Lstat(originalPath); !os.IsNotExist(err) {
+		t.Errorf("file was restored on the wrong machine (err: %v)", err)
+	}
+	if ops, _ := journal.Read(manifest.DotsyncDir(storagePath, "")); len(ops) != 1 || ops[0] != op {
+		t.Errorf("journal = %+v, want the other machine's operation kept", ops)
+	}
+
+	// add isn't blocked by another machine's interrupted add
+	absPath := filepath.Join(home, ".gitconfig")
+	os.WriteFile(absPath, []byte("[user]"), 0644)
+	addYes = true
+	defer func() { addYes = false }()
+	if err := runAdd(addCmd, []string{absPath}); err != nil {
+		t.Fatalf("runAdd() failed: %v", err)
+	}
+	if ops, _ := journal.Read(manifest.DotsyncDir(storagePath, "")); len(ops) != 1 || ops[0] != op {
+		t.Errorf("journal after add = %+v, want the other machine's operation kept", ops)
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/storage"
+)
+
+var reinitCmd = &cobra.Command{
+	Use:   "reinit --path <new-storage>",
+	Short: "Point dotsync at storage that moved",
+	Long: `Point the config at the new location of the same cloud folder, e.g.
+after a new mount point or a renamed account, and repoint the symlinks
+that still go into the old location.
+
+The new location must already hold the dotsync folder and manifest; its
+contents are used as they are. Other config settings are kept. Unlike
+'dotsync init', nothing is set up from scratch, and unlike moving files
+between storages, nothing in storage is copied or changed.
+
+Symlinks that don't point into a dotsync storage folder are left alone;
+run 'dotsync link' afterwards for files that aren't linked at all.`,
+	Example: `  dotsync reinit --path /Volumes/NewDrive/Dropbox
+  dotsync reinit --path "~/Library/CloudStorage/GoogleDrive-me@example.com/My Drive"`,
+	Args: cobra.NoArgs,
+	RunE: runReinit,
+}
+
+var reinitPath string
+
+func init() {
+	reinitCmd.Flags().StringVarP(&reinitPath, "path", "p", "", "New location of the storage folder (required)")
+	rootCmd.AddCommand(reinitCmd)
+}
+
+func runReinit(cmd *cobra.Command, args []string) error {
+	if reinitPath == "" {
+		return fmt.Errorf("--path is required: the new location of the storage folder")
+	}
+
+	// 1. Load config, without requiring the old storage to be available
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if cfg == nil {
+		return ErrNotInitialized
+	}
+	subpath, err := manifest.CleanSubpath(cfg.StorageSubpath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	// 2. The new location must hold the existing manifest
+	if err := storage.ValidatePath(reinitPath); err != nil {
+		return err
+	}
+	storagePath := storage.ExpandPath(reinitPath)
+	m, err := manifest.Load(storagePath, subpath)
+	if err != nil {
+		if strings.Contains(err.Error(), "manifest not found") {
+			return fmt.Errorf("no dotsync manifest in %s\nUse 'dotsync init' to set up new storage", pathutil.ContractHome(storagePath))
+		}
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+
+	// 3. Save the new location
+	oldPath := cfg.StoragePath
+	cfg.StoragePath = reinitPath
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	fmt.Printf("Storage: %s -> %s\n", oldPath, reinitPath)
+
+	// 4. Repoint symlinks into the old location
+	repointed, failed := repointAll(m, storagePath)
+	fmt.Printf("\nSummary: %d repointed, %d failed\n", repointed, failed)
+	if failed > 0 {
+		return markAs(ErrPartialFailure, fmt.Errorf("some symlinks could not be repointed"))
+	}
+	fmt.Println("Run 'dotsync link' to link any files that aren't linked yet.")
+	return nil
+}
+
+// repointAll repoints the symlinks of every enabled entry that go into
+// another dotsync storage location (see repointFile), and returns how many
+// were repointed and how many failed.
+func repointAll(m *manifest.Manifest, storagePath string) (repointed, failed int) {
+	for _, name := range m.Names() {
+		entry := m.Entries[name]
+		if !entry.Enabled() {
+			continue
+		}
+		entryRoot := pathutil.ExpandHome(entry.Root)
+		for _, relPath := range entry.Files {
+			if entry.FileMode(relPath) == manifest.ModeCopy {
+				continue
+			}
+			originalPath := filepath.Join(entryRoot, manifest.FromStorageSlash(relPath))
+			cloudPath := m.CloudPath(storagePath, name, relPath)
+			oldTarget, err := repointFile(originalPath, cloudPath, storageRelPath(m, name, relPath))
+			switch {
+			case err != nil:
+				fmt.Printf("  [failed]    %s/%s: %v\n", name, relPath, err)
+				failed++
+			case oldTarget != "":
+				fmt.Printf("  [repointed] %s/%s (was %s)\n", name, relPath, pathutil.ContractHome(oldTarget))
+				repointed++
+			}
+		}
+	}
+	return repointed, failed
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+// TestRunReinit_Repoint tests that symlinks into the old storage location
+// are repointed and the config is updated once storage has moved
+func TestRunReinit_Repoint(t *testing.T) {
+	home, originalPath, _ := setupLinkedFile(t)
+	oldStorage := filepath.Join(home, "storage")
+	newStorage := filepath.Join(home, "mnt", "storage")
+
+	// A second, unlinked file stays unlinked
+	m, err := manifest.Load(oldStorage, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddFile("app", "~/.config/app", "other.json")
+	m.Save(oldStorage)
+	os.WriteFile(filepath.Join(oldStorage, "dotsync", "app", "other.json"), []byte("other"), 0644)
+
+	os.MkdirAll(filepath.Dir(newStorage), 0755)
+	if err := os.Rename(oldStorage, newStorage); err != nil {
+		t.Fatal(err)
+	}
+
+	reinitPath = newStorage
+	defer func() { reinitPath = "" }()
+	if err := runReinit(reinitCmd, nil); err != nil {
+		t.Fatalf("runReinit() error = %v", err)
+	}
+
+	target, err := os.Readlink(originalPath)
+	if err != nil {
+		t.Fatalf("Readlink() failed: %v", err)
+	}
+	if want := filepath.Join(newStorage, "dotsync", "app", "config.json"); target != want {
+		t.Errorf("symlink target = %q, want %q", target, want)
+	}
+	if _, err := os.Lstat(filepath.Join(home, ".config", "app", "other.json")); !os.IsNotExist(err) {
+		t.Errorf("unlinked file should be left alone (err: %v)", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.StoragePath != newStorage {
+		t.Errorf("config storage path = %q, want %q", cfg.StoragePath, newStorage)
+	}
+}
+
+// TestRunReinit_NoManifest tests that a location without a manifest is
+// refused and the config is left alone
+func TestRunReinit_NoManifest(t *testing.T) {
+	home, originalPath, cloudPath := setupLinkedFile(t)
+	empty := filepath.Join(home, "empty")
+	os.Mkdir(empty, 0755)
+
+	reinitPath = empty
+	defer func() { reinitPath = "" }()
+	if err := runReinit(reinitCmd, nil); err == nil {
+		t.Fatal("runReinit() should fail without a manifest")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, "storage"); cfg.StoragePath != want {
+		t.Errorf("config storage path = %q, want %q", cfg.StoragePath, want)
+	}
+	if target, _ := os.Readlink(originalPath); target != cloudPath {
+		t.Errorf("symlink target = %q, want %q", target, cloudPath)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+)
+
+// reportPath is where --report writes the run summary (add, link, unlink).
+var reportPath string
+
+// opReport collects what the running command did, for --report. It is nil
+// unless --report was given, and its methods do nothing on nil, so commands
+// record results unconditionally.
+var opReport *runReport
+
+// runReport is the JSON summary of one dotsync invocation. Unlike the inline
+// summary it lists every file, so runs can be audited across machines.
+type runReport struct {
+	Command  string         `json:"command"`
+	Args     []string       `json:"args,omitempty"`
+	Hostname string         `json:"hostname"`
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	ExitCode int            `json:"exitCode"`
+	Error    string         `json:"error,omitempty"`
+	Summary  map[string]int `json:"summary"`
+	Files    []reportFile   `json:"files"`
+}
+
+// reportFile is the outcome of one file. Result is the word shown inline,
+// e.g. "linked", "skipped" or "failed".
+type reportFile struct {
+	Entry   string `json:"entry,omitempty"`
+	Path    string `json:"path"`
+	Result  string `json:"result"`
+	Message string `json:"message,omitempty"`
+}
+
+// addReportFlag registers --report on a command that records its results.
+func addReportFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&reportPath, "report", "", "Write a JSON summary of every file handled to this path")
+}
+
+// startReport begins collecting results for cmd if --report was given.
+func startReport(cmd *cobra.Command, args []string) {
+	if reportPath == "" {
+		return
+	}
+	hostname, _ := os.Hostname()
+	opReport = &runReport{
+		Command:  cmd.Name(),
+		Args:     args,
+		Hostname: hostname,
+		Started:  time.Now(),
+		Summary:  make(map[string]int),
+		Files:    []reportFile{},
+	}
+}
+
+// file records the result of one file. err or msg explain it, if any.
+func (r *runReport) file(entry, path, result string, err error, msg string) {
+	if r == nil {
+		return
+	}
+	if err != nil {
+		msg = err.Error()
+	}
+	r.Files = append(r.Files, reportFile{Entry: entry, Path: path, Result: result, Message: msg})
+	r.Summary[result]++
+}
+
+// finishReport writes the collected report, if any, with the outcome of the
+// command. A report that can't be written fails an otherwise successful run.
+func finishReport(runErr error) error {
+	r := opReport
+	if r == nil {
+		return runErr
+	}
+	opReport = nil
+
+	r.Finished = time.Now()
+	r.ExitCode = ExitCode(runErr)
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+
+	err := writeReport(pathutil.ExpandHome(reportPath), r)
+	switch {
+	case err == nil:
+		return runErr
+	case runErr == nil:
+		return fmt.Errorf("writing report: %w", err)
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: writing report: %v\n", err)
+		return runErr
+	}
+}
+
+func writeReport(path string, r *runReport) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useReport sets --report to a file in a temp dir for the test
+func useReport(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "report.json")
+	reportPath = path
+	t.Cleanup(func() {
+		reportPath = ""
+		opReport = nil
+	})
+	return path
+}
+
+func readReport(t *testing.T, path string) runReport {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var r runReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	return r
+}
+
+// TestReport tests collecting and writing a run report
+func TestReport(t *testing.T) {
+	path := useReport(t)
+
+	startReport(linkCmd, []string{"app"})
+	opReport.file("app", "config.json", "linked", nil, "")
+	opReport.file("app", "other.json", "failed", fmt.Errorf("boom"), "")
+	runErr := markAs(ErrPartialFailure, fmt.Errorf("some files failed to link"))
+	if err := finishReport(runErr); err != runErr {
+		t.Fatalf("finishReport() = %v, want the run error", err)
+	}
+	if opReport != nil {
+		t.Error("report should be reset after finishing")
+	}
+
+	r := readReport(t, path)
+	if r.Command != "link" || len(r.Args) != 1 || r.Hostname == "" {
+		t.Errorf("report header = %+v", r)
+	}
+	if r.ExitCode != ExitPartialFailure || r.Error == "" {
+		t.Errorf("exitCode = %d, error = %q", r.ExitCode, r.Error)
+	}
+	if r.Finished.Before(r.Started) {
+		t.Errorf("finished %v before started %v", r.Finished, r.Started)
+	}
+	if r.Summary["linked"] != 1 || r.Summary["failed"] != 1 {
+		t.Errorf("summary = %v", r.Summary)
+	}
+	if len(r.Files) != 2 || r.Files[1].Message != "boom" {
+		t.Errorf("files = %+v", r.Files)
+	}
+}
+
+// TestReport_Disabled tests that recording without --report is a no-op
+func TestReport_Disabled(t *testing.T) {
+	useReport(t)
+	reportPath = ""
+
+	startReport(linkCmd, nil)
+	opReport.file("app", "config.json", "linked", nil, "")
+	if err := finishReport(nil); err != nil {
+		t.Errorf("finishReport() = %v, want nil", err)
+	}
+}
+
+// TestRunLink_Report tests that link records every file in the report
+func TestRunLink_Report(t *testing.T) {
+	setupLinkedFile(t)
+	path := useReport(t)
+
+	startReport(linkCmd, nil)
+	if err := finishReport(runLink(linkCmd, nil)); err != nil {
+		t.Fatalf("runLink() failed: %v", err)
+	}
+
+	r := readReport(t, path)
+	if len(r.Files) != 1 {
+		t.Fatalf("files = %+v, want 1", r.Files)
+	}
+	if f := r.Files[0]; f.Entry != "app" || f.Path != "config.json" || f.Result != "ok" {
+		t.Errorf("file = %+v, want app/config.json ok", f)
+	}
+}
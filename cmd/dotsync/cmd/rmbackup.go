@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/backup"
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+)
+
+var rmBackupCmd = &cobra.Command{
+	Use:   "rm-backup",
+	Short: "Remove leftover backups",
+	Long: `Remove backup files left behind by dotsync operations.
+
+Backups are normally restored or removed automatically. Files can be left
+behind when an operation is interrupted, when a rollback fails, when
+"dotsync link" backs up a conflicting file, or when --keep-backups is used.
+
+Both the local backup directory (~/.cache/dotsync/backups) and, if
+initialized, the storage backup directory (<storage>/dotsync/.backups)
+are cleaned.`,
+	Example: `  dotsync rm-backup        # List backups and confirm removal
+  dotsync rm-backup --yes  # Remove without prompting`,
+	Args: cobra.NoArgs,
+	RunE: runRmBackup,
+}
+
+var rmBackupYes bool
+
+func init() {
+	rmBackupCmd.Flags().BoolVarP(&rmBackupYes, "yes", "y", false, "Remove backups without prompting")
+	rootCmd.AddCommand(rmBackupCmd)
+}
+
+func runRmBackup(cmd *cobra.Command, args []string) error {
+	// 1. Collect backup directories
+	localDir, err := backup.BackupDir()
+	if err != nil {
+		return err
+	}
+	dirs := []string{localDir}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	var subpath string
+	if cfg != nil {
+		subpath = cfg.StorageSubpath
+	}
+	if storageOverride != "" {
+		dirs = append(dirs, backup.StorageBackupDir(manifest.DotsyncDir(pathutil.ExpandHome(storageOverride), subpath)))
+	} else if cfg != nil {
+		dirs = append(dirs, backup.StorageBackupDir(manifest.DotsyncDir(pathutil.ExpandHome(cfg.StoragePath), subpath)))
+	}
+
+	// 2. List backups
+	paths, err := findBackups(dirs)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		fmt.Println("No backups found.")
+		return nil
+	}
+
+	// 3. Confirm and remove
+	return removeBackups(paths, rmBackupYes)
+}
+
+// findBackups returns the backup files in all of dirs.
+func findBackups(dirs []string) ([]string, error) {
+	var paths []string
+	for _, dir := range dirs {
+		found, err := backup.List(dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, found...)
+	}
+	return paths, nil
+}
+
+// removeBackups lists the given backups and removes them, after
+// confirmation unless yes is set.
+func removeBackups(paths []string, yes bool) error {
+	for _, path := range paths {
+		fmt.Printf("  %s\n", pathutil.ContractHome(path))
+	}
+
+	if !yes && !confirmPrompt(fmt.Sprintf("Remove %d backup(s)?", len(paths))) {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	var removed, failed int
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			fmt.Printf("  [failed]  %s: %v\n", pathutil.ContractHome(path), err)
+			failed++
+			continue
+		}
+		removed++
+	}
+
+	fmt.Printf("Removed %d backup(s)\n", removed)
+	if failed > 0 {
+		return markAs(ErrPartialFailure, fmt.Errorf("some backups could not be removed"))
+	}
+
+	return nil
+}
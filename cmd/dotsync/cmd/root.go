@@ -1,9 +1,18 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/storage"
 )
 
 var (
@@ -21,7 +30,158 @@ across multiple machines by leveraging cloud storage providers like
 Google Drive, Dropbox, and iCloud.
 
 The tool manages symlinks between your config files and cloud storage,
-letting the cloud provider handle the actual synchronization.`,
+letting the cloud provider handle the actual synchronization.
+
+Exit codes:
+  0  success
+  1  error
+  2  dotsync not initialized
+  3  storage unavailable (not mounted/syncing)
+  4  partial failure (some files failed)
+  5  conflict, or aborted by the user
+  6  list --exit-code: broken or incorrect symlinks
+  7  list --exit-code: missing cloud files or untracked files in storage
+  130  interrupted with Ctrl-C (files handled so far are kept)`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		startReport(cmd, args)
+		if nonInteractive {
+			prompter = nonInteractivePrompter{w: os.Stdout}
+		}
+		if p, ok := prompter.(*readerPrompter); ok {
+			// Let Ctrl-C interrupt a prompt that is waiting for input
+			p.ctx = cmd.Context()
+		}
+		if homeFlag != "" {
+			return setHome(homeFlag)
+		}
+		return nil
+	},
+}
+
+var (
+	// keepBackups preserves temporary backups after successful operations (debug aid)
+	keepBackups bool
+	// storageOverride replaces the configured storage path for a single invocation
+	storageOverride string
+	// nonInteractive answers no to every prompt instead of reading stdin
+	nonInteractive bool
+	// homeFlag replaces the home directory for a single invocation
+	homeFlag string
+)
+
+// stdinReader is shared by all stdin reads, so answers piped on stdin aren't
+// lost in the buffer of an earlier prompt.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&keepBackups, "keep-backups", false, "Keep temporary backups after successful operations (for debugging)")
+	rootCmd.PersistentFlags().StringVar(&storageOverride, "storage", "", "Use this storage path instead of the configured one")
+	rootCmd.PersistentFlags().StringVar(&homeFlag, "home", "", "Use this directory as the home directory (for containers and testing)")
+	rootCmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "Never prompt: answer no to confirmations and fail when input is needed")
+}
+
+// loadConfig loads the local config and resolves the storage path.
+// The --storage flag wins over the configured path and also works
+// when dotsync hasn't been initialized. Returns the config and the
+// expanded storage path, which is verified to be available.
+func loadConfig() (*config.Config, string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, "", fmt.Errorf("loading config: %w", err)
+	}
+
+	// Every path into storage goes through the subpath, kept cleaned in
+	// the config for callers
+	if cfg != nil {
+		if cfg.StorageSubpath, err = manifest.CleanSubpath(cfg.StorageSubpath); err != nil {
+			return nil, "", fmt.Errorf("loading config: %w", err)
+		}
+	}
+
+	if storageOverride != "" {
+		if cfg == nil {
+			cfg = config.New(storageOverride)
+		} else {
+			cfg.StoragePath = storageOverride
+		}
+
+		storagePath := pathutil.ExpandHome(storageOverride)
+		if err := checkStorage(storagePath); err != nil {
+			return nil, "", err
+		}
+		return cfg, storagePath, nil
+	}
+
+	if cfg == nil {
+		return nil, "", ErrNotInitialized
+	}
+
+	storagePath := pathutil.ExpandHome(cfg.StoragePath)
+
+	// Verify storage is available
+	if err := checkStorage(storagePath); err != nil {
+		return nil, "", err
+	}
+
+	return cfg, storagePath, nil
+}
+
+// commandContext returns cmd's context, cancelled by Ctrl-C (see Execute),
+// or a background context when cmd wasn't run through Execute (e.g. tests).
+func commandContext(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// setHome makes dir the home directory for ~ paths, entry roots, the
+// config and local backups. dir must be an existing directory.
+func setHome(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolving --home: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return fmt.Errorf("--home: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("--home is not a directory: %s", abs)
+	}
+	pathutil.SetHome(abs)
+	return nil
+}
+
+// checkStorage verifies that the storage path exists and is a directory,
+// and that <storage>/dotsync, if present, is a directory too. A storage
+// path pointing at a file would otherwise fail later with obscure errors.
+func checkStorage(storagePath string) error {
+	info, err := os.Stat(storagePath)
+	if err != nil {
+		return markAs(ErrStorageUnavailable, fmt.Errorf("storage unavailable: %s\nMake sure your cloud storage is mounted/syncing", storagePath))
+	}
+	if !info.IsDir() {
+		return markAs(ErrStorageUnavailable, fmt.Errorf("storage path is not a directory: %s", storagePath))
+	}
+
+	dotsyncDir := filepath.Join(storagePath, "dotsync")
+	if info, err := os.Stat(dotsyncDir); err == nil && !info.IsDir() {
+		return markAs(ErrStorageUnavailable, fmt.Errorf("storage path is not a directory: %s", dotsyncDir))
+	}
+
+	return nil
+}
+
+// checkPopulated tells an unmounted storage apart from one with nothing
+// tracked yet, for commands that find no manifest. Without a dotsync
+// folder the storage path is most likely an empty mount point, and
+// reporting every file as missing would be misleading.
+func checkPopulated(storagePath string) error {
+	if storage.IsPopulated(storagePath) {
+		return nil
+	}
+	return markAs(ErrStorageUnavailable, fmt.Errorf("storage not mounted: %s has no dotsync folder\nMake sure your cloud storage is mounted/syncing. If it is, 'dotsync add' creates the folder", pathutil.ContractHome(storagePath)))
 }
 
 // SetVersion sets the version info at build time
@@ -35,7 +195,15 @@ func SetVersion(v, c, d, b string) {
 `, commit, date, builtBy))
 }
 
-// Execute runs the root command
+// Execute runs the root command. Ctrl-C cancels the command's context
+// instead of killing the process, so commands can stop between files and
+// print what they did. A second Ctrl-C kills the process as usual.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return finishReport(rootCmd.ExecuteContext(ctx))
 }
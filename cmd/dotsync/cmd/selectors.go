@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+// selectEntries returns the entries a bulk command works on: the entry
+// named in args, or all entries narrowed down by --only and --except
+// (comma-separated entry names). Every name given must exist.
+func selectEntries(m *manifest.Manifest, args, only, except []string) (map[string]manifest.Entry, error) {
+	only, except = trimNames(only), trimNames(except)
+
+	if len(args) > 0 {
+		if len(only) > 0 || len(except) > 0 {
+			return nil, fmt.Errorf("an entry name can't be combined with --only or --except")
+		}
+		only = args[:1]
+	}
+
+	for _, name := range append(append([]string{}, only...), except...) {
+		if !m.HasEntry(name) {
+			return nil, fmt.Errorf("entry '%s' not found", name)
+		}
+	}
+
+	selected := make(map[string]manifest.Entry)
+	if len(only) > 0 {
+		for _, name := range only {
+			selected[name] = m.Entries[name]
+		}
+	} else {
+		for name, entry := range m.Entries {
+			selected[name] = entry
+		}
+	}
+	for _, name := range except {
+		delete(selected, name)
+	}
+	return selected, nil
+}
+
+// sortedNames returns the names of entries sorted, so output and failures
+// come in the same order on every run.
+func sortedNames(entries map[string]manifest.Entry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// trimNames trims spaces around entry names and drops empty ones, so
+// "--only 'a, b,'" works as expected.
+func trimNames(names []string) []string {
+	var trimmed []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			trimmed = append(trimmed, name)
+		}
+	}
+	return trimmed
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+// TestSelectEntries tests choosing entries by name, --only and --except
+func TestSelectEntries(t *testing.T) {
+	m := manifest.New()
+	for _, name := range []string{"git", "work-secrets", "zsh"} {
+		m.AddFile(name, "~/."+name, "config")
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		only        []string
+		except      []string
+		want        []string
+		errContains string
+	}{
+		{name: "all", want: []string{"git", "work-secrets", "zsh"}},
+		{name: "entry name", args: []string{"zsh"}, want: []string{"zsh"}},
+		{name: "only", only: []string{"git", "zsh"}, want: []string{"git", "zsh"}},
+		{name: "except", except: []string{"work-secrets"}, want: []string{"git", "zsh"}},
+		{name: "only and except", only: []string{"git", "zsh"}, except: []string{"zsh"}, want: []string{"git"}},
+		{name: "spaces and empty names", only: []string{" git", "", "zsh "}, want: []string{"git", "zsh"}},
+		{name: "unknown entry name", args: []string{"nope"}, errContains: "entry 'nope' not found"},
+		{name: "unknown only", only: []string{"git", "nope"}, errContains: "entry 'nope' not found"},
+		{name: "unknown except", except: []string{"nope"}, errContains: "entry 'nope' not found"},
+		{name: "entry name with only", args: []string{"git"}, only: []string{"zsh"}, errContains: "can't be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := selectEntries(m, tt.args, tt.only, tt.except)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("selectEntries() error = %v, want containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectEntries() failed: %v", err)
+			}
+
+			got := sortedNames(selected)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("selected = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
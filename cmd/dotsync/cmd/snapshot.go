@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/snapshot"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Record tracked file contents for later comparison",
+	Long: `Save a snapshot of the manifest and the content hash of every tracked
+file, and later see which files changed since then.
+
+Snapshots are stored locally in ~/.cache/dotsync/snapshots/ and are not
+synced. They record hashes, sizes and times only, not file contents.`,
+	Example: `  dotsync snapshot save weekly  # Record the current state
+  dotsync snapshot diff weekly  # Show files changed since then`,
+}
+
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save a snapshot of the tracked files",
+	Long: `Save the manifest and the content hash, size and modification time
+of every tracked file under the given name. An existing snapshot with the
+same name is replaced.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSnapshotSave,
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <name>",
+	Short: "Show tracked files changed since a snapshot",
+	Long: `Compare the tracked files in cloud storage with a saved snapshot and
+list the files that were added, removed or modified since then.
+
+Use --quick to compare only each file's size and modification time
+instead of hashing its content. This is much faster on cloud mounts and
+good for routine checks, but a file whose time was merely touched shows
+as modified; run without --quick to be certain.`,
+	Example: `  dotsync snapshot diff weekly
+  dotsync snapshot diff weekly --quick`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSnapshotDiff,
+}
+
+var snapshotDiffQuick bool
+
+func init() {
+	snapshotDiffCmd.Flags().BoolVar(&snapshotDiffQuick, "quick", false, "Compare file sizes and modification times instead of hashes")
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+func runSnapshotSave(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+
+	// 1. Load config and manifest
+	m, storagePath, err := loadSnapshotManifest()
+	if err != nil {
+		return err
+	}
+
+	// 2. Hash tracked files and save
+	s, err := snapshot.Take(name, storagePath, m)
+	if err != nil {
+		return err
+	}
+	if err := s.Save(); err != nil {
+		return err
+	}
+
+	path, _ := snapshot.Path(name)
+	fmt.Printf("Saved snapshot '%s' (%d file(s)) to %s\n", name, len(s.Hashes), pathutil.ContractHome(path))
+	return nil
+}
+
+func runSnapshotDiff(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+
+	// 1. Load config, manifest and snapshot
+	m, storagePath, err := loadSnapshotManifest()
+	if err != nil {
+		return err
+	}
+
+	s, err := snapshot.Load(name)
+	if err != nil {
+		return err
+	}
+
+	// 2. Compare
+	diff := s.Diff
+	if snapshotDiffQuick {
+		diff = s.QuickDiff
+	}
+	changes, err := diff(storagePath, m)
+	if err != nil {
+		return err
+	}
+
+	quick := ""
+	if snapshotDiffQuick {
+		quick = ", by size and time"
+	}
+	fmt.Printf("Changes since snapshot '%s' (%s%s):\n", name, s.Created.Format("2006-01-02 15:04"), quick)
+	if len(changes) == 0 {
+		fmt.Println("  No changes")
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Printf("  %-10s %s\n", "["+string(c.Kind)+"]", c.File)
+	}
+	fmt.Printf("\n%d file(s) changed\n", len(changes))
+	return nil
+}
+
+// loadSnapshotManifest loads the config and manifest. A missing manifest
+// is treated as empty.
+func loadSnapshotManifest() (*manifest.Manifest, string, error) {
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
+	if err != nil {
+		if strings.Contains(err.Error(), "manifest not found") {
+			m := manifest.New()
+			m.Subpath = cfg.StorageSubpath
+			return m, storagePath, nil
+		}
+		return nil, "", fmt.Errorf("loading manifest: %w", err)
+	}
+	return m, storagePath, nil
+}
+
+// validateSnapshotName checks that a snapshot name is usable as a file name.
+func validateSnapshotName(name string) error {
+	if name == "" {
+		return fmt.Errorf("snapshot name cannot be empty")
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("snapshot name cannot contain path separators (/ or \\)")
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("snapshot name cannot be '.' or '..'")
+	}
+	return nil
+}
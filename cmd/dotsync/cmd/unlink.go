@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/wtfzambo/dotsync/internal/backup"
 	"github.com/wtfzambo/dotsync/internal/config"
 	"github.com/wtfzambo/dotsync/internal/manifest"
 	"github.com/wtfzambo/dotsync/internal/pathutil"
@@ -21,36 +24,89 @@ var unlinkCmd = &cobra.Command{
 This restores files to be regular files (not symlinks) while keeping
 the cloud copy intact. You can re-link later with "dotsync link".
 
-If no entry name is provided, all entries will be unlinked.`,
-	Example: `  dotsync unlink           # Unlink all entries
-  dotsync unlink opencode  # Unlink only the "opencode" entry`,
+If no entry name is provided, all entries will be unlinked.
+Use --only or --except with comma-separated entry names to unlink a subset.
+
+Use --prune-missing to also stop tracking files that no longer exist
+anywhere (missing both in cloud storage and locally).
+
+Use --remove-empty-dirs to delete directories left empty once a file is
+gone from its original location: when only a broken symlink was removed,
+or a file was pruned. Only empty directories below the entry root are
+removed; the root itself and anything outside it are kept.
+
+Use --all-then-remove-storage to decommission dotsync on this machine:
+every entry is unlinked, each file is verified to be restored locally,
+and after confirmation the storage folder (<storage>/dotsync) and the
+local config are deleted. Backups in .backups and .replaced are kept,
+and so is the config when --storage is given. Nothing is deleted if any
+file could not be restored, or while other machines keep a storage
+subpath inside it.
+
+Use --parallel N to copy up to N files of an entry back at the same time,
+which speeds up unlinking from high-latency mounts. Results are still
+printed in manifest order.
+
+Use --verify-after to hash each restored file and compare it with the
+cloud copy. A file that doesn't match (e.g. an interrupted copy) is
+reported as a failure and its symlink is put back, so nothing is lost.
+Worth running before deleting the cloud copy.
+
+Use --report <file> to write a JSON summary of the run, listing the
+outcome of every file.`,
+	Example: `  dotsync unlink                  # Unlink all entries
+  dotsync unlink opencode         # Unlink only the "opencode" entry
+  dotsync unlink --only zsh,git   # Unlink the "zsh" and "git" entries
+  dotsync unlink --prune-missing  # Also drop files that are gone everywhere
+  dotsync unlink --parallel 8     # Copy 8 files back at a time
+  dotsync unlink --verify-after   # Check restored files against the cloud copy
+  dotsync unlink --all-then-remove-storage  # Restore everything and uninstall`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runUnlink,
 }
 
+var (
+	unlinkPruneMissing  bool
+	unlinkRemoveStorage bool
+	unlinkParallel      int
+	unlinkOnly          []string
+	unlinkExcept        []string
+	unlinkVerifyAfter   bool
+	unlinkRemoveEmpty   bool
+)
+
 func init() {
+	unlinkCmd.Flags().BoolVar(&unlinkPruneMissing, "prune-missing", false, "Remove files missing from both cloud storage and this machine from the manifest")
+	unlinkCmd.Flags().BoolVar(&unlinkRemoveStorage, "all-then-remove-storage", false, "Unlink all entries, then delete the storage folder and local config")
+	unlinkCmd.Flags().IntVar(&unlinkParallel, "parallel", 1, "Copy up to N files back from cloud storage at the same time")
+	unlinkCmd.Flags().StringSliceVar(&unlinkOnly, "only", nil, "Unlink only these entries (comma-separated)")
+	unlinkCmd.Flags().StringSliceVar(&unlinkExcept, "except", nil, "Unlink all entries but these (comma-separated)")
+	unlinkCmd.Flags().BoolVar(&unlinkVerifyAfter, "verify-after", false, "Compare each restored file's hash with the cloud copy")
+	unlinkCmd.Flags().BoolVar(&unlinkRemoveEmpty, "remove-empty-dirs", false, "Delete directories below the entry root left empty by removed files")
+	addReportFlag(unlinkCmd)
 	rootCmd.AddCommand(unlinkCmd)
 }
 
 func runUnlink(cmd *cobra.Command, args []string) error {
-	// 1. Load config (must be initialized)
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
+	if unlinkParallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
 	}
-	if cfg == nil {
-		return fmt.Errorf("dotsync not initialized. Run 'dotsync init <provider>' first")
+	if unlinkRemoveStorage && (len(args) > 0 || len(unlinkOnly) > 0 || len(unlinkExcept) > 0) {
+		return fmt.Errorf("--all-then-remove-storage unlinks every entry and can't be combined with an entry name, --only or --except")
+	}
+	if unlinkRemoveStorage && unlinkPruneMissing {
+		return fmt.Errorf("--all-then-remove-storage can't be combined with --prune-missing")
 	}
 
-	storagePath := pathutil.ExpandHome(cfg.StoragePath)
-
-	// Verify storage is available
-	if _, err := os.Stat(storagePath); os.IsNotExist(err) {
-		return fmt.Errorf("storage unavailable: %s\nMake sure your cloud storage is mounted/syncing", storagePath)
+	// 1. Load config (must be initialized)
+	cfg, storagePath, err := loadConfig()
+	if err != nil {
+		return err
 	}
+	warnInterruptedAdd(manifest.DotsyncDir(storagePath, cfg.StorageSubpath))
 
 	// 2. Load manifest
-	m, err := manifest.Load(storagePath)
+	m, err := manifest.Load(storagePath, cfg.StorageSubpath)
 	if err != nil {
 		if strings.Contains(err.Error(), "manifest not found") {
 			return fmt.Errorf("no manifest found. Nothing to unlink")
@@ -64,46 +120,64 @@ func runUnlink(cmd *cobra.Command, args []string) error {
 	}
 
 	// 3. Determine which entries to unlink
-	var entriesToUnlink map[string]manifest.Entry
-	if len(args) > 0 {
-		entryName := args[0]
-		entry := m.GetEntry(entryName)
-		if entry == nil {
-			return fmt.Errorf("entry '%s' not found", entryName)
-		}
-		entriesToUnlink = map[string]manifest.Entry{entryName: *entry}
-	} else {
-		entriesToUnlink = m.Entries
+	entriesToUnlink, err := selectEntries(m, args, unlinkOnly, unlinkExcept)
+	if err != nil {
+		return err
 	}
 
-	// 4. Unlink each entry
+	// 4. Unlink each entry, stopping between files on Ctrl-C
+	ctx := commandContext(cmd)
 	var unlinked, skipped, failed int
 
-	for name, entry := range entriesToUnlink {
+	for _, name := range sortedNames(entriesToUnlink) {
+		entry := entriesToUnlink[name]
+		if ctx.Err() != nil {
+			break
+		}
 		fmt.Printf("\nUnlinking entry '%s':\n", name)
 
-		entryRoot := pathutil.ExpandHome(entry.Root)
-		for _, relPath := range entry.Files {
-			originalPath := filepath.Join(entryRoot, relPath)
-			cloudPath := filepath.Join(storagePath, "dotsync", name, relPath)
-
-			result, err := unlinkFile(originalPath, cloudPath)
-			switch result {
+		outcomes := unlinkEntry(ctx, name, entry, m.DotsyncDir(storagePath), unlinkParallel, unlinkVerifyAfter)
+		for i, relPath := range entry.Files {
+			switch o := outcomes[i]; o.result {
 			case unlinkResultUnlinked:
 				fmt.Printf("  [unlinked] %s\n", relPath)
+				opReport.file(name, relPath, "unlinked", nil, "")
+				unlinked++
+			case unlinkResultSourceMissing:
+				fmt.Printf("  [unlinked] %s (source file missing in cloud storage, symlink removed)\n", relPath)
+				opReport.file(name, relPath, "unlinked", nil, "source file missing in cloud storage, symlink removed")
 				unlinked++
+				if unlinkRemoveEmpty {
+					entryRoot := pathutil.ExpandHome(entry.Root)
+					cleanEmptyParents(entryRoot, filepath.Join(entryRoot, manifest.FromStorageSlash(relPath)))
+				}
 			case unlinkResultSkipped:
 				fmt.Printf("  [skipped]  %s (not a symlink)\n", relPath)
+				opReport.file(name, relPath, "skipped", nil, "not a symlink")
+				skipped++
+			case unlinkResultCopyMode:
+				// Copy-mode files are already regular files
+				fmt.Printf("  [skipped]  %s (copy mode)\n", relPath)
+				opReport.file(name, relPath, "skipped", nil, "copy mode")
 				skipped++
 			case unlinkResultNotExist:
 				fmt.Printf("  [skipped]  %s (doesn't exist)\n", relPath)
+				opReport.file(name, relPath, "skipped", nil, "doesn't exist")
 				skipped++
+			case unlinkResultMismatch:
+				fmt.Printf("  [failed]   %s: %v (symlink restored)\n", relPath, o.err)
+				opReport.file(name, relPath, "failed", o.err, "")
+				failed++
 			case unlinkResultFailed:
-				fmt.Printf("  [failed]   %s: %v\n", relPath, err)
+				fmt.Printf("  [failed]   %s: %v\n", relPath, o.err)
+				opReport.file(name, relPath, "failed", o.err, "")
 				failed++
 			}
 		}
 	}
+	if ctx.Err() != nil {
+		fmt.Println("\nInterrupted, stopping.")
+	}
 
 	// 5. Print summary
 	fmt.Println()
@@ -112,26 +186,215 @@ func runUnlink(cmd *cobra.Command, args []string) error {
 	}
 
 	// Note: We don't modify the manifest - entries stay tracked so they can be re-linked
-	fmt.Println("\nFiles are now regular files. Use 'dotsync link' to restore symlinks.")
+	if !unlinkRemoveStorage {
+		fmt.Println("\nFiles are now regular files. Use 'dotsync link' to restore symlinks.")
+	}
+
+	if ctx.Err() != nil {
+		return ErrInterrupted
+	}
+
+	// 6. Prune files that are gone everywhere, if requested
+	if unlinkPruneMissing {
+		if err := pruneMissing(m, entriesToUnlink, storagePath); err != nil {
+			return err
+		}
+	}
 
 	if failed > 0 {
-		return fmt.Errorf("some files failed to unlink")
+		if unlinkRemoveStorage {
+			return markAs(ErrPartialFailure, fmt.Errorf("some files failed to unlink; storage was not removed"))
+		}
+		return markAs(ErrPartialFailure, fmt.Errorf("some files failed to unlink"))
+	}
+
+	// 7. Remove storage and config, if requested
+	if unlinkRemoveStorage {
+		return removeStorage(m, storagePath)
+	}
+
+	return nil
+}
+
+// removeStorage deletes <storage>/dotsync and the local config once every
+// tracked file has been verified to exist locally as a regular file with
+// the same content as its cloud copy.
+func removeStorage(m *manifest.Manifest, storagePath string) error {
+	dotsyncDir := m.DotsyncDir(storagePath)
+
+	// 1. Refuse while other machines keep their subpaths inside this folder
+	trees, err := manifest.NestedTrees(dotsyncDir)
+	if err != nil {
+		return markAs(ErrStorageUnavailable, err)
+	}
+	if len(trees) > 0 {
+		fmt.Println("\nThese storage subpaths are kept inside this machine's folder:")
+		for _, tree := range trees {
+			fmt.Printf("  %s\n", tree)
+		}
+		return markAs(ErrConflict, fmt.Errorf("other machines still use %s; storage was not removed", pathutil.ContractHome(dotsyncDir)))
+	}
+
+	// 2. Verify every file that still has a cloud copy was restored
+	var unrestored []string
+	var restored int
+	for _, name := range m.Names() {
+		entry := m.Entries[name]
+		entryRoot := pathutil.ExpandHome(entry.Root)
+		for _, relPath := range entry.Files {
+			originalPath := filepath.Join(entryRoot, manifest.FromStorageSlash(relPath))
+			cloudPath := entry.CloudPath(dotsyncDir, name, relPath)
+
+			if cloudMissing(cloudPath) {
+				// Nothing in storage to lose
+				continue
+			}
+			if !restoredLocally(originalPath, cloudPath) {
+				unrestored = append(unrestored, fmt.Sprintf("%s/%s", name, relPath))
+				continue
+			}
+			restored++
+		}
+	}
+
+	if len(unrestored) > 0 {
+		sort.Strings(unrestored)
+		fmt.Println("\nThese files are not restored on this machine:")
+		for _, f := range unrestored {
+			fmt.Printf("  %s\n", f)
+		}
+		return markAs(ErrPartialFailure, fmt.Errorf("%d file(s) only exist in storage; storage was not removed", len(unrestored)))
+	}
+
+	// 3. Confirm. A --storage override points at someone else's storage
+	// for this run only, so the local config stays.
+	fmt.Printf("\nAll %d file(s) are restored locally.\n", restored)
+	if storageOverride != "" {
+		fmt.Printf("This will delete %s; the local dotsync config is kept.\n", pathutil.ContractHome(dotsyncDir))
+	} else {
+		fmt.Printf("This will delete %s and the local dotsync config.\n", pathutil.ContractHome(dotsyncDir))
+	}
+	fmt.Printf("Backups in %s and %s are kept.\n", backup.StorageBackupDirName, backup.ReplacedDirName)
+	fmt.Println("Other machines syncing this storage will lose their files too.")
+	if !confirmPrompt("Remove storage and config?") {
+		fmt.Println("Storage and config kept.")
+		return ErrAborted
 	}
 
+	// 4. Remove storage, then config
+	kept, err := removeStorageFiles(dotsyncDir)
+	if err != nil {
+		return markAs(ErrStorageUnavailable, fmt.Errorf("removing storage folder: %w", err))
+	}
+	if len(kept) > 0 {
+		fmt.Printf("Removed tracked files from %s\n", pathutil.ContractHome(dotsyncDir))
+		for _, dir := range kept {
+			fmt.Printf("  Kept backups in %s\n", pathutil.ContractHome(dir))
+		}
+	} else {
+		fmt.Printf("Removed %s\n", pathutil.ContractHome(dotsyncDir))
+	}
+
+	if storageOverride == "" {
+		if err := config.Delete(); err != nil {
+			return fmt.Errorf("removing config: %w", err)
+		}
+		fmt.Println("Removed local config")
+		fmt.Println("\ndotsync has been removed from this machine. Your files are regular files again.")
+	} else {
+		fmt.Println("\nStorage removed. Your files are regular files again.")
+	}
 	return nil
 }
 
+// removeStorageFiles removes everything in dotsyncDir except the backup
+// folders, which may hold the only copy of a file replaced by link or add,
+// then dotsyncDir itself if nothing was kept. Returns the kept folders.
+func removeStorageFiles(dotsyncDir string) ([]string, error) {
+	children, err := os.ReadDir(dotsyncDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var kept []string
+	for _, child := range children {
+		path := filepath.Join(dotsyncDir, child.Name())
+		if child.IsDir() && (child.Name() == backup.StorageBackupDirName || child.Name() == backup.ReplacedDirName) {
+			kept = append(kept, path)
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return kept, err
+		}
+	}
+	if len(kept) == 0 {
+		return nil, os.Remove(dotsyncDir)
+	}
+	return kept, nil
+}
+
+// restoredLocally returns true if originalPath is a regular file with the
+// same content as cloudPath.
+func restoredLocally(originalPath, cloudPath string) bool {
+	info, err := os.Lstat(originalPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	same, err := symlink.SameContent(originalPath, cloudPath)
+	return err == nil && same
+}
+
 type unlinkResult int
 
 const (
-	unlinkResultUnlinked unlinkResult = iota
+	unlinkResultUnlinked      unlinkResult = iota
+	unlinkResultSourceMissing              // symlink removed, but there was nothing to copy back
 	unlinkResultSkipped
+	unlinkResultCopyMode
 	unlinkResultNotExist
+	unlinkResultMismatch // restored copy didn't match the cloud file, symlink put back
 	unlinkResultFailed
+	unlinkResultInterrupted // not attempted because of Ctrl-C
 )
 
+// unlinkOutcome is the result of unlinking one file.
+type unlinkOutcome struct {
+	result unlinkResult
+	err    error
+}
+
+// unlinkEntry unlinks the files of an entry using up to workers goroutines.
+// With verify, each restored file is checked against its cloud copy. Once
+// ctx is cancelled, the remaining files are left alone.
+// Returns one outcome per file, in the order of entry.Files.
+func unlinkEntry(ctx context.Context, name string, entry manifest.Entry, dotsyncDir string, workers int, verify bool) []unlinkOutcome {
+	entryRoot := pathutil.ExpandHome(entry.Root)
+	outcomes := make([]unlinkOutcome, len(entry.Files))
+
+	runParallel(len(entry.Files), workers, func(i int) {
+		relPath := entry.Files[i]
+		if ctx.Err() != nil {
+			outcomes[i] = unlinkOutcome{result: unlinkResultInterrupted}
+			return
+		}
+		if entry.FileMode(relPath) == manifest.ModeCopy {
+			outcomes[i] = unlinkOutcome{result: unlinkResultCopyMode}
+			return
+		}
+
+		originalPath := filepath.Join(entryRoot, manifest.FromStorageSlash(relPath))
+		cloudPath := entry.CloudPath(dotsyncDir, name, relPath)
+		result, err := unlinkFile(originalPath, cloudPath, verify)
+		outcomes[i] = unlinkOutcome{result: result, err: err}
+	})
+	return outcomes
+}
+
 // unlinkFile removes a symlink and copies the file from cloud storage.
-func unlinkFile(originalPath, cloudPath string) (unlinkResult, error) {
+func unlinkFile(originalPath, cloudPath string, verify bool) (unlinkResult, error) {
 	// Check current state
 	status, _, err := symlink.Check(originalPath, cloudPath)
 	if err != nil {
@@ -139,7 +402,7 @@ func unlinkFile(originalPath, cloudPath string) (unlinkResult, error) {
 	}
 
 	switch status {
-	case symlink.StatusNotExist:
+	case symlink.StatusNotExist, symlink.StatusParentMissing:
 		// Nothing to unlink
 		return unlinkResultNotExist, nil
 
@@ -149,15 +412,14 @@ func unlinkFile(originalPath, cloudPath string) (unlinkResult, error) {
 
 	case symlink.StatusLinked, symlink.StatusIncorrect:
 		// It's a symlink - remove it and copy file
-		return doUnlink(originalPath, cloudPath)
+		return doUnlink(originalPath, cloudPath, verify)
 
 	case symlink.StatusBroken:
-		// Broken symlink - just remove it, warn about missing source
+		// Broken symlink - just remove it, the caller warns about the missing source
 		if err := symlink.Remove(originalPath); err != nil {
 			return unlinkResultFailed, fmt.Errorf("removing broken symlink: %w", err)
 		}
-		fmt.Printf("  Warning: source file missing in cloud storage: %s\n", pathutil.ContractHome(cloudPath))
-		return unlinkResultUnlinked, nil
+		return unlinkResultSourceMissing, nil
 
 	default:
 		return unlinkResultFailed, fmt.Errorf("unexpected status: %v", status)
@@ -165,15 +427,14 @@ func unlinkFile(originalPath, cloudPath string) (unlinkResult, error) {
 }
 
 // doUnlink performs the actual unlink operation.
-func doUnlink(originalPath, cloudPath string) (unlinkResult, error) {
+func doUnlink(originalPath, cloudPath string, verify bool) (unlinkResult, error) {
 	// Verify cloud file exists
-	if _, err := os.Stat(cloudPath); os.IsNotExist(err) {
-		// Cloud file missing - just remove symlink and warn
+	if cloudMissing(cloudPath) {
+		// Cloud file missing - just remove symlink, the caller warns
 		if err := symlink.Remove(originalPath); err != nil {
 			return unlinkResultFailed, fmt.Errorf("removing symlink: %w", err)
 		}
-		fmt.Printf("  Warning: source file missing in cloud storage, symlink removed\n")
-		return unlinkResultUnlinked, nil
+		return unlinkResultSourceMissing, nil
 	}
 
 	// Remove symlink first
@@ -182,11 +443,158 @@ func doUnlink(originalPath, cloudPath string) (unlinkResult, error) {
 	}
 
 	// Copy file from cloud to original location
-	if err := symlink.CopyFile(cloudPath, originalPath); err != nil {
+	cloudHash, err := symlink.CopyFileWithHash(cloudPath, originalPath)
+	if err != nil {
 		// Try to restore symlink on failure
 		symlink.Create(originalPath, cloudPath)
 		return unlinkResultFailed, fmt.Errorf("copying file: %w", err)
 	}
 
+	if verify {
+		if err := verifyRestored(originalPath, cloudHash); err != nil {
+			// Don't leave a bad copy behind, the cloud file is still good
+			os.Remove(originalPath)
+			symlink.Create(originalPath, cloudPath)
+			return unlinkResultMismatch, err
+		}
+	}
+
 	return unlinkResultUnlinked, nil
 }
+
+// verifyRestored checks that the file at originalPath hashes to cloudHash,
+// the hash of the cloud content that was copied there.
+func verifyRestored(originalPath, cloudHash string) error {
+	localHash, err := symlink.HashFile(originalPath)
+	if err != nil {
+		return fmt.Errorf("verifying restored file: %w", err)
+	}
+	if localHash != cloudHash {
+		return fmt.Errorf("restored file doesn't match the cloud copy")
+	}
+	return nil
+}
+
+// cleanEmptyParents removes the directories below entryRoot left empty now
+// that originalPath is gone, and reports each one. Failures are only
+// warnings, the file itself is already handled.
+func cleanEmptyParents(entryRoot, originalPath string) {
+	removed, err := removeEmptyParents(originalPath, entryRoot)
+	for _, dir := range removed {
+		fmt.Printf("  [removed]  %s (empty directory)\n", pathutil.ContractHome(dir))
+	}
+	if err != nil {
+		fmt.Printf("  Warning: removing empty directories: %v\n", err)
+	}
+}
+
+// removeEmptyParents removes the parent directories of path that are empty,
+// walking up until a directory isn't. Only directories strictly inside
+// stopAt are touched, so stopAt and anything beyond it are always kept.
+// Symlinks to directories are never followed or removed. Returns the
+// removed directories, deepest first.
+func removeEmptyParents(path, stopAt string) ([]string, error) {
+	var removed []string
+	for dir := filepath.Dir(filepath.Clean(path)); ; dir = filepath.Dir(dir) {
+		if _, err := pathutil.SafeRel(stopAt, dir); err != nil {
+			return removed, nil
+		}
+		info, err := os.Lstat(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return removed, err
+		}
+		if !info.IsDir() {
+			return removed, nil
+		}
+		children, err := os.ReadDir(dir)
+		if err != nil {
+			return removed, err
+		}
+		if len(children) > 0 {
+			return removed, nil
+		}
+		// os.Remove refuses directories that aren't empty, in case
+		// something was created in the meantime
+		if err := os.Remove(dir); err != nil {
+			return removed, err
+		}
+		removed = append(removed, dir)
+	}
+}
+
+// cloudMissing returns true if the file is absent from cloud storage.
+func cloudMissing(cloudPath string) bool {
+	_, err := os.Stat(cloudPath)
+	return os.IsNotExist(err)
+}
+
+// pruneMissing removes files that are missing both in cloud storage and
+// locally from the manifest, after confirmation.
+func pruneMissing(m *manifest.Manifest, entries map[string]manifest.Entry, storagePath string) error {
+	missing := findPrunable(entries, m.DotsyncDir(storagePath))
+	if len(missing) == 0 {
+		fmt.Println("No missing files to prune.")
+		return nil
+	}
+	if err := pruneFiles(m, storagePath, missing); err != nil {
+		return err
+	}
+
+	if unlinkRemoveEmpty {
+		for _, f := range missing {
+			// Skip files that are still tracked because pruning was declined
+			if m.IsFileTracked(f.originalPath) == "" {
+				cleanEmptyParents(pathutil.ExpandHome(entries[f.name].Root), f.originalPath)
+			}
+		}
+	}
+	return nil
+}
+
+// findPrunable returns the files of entries that are missing both in cloud
+// storage and locally, sorted by entry and path.
+func findPrunable(entries map[string]manifest.Entry, dotsyncDir string) []fileCheck {
+	var missing []fileCheck
+	for name, entry := range entries {
+		for _, c := range checkEntry(name, entry, dotsyncDir) {
+			if c.cloudMissing && (c.status == symlink.StatusNotExist || c.status == symlink.StatusParentMissing) {
+				missing = append(missing, c)
+			}
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].name != missing[j].name {
+			return missing[i].name < missing[j].name
+		}
+		return missing[i].relPath < missing[j].relPath
+	})
+	return missing
+}
+
+// pruneFiles stops tracking the given files after confirmation and saves
+// the manifest.
+func pruneFiles(m *manifest.Manifest, storagePath string, missing []fileCheck) error {
+	fmt.Println("\nMissing from cloud storage and this machine:")
+	for _, f := range missing {
+		fmt.Printf("  %s/%s\n", f.name, f.relPath)
+	}
+
+	if !confirmPrompt(fmt.Sprintf("Stop tracking %d file(s)?", len(missing))) {
+		fmt.Println("Prune skipped.")
+		return nil
+	}
+
+	for _, f := range missing {
+		m.RemoveFile(f.name, f.relPath)
+	}
+	if err := m.Save(storagePath); err != nil {
+		return fmt.Errorf("saving manifest: %w", err)
+	}
+
+	fmt.Printf("Pruned %d file(s) from the manifest\n", len(missing))
+	return nil
+}
@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/backup"
+	"github.com/wtfzambo/dotsync/internal/config"
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// setupLinkedEntry creates an entry with n files symlinked into storage.
+// Returns the entry and the storage path.
+func setupLinkedEntry(tb testing.TB, n int) (manifest.Entry, string) {
+	tb.Helper()
+	tmpDir := tb.TempDir()
+	root := filepath.Join(tmpDir, "home")
+	storagePath := filepath.Join(tmpDir, "storage")
+
+	entry := manifest.Entry{Root: root}
+	for i := range n {
+		relPath := fmt.Sprintf("dir%d/file%d.conf", i%4, i)
+		originalPath := filepath.Join(root, filepath.FromSlash(relPath))
+		cloudPath := filepath.Join(storagePath, "dotsync", "app", filepath.FromSlash(relPath))
+
+		os.MkdirAll(filepath.Dir(originalPath), 0755)
+		os.MkdirAll(filepath.Dir(cloudPath), 0755)
+		if err := os.WriteFile(cloudPath, []byte(relPath), 0644); err != nil {
+			tb.Fatalf("failed to create cloud file: %v", err)
+		}
+		if err := os.Symlink(cloudPath, originalPath); err != nil {
+			tb.Fatalf("failed to create symlink: %v", err)
+		}
+		entry.Files = append(entry.Files, relPath)
+	}
+	return entry, storagePath
+}
+
+// TestUnlinkEntry_Parallel tests that outcomes line up with entry.Files and
+// every file is restored when unlinking concurrently
+func TestUnlinkEntry_Parallel(t *testing.T) {
+	entry, storagePath := setupLinkedEntry(t, 20)
+
+	// A broken symlink and a copy-mode file keep their own outcomes
+	missing := "dir0/missing.conf"
+	os.Symlink(filepath.Join(storagePath, "dotsync", "app", "dir0", "missing.conf"), filepath.Join(entry.Root, "dir0", "missing.conf"))
+	entry.Files = append(entry.Files, missing, "copied.conf")
+	entry.Modes = map[string]manifest.LinkMode{"copied.conf": manifest.ModeCopy}
+
+	outcomes := unlinkEntry(context.Background(), "app", entry, manifest.DotsyncDir(storagePath, ""), 4, true)
+	if len(outcomes) != len(entry.Files) {
+		t.Fatalf("got %d outcomes, want %d", len(outcomes), len(entry.Files))
+	}
+
+	for i, relPath := range entry.Files {
+		want := unlinkResultUnlinked
+		switch relPath {
+		case missing:
+			want = unlinkResultSourceMissing
+		case "copied.conf":
+			want = unlinkResultCopyMode
+		}
+		if outcomes[i].result != want {
+			t.Errorf("%s: result = %v (err: %v), want %v", relPath, outcomes[i].result, outcomes[i].err, want)
+		}
+		if want != unlinkResultUnlinked {
+			continue
+		}
+
+		originalPath := filepath.Join(entry.Root, filepath.FromSlash(relPath))
+		cloudPath := filepath.Join(storagePath, "dotsync", "app", filepath.FromSlash(relPath))
+		if !restoredLocally(originalPath, cloudPath) {
+			t.Errorf("%s was not restored as a regular file", relPath)
+		}
+	}
+}
+
+// TestUnlinkEntry_Interrupted tests that nothing is unlinked once the context is cancelled
+func TestUnlinkEntry_Interrupted(t *testing.T) {
+	entry, storagePath := setupLinkedEntry(t, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i, o := range unlinkEntry(ctx, "app", entry, manifest.DotsyncDir(storagePath, ""), 1, false) {
+		if o.result != unlinkResultInterrupted {
+			t.Errorf("%s: result = %v, want interrupted", entry.Files[i], o.result)
+		}
+		if isLink, _ := symlink.IsSymlink(filepath.Join(entry.Root, filepath.FromSlash(entry.Files[i]))); !isLink {
+			t.Errorf("%s should still be a symlink", entry.Files[i])
+		}
+	}
+}
+
+// TestVerifyRestored tests that a restored file differing from the cloud copy is caught
+func TestVerifyRestored(t *testing.T) {
+	entry, storagePath := setupLinkedEntry(t, 1)
+	originalPath := filepath.Join(entry.Root, filepath.FromSlash(entry.Files[0]))
+	cloudPath := filepath.Join(storagePath, "dotsync", "app", filepath.FromSlash(entry.Files[0]))
+
+	if result, err := doUnlink(originalPath, cloudPath, true); result != unlinkResultUnlinked {
+		t.Fatalf("doUnlink() = %v, %v; want unlinked", result, err)
+	}
+
+	cloudHash, err := symlink.HashFile(cloudPath)
+	if err != nil {
+		t.Fatalf("HashFile() error: %v", err)
+	}
+	if err := verifyRestored(originalPath, cloudHash); err != nil {
+		t.Errorf("verifyRestored() on a good copy: %v", err)
+	}
+
+	// Simulate a truncated copy
+	if err := os.WriteFile(originalPath, []byte("dir0/fi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyRestored(originalPath, cloudHash); err == nil {
+		t.Error("verifyRestored() should report a mismatch")
+	}
+}
+
+// BenchmarkUnlinkEntry compares serial and parallel unlinking of an entry
+func BenchmarkUnlinkEntry(b *testing.B) {
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("parallel=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				entry, storagePath := setupLinkedEntry(b, 64)
+				b.StartTimer()
+
+				unlinkEntry(context.Background(), "app", entry, manifest.DotsyncDir(storagePath, ""), workers, false)
+			}
+		})
+	}
+}
+
+// TestRemoveEmptyParents tests that only empty directories strictly inside
+// the stop directory are removed
+func TestRemoveEmptyParents(t *testing.T) {
+	tests := []struct {
+		name string
+		// dirs and the keep file are created below the temp dir
+		dirs        []string
+		keep        string
+		path        string
+		stopAt      string
+		wantRemoved []string
+	}{
+		{
+			name:        "empty chain up to root",
+			dirs:        []string{"root/a/b/c"},
+			path:        "root/a/b/c/file.conf",
+			stopAt:      "root",
+			wantRemoved: []string{"root/a/b/c", "root/a/b", "root/a"},
+		},
+		{
+			name:        "stops at a non-empty directory",
+			dirs:        []string{"root/a/b"},
+			keep:        "root/a/other.conf",
+			path:        "root/a/b/file.conf",
+			stopAt:      "root",
+			wantRemoved: []string{"root/a/b"},
+		},
+		{
+			name:   "parent not empty",
+			dirs:   []string{"root/a"},
+			keep:   "root/a/other.conf",
+			path:   "root/a/file.conf",
+			stopAt: "root",
+		},
+		{
+			name:   "file directly in root",
+			dirs:   []string{"root"},
+			path:   "root/file.conf",
+			stopAt: "root",
+		},
+		{
+			name:   "path outside stop directory",
+			dirs:   []string{"other/a"},
+			path:   "other/a/file.conf",
+			stopAt: "root",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			join := func(p string) string { return filepath.Join(tmpDir, filepath.FromSlash(p)) }
+			os.MkdirAll(join("root"), 0755)
+			for _, d := range tt.dirs {
+				os.MkdirAll(join(d), 0755)
+			}
+			if tt.keep != "" {
+				os.WriteFile(join(tt.keep), []byte("keep"), 0644)
+			}
+
+			removed, err := removeEmptyParents(join(tt.path), join(tt.stopAt))
+			if err != nil {
+				t.Fatalf("removeEmptyParents() error = %v", err)
+			}
+			var want []string
+			for _, d := range tt.wantRemoved {
+				want = append(want, join(d))
+			}
+			if !slices.Equal(removed, want) {
+				t.Errorf("removed = %v, want %v", removed, want)
+			}
+			for _, d := range want {
+				if _, err := os.Lstat(d); !os.IsNotExist(err) {
+					t.Errorf("%s should be removed", d)
+				}
+			}
+			if _, err := os.Stat(join(tt.stopAt)); err != nil {
+				t.Errorf("stop directory should be kept: %v", err)
+			}
+			if tt.keep != "" {
+				if _, err := os.Stat(join(tt.keep)); err != nil {
+					t.Errorf("%s should be kept: %v", tt.keep, err)
+				}
+			}
+		})
+	}
+}
+
+// TestRemoveEmptyParents_SymlinkedDir tests that a symlink to an empty
+// directory is left alone
+func TestRemoveEmptyParents_SymlinkedDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "root")
+	target := filepath.Join(tmpDir, "target")
+	os.MkdirAll(root, 0755)
+	os.MkdirAll(target, 0755)
+	link := filepath.Join(root, "linked")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	removed, err := removeEmptyParents(filepath.Join(link, "file.conf"), root)
+	if err != nil {
+		t.Fatalf("removeEmptyParents() error = %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("removed = %v, want nothing", removed)
+	}
+	if _, err := os.Lstat(link); err != nil {
+		t.Errorf("symlink should be kept: %v", err)
+	}
+}
+
+// TestRemoveStorage tests what --all-then-remove-storage deletes and keeps
+func TestRemoveStorage(t *testing.T) {
+	tests := []struct {
+		name        string
+		unrestored  bool
+		override    bool
+		nested      bool
+		wantErr     error
+		wantRemoved bool
+		wantConfig  bool
+	}{
+		{name: "all restored", wantRemoved: true},
+		{name: "file not restored", unrestored: true, wantErr: ErrPartialFailure, wantConfig: true},
+		{name: "storage override keeps config", override: true, wantRemoved: true, wantConfig: true},
+		{name: "nested subpath", nested: true, wantErr: ErrConflict, wantConfig: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, originalPath, cloudPath := setupLinkedFile(t)
+			storagePath := filepath.Join(home, "storage")
+			dotsyncDir := manifest.DotsyncDir(storagePath, "")
+			if !tt.unrestored {
+				os.Remove(originalPath)
+				os.WriteFile(originalPath, []byte("content"), 0644)
+			}
+			if tt.override {
+				storageOverride = storagePath
+				t.Cleanup(func() { storageOverride = "" })
+			}
+			if tt.nested {
+				other := manifest.New()
+				other.Subpath = "team/alice"
+				if err := other.Save(storagePath); err != nil {
+					t.Fatal(err)
+				}
+			}
+			backups := []string{
+				filepath.Join(backup.StorageBackupDir(dotsyncDir), "config.json.20260101-000000.bak"),
+				filepath.Join(backup.ReplacedDir(dotsyncDir, "laptop"), "app", "config.json"),
+			}
+			for _, path := range backups {
+				os.MkdirAll(filepath.Dir(path), 0755)
+				os.WriteFile(path, []byte("old"), 0644)
+			}
+			m, err := manifest.Load(storagePath, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			useScript(t, "y")
+			err = removeStorage(m, storagePath)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("removeStorage() = %v, want %v", err, tt.wantErr)
+			}
+
+			_, err = os.Stat(cloudPath)
+			if removed := os.IsNotExist(err); removed != tt.wantRemoved {
+				t.Errorf("cloud file removed = %v, want %v", removed, tt.wantRemoved)
+			}
+			if removed := !manifest.Exists(storagePath, ""); removed != tt.wantRemoved {
+				t.Errorf("manifest removed = %v, want %v", removed, tt.wantRemoved)
+			}
+			for _, path := range backups {
+				if _, err := os.Stat(path); err != nil {
+					t.Errorf("backup %s was removed", path)
+				}
+			}
+			if tt.nested && !manifest.Exists(storagePath, "team/alice") {
+				t.Error("nested subpath was removed")
+			}
+			if exists, _ := config.Exists(); exists != tt.wantConfig {
+				t.Errorf("config exists = %v, want %v", exists, tt.wantConfig)
+			}
+		})
+	}
+}
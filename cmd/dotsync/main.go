@@ -18,7 +18,9 @@ var (
 func main() {
 	cmd.SetVersion(version, commit, date, builtBy)
 	if err := cmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		if !cmd.Reported(err) {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		os.Exit(cmd.ExitCode(err))
 	}
 }
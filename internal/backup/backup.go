@@ -7,12 +7,14 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/wtfzambo/dotsync/internal/pathutil"
 )
 
 // BackupDir returns the path to the backup directory.
 // Default: ~/.cache/dotsync/backups/
 func BackupDir() (string, error) {
-	home, err := os.UserHomeDir()
+	home, err := pathutil.HomeDir()
 	if err != nil {
 		return "", fmt.Errorf("getting home directory: %w", err)
 	}
@@ -33,6 +35,29 @@ func EnsureBackupDir() (string, error) {
 	return dir, nil
 }
 
+// StorageBackupDirName is the name of the backup directory inside
+// <storage>/dotsync/. It is not a valid entry name.
+const StorageBackupDirName = ".backups"
+
+// StorageBackupDir returns the path to the backup directory inside cloud
+// storage, given the dotsync folder (see manifest.DotsyncDir). Backups
+// placed here are preserved via cloud sync.
+// Structure: <storage>/dotsync/[<subpath>/].backups/
+func StorageBackupDir(dotsyncDir string) string {
+	return filepath.Join(dotsyncDir, StorageBackupDirName)
+}
+
+// ReplacedDirName is the name of the directory inside <storage>/dotsync/
+// holding files that link replaced on a machine. It is not a valid entry name.
+const ReplacedDirName = ".replaced"
+
+// ReplacedDir returns the directory for files replaced by link on the given
+// host. Unlike conflict backups these are meant to be kept, and are synced.
+// Structure: <storage>/dotsync/[<subpath>/].replaced/<hostname>/
+func ReplacedDir(dotsyncDir, hostname string) string {
+	return filepath.Join(dotsyncDir, ReplacedDirName, hostname)
+}
+
 // Backup represents a backup of a file.
 type Backup struct {
 	OriginalPath string
@@ -47,6 +72,16 @@ func Create(originalPath string) (*Backup, error) {
 		return nil, err
 	}
 
+	return CreateIn(dir, originalPath)
+}
+
+// CreateIn creates a backup of a file inside the given directory.
+// The directory is created if it doesn't exist.
+func CreateIn(dir, originalPath string) (*Backup, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating backup directory: %w", err)
+	}
+
 	// Generate backup filename: timestamp-originalfilename
 	timestamp := time.Now().Format("20060102-150405")
 	filename := filepath.Base(originalPath)
@@ -88,6 +123,26 @@ func (b *Backup) Cleanup() error {
 	return os.Remove(b.BackupPath)
 }
 
+// List returns the paths of all backup files in dir, sorted by name
+// (which is also creation order). Returns nil if dir doesn't exist.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading backup directory: %w", err)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths, nil
+}
+
 // copyFile copies a file from src to dst, preserving permissions.
 func copyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)
@@ -65,6 +65,64 @@ func TestCreate_NonExistentFile(t *testing.T) {
 	}
 }
 
+// TestCreateIn tests backup creation in a custom directory
+func TestCreateIn(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalFile := filepath.Join(tmpDir, "original.txt")
+	content := []byte("test content")
+	backupDir := filepath.Join(tmpDir, "storage", "dotsync", StorageBackupDirName)
+
+	if err := os.WriteFile(originalFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	backup, err := CreateIn(backupDir, originalFile)
+	if err != nil {
+		t.Fatalf("CreateIn() failed: %v", err)
+	}
+
+	if filepath.Dir(backup.BackupPath) != backupDir {
+		t.Errorf("backup dir = %q, want %q", filepath.Dir(backup.BackupPath), backupDir)
+	}
+
+	backupContent, err := os.ReadFile(backup.BackupPath)
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if string(backupContent) != string(content) {
+		t.Errorf("backup content = %q, want %q", backupContent, content)
+	}
+
+	// Restore should work the same as a regular backup
+	if err := os.Remove(originalFile); err != nil {
+		t.Fatalf("failed to remove original: %v", err)
+	}
+	if err := backup.Restore(); err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	if _, err := os.Stat(originalFile); err != nil {
+		t.Errorf("original not restored: %v", err)
+	}
+}
+
+// TestStorageBackupDir tests the storage backup directory path
+func TestStorageBackupDir(t *testing.T) {
+	got := StorageBackupDir(filepath.Join("/storage", "dotsync"))
+	want := filepath.Join("/storage", "dotsync", ".backups")
+	if got != want {
+		t.Errorf("StorageBackupDir() = %q, want %q", got, want)
+	}
+}
+
+// TestReplacedDir tests the replaced files directory path
+func TestReplacedDir(t *testing.T) {
+	got := ReplacedDir(filepath.Join("/storage", "dotsync"), "laptop")
+	want := filepath.Join("/storage", "dotsync", ".replaced", "laptop")
+	if got != want {
+		t.Errorf("ReplacedDir() = %q, want %q", got, want)
+	}
+}
+
 // TestRestore tests backup restoration
 func TestRestore(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -220,6 +278,38 @@ func TestEnsureBackupDir(t *testing.T) {
 	}
 }
 
+// TestList tests listing backups in a directory
+func TestList(t *testing.T) {
+	tmpDir := t.TempDir()
+	backupDir := filepath.Join(tmpDir, "backups")
+
+	// Missing directory lists nothing
+	paths, err := List(backupDir)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(paths) != 0 {
+		t.Errorf("expected no backups, got %v", paths)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		original := filepath.Join(tmpDir, name)
+		os.WriteFile(original, []byte(name), 0644)
+		if _, err := CreateIn(backupDir, original); err != nil {
+			t.Fatalf("CreateIn() failed: %v", err)
+		}
+	}
+	os.MkdirAll(filepath.Join(backupDir, "subdir"), 0755)
+
+	paths, err = List(backupDir)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Errorf("expected 2 backups, got %v", paths)
+	}
+}
+
 // TestBackup_PreservesPermissions tests that backup preserves file permissions
 func TestBackup_PreservesPermissions(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -2,17 +2,107 @@
 // The config is machine-specific and stored in ~/.config/dotsync/config.json
 package config
 
+import "fmt"
+
+// CurrentVersion is the current config schema version.
+const CurrentVersion = 1
+
 // Config represents the local dotsync configuration.
 // This is NOT synced - it's machine-specific.
 type Config struct {
+	// Version is the schema version, used to migrate older configs.
+	// Configs written before versioning have no version (0).
+	Version int `json:"version"`
+
 	// StoragePath is the path to the cloud storage folder
 	// e.g., "~/Library/CloudStorage/GoogleDrive-user@gmail.com/My Drive"
 	StoragePath string `json:"storagePath"`
+
+	// BackupToStorage places conflict backups in <storage>/dotsync/.backups/
+	// instead of ~/.cache/dotsync/backups/, so they survive via cloud sync
+	BackupToStorage bool `json:"backupToStorage,omitempty"`
+
+	// WindowsFallback tracks files in copy mode when symlinks can't be
+	// created (Windows without Developer Mode), like --windows-fallback
+	WindowsFallback bool `json:"windowsFallback,omitempty"`
+
+	// CopyIfSymlinkUnsupported tracks files in copy mode when symlinks can't
+	// be created, on Windows or on filesystems without symlink support,
+	// like --as-copy-if-symlink-unsupported on add and link
+	CopyIfSymlinkUnsupported bool `json:"copyIfSymlinkUnsupported,omitempty"`
+
+	// GitTrack stages files added to storage with git add, for storage
+	// kept in a git repository, like --git-track
+	GitTrack bool `json:"gitTrack,omitempty"`
+
+	// ConfirmAdds makes add ask before moving a file into cloud storage.
+	// Unset means enabled, see AddsNeedConfirmation.
+	ConfirmAdds *bool `json:"confirmAdds,omitempty"`
+
+	// StorageSubpath puts this machine's dotsync files in a folder below
+	// <storage>/dotsync, so machines or users sharing one cloud folder
+	// don't clobber each other. Empty uses <storage>/dotsync itself
+	// e.g., "alice"
+	StorageSubpath string `json:"storageSubpath,omitempty"`
+
+	// Permissions adds rules for 'dotsync fix-permissions', checked before
+	// the built-in ones
+	// e.g., [{"pattern": ".ssh/config", "mode": "0644"}]
+	Permissions []PermissionRule `json:"permissions,omitempty"`
+}
+
+// PermissionRule sets the mode of tracked files matching Pattern, a glob on
+// the path relative to home with forward slashes. Mode is octal, e.g. "0600".
+type PermissionRule struct {
+	Pattern string `json:"pattern"`
+	Mode    string `json:"mode"`
+}
+
+// AddsNeedConfirmation reports whether add should ask before moving a file
+// into cloud storage. True unless confirmAdds is set to false.
+func (c *Config) AddsNeedConfirmation() bool {
+	return c.ConfirmAdds == nil || *c.ConfirmAdds
 }
 
 // New creates a new config with the given storage path.
 func New(storagePath string) *Config {
 	return &Config{
+		Version:     CurrentVersion,
 		StoragePath: storagePath,
 	}
 }
+
+// ErrVersionTooNew is returned when the config version is newer than supported.
+type ErrVersionTooNew struct {
+	Version int
+}
+
+func (e ErrVersionTooNew) Error() string {
+	return fmt.Sprintf("config version %d not supported. Please upgrade dotsync", e.Version)
+}
+
+// migrations[i] upgrades a config from version i to version i+1.
+var migrations = []func(c *Config){
+	// 0 -> 1: versioning introduced, existing fields keep their meaning
+	func(c *Config) {},
+}
+
+// Migrate upgrades c to CurrentVersion in place.
+// Returns true if the config was changed and should be saved.
+// Returns ErrVersionTooNew if the config version is not supported.
+func Migrate(c *Config) (bool, error) {
+	if c.Version > CurrentVersion {
+		return false, ErrVersionTooNew{Version: c.Version}
+	}
+	if c.Version < 0 {
+		return false, fmt.Errorf("invalid config version %d", c.Version)
+	}
+
+	migrated := false
+	for c.Version < CurrentVersion {
+		migrations[c.Version](c)
+		c.Version++
+		migrated = true
+	}
+	return migrated, nil
+}
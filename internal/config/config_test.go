@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -215,6 +216,32 @@ func TestConfig_JSON(t *testing.T) {
 	}
 }
 
+// TestAddsNeedConfirmation tests that confirmAdds defaults to enabled and
+// only an explicit false turns it off
+func TestAddsNeedConfirmation(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want bool
+	}{
+		{"unset", `{"storagePath": "/s"}`, true},
+		{"true", `{"storagePath": "/s", "confirmAdds": true}`, true},
+		{"false", `{"storagePath": "/s", "confirmAdds": false}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			if err := json.Unmarshal([]byte(tt.json), &cfg); err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			if got := cfg.AddsNeedConfirmation(); got != tt.want {
+				t.Errorf("AddsNeedConfirmation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestConfig_JSONPretty tests pretty JSON formatting
 func TestConfig_JSONPretty(t *testing.T) {
 	cfg := New("/test/path")
@@ -253,6 +280,87 @@ func TestDelete(t *testing.T) {
 	_ = Delete
 }
 
+// TestLoad_MigratesOldConfig tests that a config without a version is
+// upgraded and saved back
+func TestLoad_MigratesOldConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	path, err := ConfigPath()
+	if err != nil {
+		t.Fatalf("ConfigPath() failed: %v", err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	old := `{"storagePath": "/test/storage", "backupToStorage": true}`
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+	}
+	if cfg.StoragePath != "/test/storage" || !cfg.BackupToStorage {
+		t.Errorf("fields not preserved: %+v", cfg)
+	}
+
+	// The upgraded config is written back
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	var saved Config
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(saved, *cfg) {
+		t.Errorf("saved config = %+v, want %+v", saved, *cfg)
+	}
+
+	// Loading again is a no-op
+	again, err := Load()
+	if err != nil {
+		t.Fatalf("second Load() failed: %v", err)
+	}
+	if !reflect.DeepEqual(*again, *cfg) {
+		t.Errorf("second Load() = %+v, want %+v", *again, *cfg)
+	}
+}
+
+// TestMigrate tests upgrading configs between versions
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      int
+		wantMigrated bool
+		wantErr      bool
+	}{
+		{"unversioned", 0, true, false},
+		{"current", CurrentVersion, false, false},
+		{"too new", CurrentVersion + 1, false, true},
+		{"negative", -1, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Version: tt.version, StoragePath: "/test/storage"}
+			migrated, err := Migrate(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Migrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if migrated != tt.wantMigrated {
+				t.Errorf("Migrate() = %v, want %v", migrated, tt.wantMigrated)
+			}
+			if !tt.wantErr && cfg.Version != CurrentVersion {
+				t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+			}
+		})
+	}
+}
+
 // Helper function
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && containsAt(s, substr))
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/wtfzambo/dotsync/internal/pathutil"
 )
 
 // ConfigDir returns the path to the dotsync config directory.
 // Default: ~/.config/dotsync
 func ConfigDir() (string, error) {
-	home, err := os.UserHomeDir()
+	home, err := pathutil.HomeDir()
 	if err != nil {
 		return "", fmt.Errorf("getting home directory: %w", err)
 	}
@@ -26,7 +28,8 @@ func ConfigPath() (string, error) {
 	return filepath.Join(dir, "config.json"), nil
 }
 
-// Load reads the local config file.
+// Load reads the local config file, upgrading and saving it back if it was
+// written by an older version (see Migrate).
 // Returns nil, nil if the config doesn't exist.
 func Load() (*Config, error) {
 	path, err := ConfigPath()
@@ -47,6 +50,16 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("parsing config: %w", err)
 	}
 
+	migrated, err := Migrate(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		if err := cfg.Save(); err != nil {
+			return nil, fmt.Errorf("saving migrated config: %w", err)
+		}
+	}
+
 	return &cfg, nil
 }
 
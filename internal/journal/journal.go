@@ -0,0 +1,165 @@
+// Package journal records add operations in progress, so that an add
+// interrupted by a crash (after the file was moved, but before the manifest
+// was saved) can be detected and recovered.
+//
+// The journal lives in cloud storage, so every machine sees it. Each
+// operation records the machine that started it, and only that machine
+// may finish it: the original paths and backups only exist there.
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileName is the name of the journal file inside <storage>/dotsync/.
+const FileName = ".journal"
+
+// Op is a file being added: moved from OriginalPath to CloudPath and
+// replaced with a symlink, but not yet recorded in the manifest.
+type Op struct {
+	Entry        string `json:"entry"`
+	Root         string `json:"root"`
+	RelPath      string `json:"relPath"`
+	OriginalPath string `json:"originalPath"`
+	CloudPath    string `json:"cloudPath"`
+	// BackupPath is the backup of the original file taken before the move
+	BackupPath string `json:"backupPath"`
+	// Hostname is the machine running the add
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// LocalHost returns the name of this machine, as recorded in Op.Hostname.
+func LocalHost() string {
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// IsLocal reports whether op was started on this machine. Operations
+// journaled before hostnames were recorded count as local.
+func (op Op) IsLocal() bool {
+	return op.Hostname == "" || op.Hostname == LocalHost()
+}
+
+// Split separates the operations started on this machine from those of
+// other machines.
+func Split(ops []Op) (local, foreign []Op) {
+	for _, op := range ops {
+		if op.IsLocal() {
+			local = append(local, op)
+		} else {
+			foreign = append(foreign, op)
+		}
+	}
+	return local, foreign
+}
+
+// Path returns the path of the journal file, given the dotsync folder (see
+// manifest.DotsyncDir). Every function of the package takes that folder.
+// Structure: <storage>/dotsync/[<subpath>/].journal
+func Path(dotsyncDir string) string {
+	return filepath.Join(dotsyncDir, FileName)
+}
+
+// Exists reports whether the journal holds operations of this machine,
+// i.e. an add is running or was interrupted here. An unreadable journal
+// counts as present, so 'dotsync recover' gets to report the problem.
+func Exists(dotsyncDir string) bool {
+	ops, err := Read(dotsyncDir)
+	if err != nil {
+		return true
+	}
+	local, _ := Split(ops)
+	return len(local) > 0
+}
+
+// Read returns the operations in the journal. Returns nil if there is no
+// journal.
+func Read(dotsyncDir string) ([]Op, error) {
+	data, err := os.ReadFile(Path(dotsyncDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+
+	var ops []Op
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("parsing journal: %w", err)
+	}
+	return ops, nil
+}
+
+// Write replaces the journal with ops. The journal is removed if ops is
+// empty. The file is written to a temporary file and renamed, so a crash
+// never leaves a half-written journal.
+func Write(dotsyncDir string, ops []Op) error {
+	if len(ops) == 0 {
+		return Clear(dotsyncDir)
+	}
+
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding journal: %w", err)
+	}
+
+	path := Path(dotsyncDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating dotsync directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	return nil
+}
+
+// Append adds op to the journal.
+func Append(dotsyncDir string, op Op) error {
+	ops, err := Read(dotsyncDir)
+	if err != nil {
+		return err
+	}
+	return Write(dotsyncDir, append(ops, op))
+}
+
+// Remove drops this machine's operation for originalPath from the journal.
+// Other machines may journal the same path for their own home.
+func Remove(dotsyncDir, originalPath string) error {
+	ops, err := Read(dotsyncDir)
+	if err != nil {
+		return err
+	}
+
+	kept := ops[:0]
+	for _, op := range ops {
+		if op.OriginalPath != originalPath || !op.IsLocal() {
+			kept = append(kept, op)
+		}
+	}
+	return Write(dotsyncDir, kept)
+}
+
+// Clear drops this machine's operations once they are finished. The
+// journal is removed unless other machines still have operations in it.
+func Clear(dotsyncDir string) error {
+	ops, err := Read(dotsyncDir)
+	if err != nil {
+		return err
+	}
+	_, foreign := Split(ops)
+	if len(foreign) > 0 {
+		return Write(dotsyncDir, foreign)
+	}
+	if err := os.Remove(Path(dotsyncDir)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package journal
+
+import (
+	"os"
+	"testing"
+)
+
+// TestAppendReadRemove tests the journal lifecycle of an add
+func TestAppendReadRemove(t *testing.T) {
+	dotsyncDir := t.TempDir()
+
+	ops, err := Read(dotsyncDir)
+	if err != nil || ops != nil {
+		t.Fatalf("Read() without journal = %v, %v; want nil, nil", ops, err)
+	}
+
+	a := Op{Entry: "zsh", Root: "~", RelPath: ".zshrc", OriginalPath: "/home/u/.zshrc", CloudPath: "/s/dotsync/zsh/.zshrc"}
+	b := Op{Entry: "git", Root: "~", RelPath: ".gitconfig", OriginalPath: "/home/u/.gitconfig", CloudPath: "/s/dotsync/git/.gitconfig"}
+	for _, op := range []Op{a, b} {
+		if err := Append(dotsyncDir, op); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+	if !Exists(dotsyncDir) {
+		t.Fatal("Exists() = false after Append()")
+	}
+
+	ops, err = Read(dotsyncDir)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if len(ops) != 2 || ops[0] != a || ops[1] != b {
+		t.Fatalf("Read() = %+v, want [%+v %+v]", ops, a, b)
+	}
+
+	if err := Remove(dotsyncDir, a.OriginalPath); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	ops, _ = Read(dotsyncDir)
+	if len(ops) != 1 || ops[0] != b {
+		t.Fatalf("Read() after Remove() = %+v, want [%+v]", ops, b)
+	}
+
+	// Removing the last operation removes the journal
+	if err := Remove(dotsyncDir, b.OriginalPath); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if Exists(dotsyncDir) {
+		t.Error("journal should be removed once empty")
+	}
+}
+
+// TestClear tests clearing with and without a journal
+func TestClear(t *testing.T) {
+	dotsyncDir := t.TempDir()
+
+	if err := Clear(dotsyncDir); err != nil {
+		t.Errorf("Clear() without journal failed: %v", err)
+	}
+
+	if err := Append(dotsyncDir, Op{OriginalPath: "/a"}); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if err := Clear(dotsyncDir); err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if _, err := os.Stat(Path(dotsyncDir)); !os.IsNotExist(err) {
+		t.Errorf("journal still exists after Clear(): %v", err)
+	}
+}
+
+// TestForeignOps tests that operations of other machines, which see the
+// same journal through the storage, are left alone
+func TestForeignOps(t *testing.T) {
+	dotsyncDir := t.TempDir()
+
+	foreign := Op{Entry: "zsh", OriginalPath: "/home/u/.zshrc", Hostname: "other-machine"}
+	local := Op{Entry: "zsh", OriginalPath: "/home/u/.zshrc", Hostname: LocalHost()}
+	if err := Write(dotsyncDir, []Op{foreign}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if Exists(dotsyncDir) {
+		t.Error("Exists() = true with only another machine's operation")
+	}
+
+	if err := Append(dotsyncDir, local); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if !Exists(dotsyncDir) {
+		t.Error("Exists() = false with a local operation")
+	}
+
+	// The same path journaled by another machine isn't removed
+	if err := Remove(dotsyncDir, local.OriginalPath); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	ops, _ := Read(dotsyncDir)
+	if len(ops) != 1 || ops[0] != foreign {
+		t.Fatalf("Read() after Remove() = %+v, want [%+v]", ops, foreign)
+	}
+
+	if err := Append(dotsyncDir, local); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if err := Clear(dotsyncDir); err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	ops, _ = Read(dotsyncDir)
+	if len(ops) != 1 || ops[0] != foreign {
+		t.Errorf("Read() after Clear() = %+v, want [%+v]", ops, foreign)
+	}
+}
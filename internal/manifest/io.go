@@ -1,14 +1,22 @@
 package manifest
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 )
 
 const ManifestFileName = ".dotsync.json"
 
+// utf8BOM is the byte order mark some Windows editors prepend to UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
 // ErrVersionTooNew is returned when the manifest version is newer than supported.
 type ErrVersionTooNew struct {
 	Version int
@@ -18,10 +26,12 @@ func (e ErrVersionTooNew) Error() string {
 	return fmt.Sprintf("manifest version %d not supported. Please upgrade dotsync", e.Version)
 }
 
-// Load reads a manifest from the given dotsync storage directory.
-// Returns ErrVersionTooNew if the manifest version is not supported.
-func Load(storagePath string) (*Manifest, error) {
-	manifestPath := filepath.Join(storagePath, "dotsync", ManifestFileName)
+// Load reads the manifest kept under the given storage subpath of the
+// storage directory ("" for none), and remembers the subpath in
+// Manifest.Subpath. Returns ErrVersionTooNew if the manifest version is
+// not supported.
+func Load(storagePath, subpath string) (*Manifest, error) {
+	manifestPath := ManifestPath(storagePath, subpath)
 
 	data, err := os.ReadFile(manifestPath)
 	if err != nil {
@@ -31,9 +41,15 @@ func Load(storagePath string) (*Manifest, error) {
 		return nil, fmt.Errorf("reading manifest: %w", err)
 	}
 
+	// A BOM is harmless, but json.Unmarshal rejects it
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		return nil, fmt.Errorf("parsing manifest %s: file is UTF-16 encoded\nSave it as UTF-8 and try again", manifestPath)
+	}
+
 	var m Manifest
 	if err := json.Unmarshal(data, &m); err != nil {
-		return nil, fmt.Errorf("parsing manifest: %w", err)
+		return nil, fmt.Errorf("parsing manifest %s: %w\nIf it was edited by hand, check that it is valid JSON saved as UTF-8", manifestPath, err)
 	}
 
 	// Version check
@@ -46,12 +62,40 @@ func Load(storagePath string) (*Manifest, error) {
 		m.Entries = make(map[string]Entry)
 	}
 
+	// Normalize roots and relative paths written by older versions, on
+	// another platform, or edited by hand. Entries from before the storage
+	// folder was recorded keep the <name> folder they were created with.
+	for name, entry := range m.Entries {
+		entry.Root = NormalizeRoot(entry.Root)
+		storage, err := cleanStorageDir(entry.StorageDir(name), name)
+		if err != nil {
+			return nil, fmt.Errorf("parsing manifest %s: entry '%s': %w", manifestPath, name, err)
+		}
+		entry.Storage = storage
+		for i, f := range entry.Files {
+			entry.Files[i] = ToStorageSlash(f)
+		}
+		if entry.Modes != nil {
+			modes := make(map[string]LinkMode, len(entry.Modes))
+			for f, mode := range entry.Modes {
+				modes[ToStorageSlash(f)] = mode
+			}
+			entry.Modes = modes
+		}
+		m.Entries[name] = entry
+	}
+
+	// Collapse duplicate files so link/unlink don't process them twice
+	m.Dedup()
+
+	m.Subpath = subpath
 	return &m, nil
 }
 
-// Save writes the manifest to the given dotsync storage directory.
+// Save writes the manifest to the given storage directory, under
+// m.Subpath.
 func (m *Manifest) Save(storagePath string) error {
-	dotsyncDir := filepath.Join(storagePath, "dotsync")
+	dotsyncDir := m.DotsyncDir(storagePath)
 	manifestPath := filepath.Join(dotsyncDir, ManifestFileName)
 
 	// Ensure the dotsync directory exists
@@ -71,13 +115,237 @@ func (m *Manifest) Save(storagePath string) error {
 	return nil
 }
 
+// UnmarshalJSON decodes a manifest, keeping unknown fields in Extra.
+func (m *Manifest) UnmarshalJSON(data []byte) error {
+	type plain Manifest
+	if err := json.Unmarshal(data, (*plain)(m)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, plain{})
+	m.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes a manifest, writing the fields in Extra back after
+// the known ones.
+func (m Manifest) MarshalJSON() ([]byte, error) {
+	type plain Manifest
+	data, err := json.Marshal(plain(m))
+	if err != nil {
+		return nil, err
+	}
+	return appendFields(data, m.Extra)
+}
+
+// UnmarshalJSON decodes an entry, keeping unknown fields in Extra.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	type plain Entry
+	if err := json.Unmarshal(data, (*plain)(e)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, plain{})
+	e.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes an entry, writing the fields in Extra back after the
+// known ones.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type plain Entry
+	data, err := json.Marshal(plain(e))
+	if err != nil {
+		return nil, err
+	}
+	return appendFields(data, e.Extra)
+}
+
+// unknownFields returns the fields of the JSON object in data that have no
+// matching json tag in the struct v. Tags match case-insensitively, like
+// encoding/json does when decoding. Returns nil if there are none.
+func unknownFields(data []byte, v any) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		for key := range fields {
+			if strings.EqualFold(key, name) {
+				delete(fields, key)
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// appendFields adds the given fields, sorted by name, to the end of the
+// JSON object in data.
+func appendFields(data []byte, fields map[string]json.RawMessage) ([]byte, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimSuffix(data, []byte("}")))
+	for _, name := range names {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(fields[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// DotsyncDir returns the folder holding the manifest and entry folders:
+// <storagePath>/dotsync, or <storagePath>/dotsync/<subpath>. A storage
+// subpath (see CleanSubpath) lets several machines or users share one
+// cloud folder without clobbering each other's files.
+func DotsyncDir(storagePath, subpath string) string {
+	return filepath.Join(storagePath, "dotsync", FromStorageSlash(subpath))
+}
+
+// DotsyncDir returns the folder holding the manifest and entry folders,
+// under m.Subpath.
+func (m *Manifest) DotsyncDir(storagePath string) string {
+	return DotsyncDir(storagePath, m.Subpath)
+}
+
+// CleanSubpath checks a storage subpath and returns it with forward
+// slashes, e.g. "team\\alice" -> "team/alice". It must be relative and
+// can't contain "..", or folders starting with "." (dotsync's own files
+// use those names).
+func CleanSubpath(p string) (string, error) {
+	if filepath.IsAbs(p) || filepath.VolumeName(p) != "" || strings.HasPrefix(ToStorageSlash(p), "/") {
+		return "", fmt.Errorf("storage subpath %q must be relative", p)
+	}
+	p = strings.TrimSuffix(ToStorageSlash(p), "/")
+	if p == "" {
+		return "", nil
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == "" || strings.HasPrefix(part, ".") {
+			return "", fmt.Errorf("invalid storage subpath %q: folders can't be empty or start with '.'", p)
+		}
+	}
+	return p, nil
+}
+
+// NestedTrees returns the storage subpaths kept inside dotsyncDir: folders
+// below it holding their own manifest, relative to dotsyncDir with forward
+// slashes. A machine without a subpath shares its folder with these, so
+// scans and removals of dotsyncDir must leave them alone.
+func NestedTrees(dotsyncDir string) ([]string, error) {
+	var trees []string
+	err := filepath.WalkDir(dotsyncDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dotsyncDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() || path == dotsyncDir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, ManifestFileName)); err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(dotsyncDir, path)
+		if err != nil {
+			return err
+		}
+		trees = append(trees, ToStorageSlash(rel))
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning storage subpaths: %w", err)
+	}
+	return trees, nil
+}
+
+// Overlaps reports whether two storage folders (forward slashes, relative
+// to the same dotsync folder) are the same or one contains the other.
+func Overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// CheckSubpath returns an error if subpath would share folders with an
+// entry of a manifest kept higher up in the same storage, e.g. subpath
+// "alice" below a machine without a subpath that tracks an entry "alice".
+func CheckSubpath(storagePath, subpath string) error {
+	if subpath == "" {
+		return nil
+	}
+	parts := strings.Split(subpath, "/")
+	for i := range parts {
+		parent, rest := strings.Join(parts[:i], "/"), strings.Join(parts[i:], "/")
+		if !Exists(storagePath, parent) {
+			continue
+		}
+		m, err := Load(storagePath, parent)
+		if err != nil {
+			return err
+		}
+		for _, name := range m.Names() {
+			if Overlaps(m.Entries[name].StorageDir(name), rest) {
+				return fmt.Errorf("storage subpath %q overlaps entry '%s' stored in %s", subpath, name, m.DotsyncDir(storagePath))
+			}
+		}
+	}
+	return nil
+}
+
+// cleanStorageDir checks an entry's storage folder and returns it with
+// forward slashes. Like a subpath (see CleanSubpath), it must stay inside
+// the dotsync folder and not use dotsync's own "."-prefixed names. A folder
+// named after the entry only needs to be a single folder, since names
+// starting with "." were accepted before storage folders were recorded.
+func cleanStorageDir(storage, name string) (string, error) {
+	if storage == name {
+		if filepath.IsAbs(name) || strings.ContainsAny(name, `/\`) || name == "." || name == ".." || name == "" {
+			return "", fmt.Errorf("invalid storage folder %q", name)
+		}
+		return name, nil
+	}
+	cleaned, err := CleanSubpath(storage)
+	if err != nil || cleaned == "" {
+		return "", fmt.Errorf("invalid storage folder %q: must be relative, and folders can't be empty or start with '.'", storage)
+	}
+	return cleaned, nil
+}
+
 // ManifestPath returns the full path to the manifest file.
-func ManifestPath(storagePath string) string {
-	return filepath.Join(storagePath, "dotsync", ManifestFileName)
+func ManifestPath(storagePath, subpath string) string {
+	return filepath.Join(DotsyncDir(storagePath, subpath), ManifestFileName)
 }
 
-// Exists checks if a manifest exists at the given storage path.
-func Exists(storagePath string) bool {
-	_, err := os.Stat(ManifestPath(storagePath))
+// Exists checks if a manifest exists at the given storage path and
+// subpath.
+func Exists(storagePath, subpath string) bool {
+	_, err := os.Stat(ManifestPath(storagePath, subpath))
 	return err == nil
 }
@@ -2,8 +2,11 @@ package manifest
 
 import (
 	"encoding/json"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -89,7 +92,7 @@ func TestLoad(t *testing.T) {
 	}
 
 	// Load the manifest
-	m, err := Load(tmpDir)
+	m, err := Load(tmpDir, "")
 	if err != nil {
 		t.Fatalf("Load() failed: %v", err)
 	}
@@ -131,7 +134,7 @@ func TestLoad(t *testing.T) {
 func TestLoad_NotFound(t *testing.T) {
 	tmpDir := t.TempDir()
 
-	_, err := Load(tmpDir)
+	_, err := Load(tmpDir, "")
 	if err == nil {
 		t.Fatal("Load() should fail when manifest doesn't exist")
 	}
@@ -162,7 +165,7 @@ func TestLoad_VersionTooNew(t *testing.T) {
 		t.Fatalf("failed to write manifest: %v", err)
 	}
 
-	_, err := Load(tmpDir)
+	_, err := Load(tmpDir, "")
 	if err == nil {
 		t.Fatal("Load() should fail for newer version")
 	}
@@ -193,7 +196,7 @@ func TestLoad_EmptyManifest(t *testing.T) {
 		t.Fatalf("failed to write manifest: %v", err)
 	}
 
-	m, err := Load(tmpDir)
+	m, err := Load(tmpDir, "")
 	if err != nil {
 		t.Fatalf("Load() failed: %v", err)
 	}
@@ -225,10 +228,87 @@ func TestLoad_InvalidJSON(t *testing.T) {
 		t.Fatalf("failed to write manifest: %v", err)
 	}
 
-	_, err := Load(tmpDir)
+	_, err := Load(tmpDir, "")
 	if err == nil {
 		t.Fatal("Load() should fail for invalid JSON")
 	}
+	if !strings.Contains(err.Error(), manifestPath) {
+		t.Errorf("error %q should name the manifest path", err)
+	}
+}
+
+// TestLoad_BOM tests that a manifest saved with a UTF-8 byte order mark
+// loads, and that UTF-16 manifests get a clear error
+func TestLoad_BOM(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := New()
+	m.AddFile("zsh", "~", ".zshrc")
+	if err := m.Save(tmpDir); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	manifestPath := ManifestPath(tmpDir, "")
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+
+	if err := os.WriteFile(manifestPath, append([]byte("\xEF\xBB\xBF"), data...), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	loaded, err := Load(tmpDir, "")
+	if err != nil {
+		t.Fatalf("Load() with BOM failed: %v", err)
+	}
+	if !loaded.HasEntry("zsh") {
+		t.Error("entry 'zsh' missing after loading a BOM-prefixed manifest")
+	}
+
+	if err := os.WriteFile(manifestPath, append([]byte("\xFF\xFE"), data...), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	_, err = Load(tmpDir, "")
+	if err == nil || !strings.Contains(err.Error(), "UTF-16") {
+		t.Errorf("Load() error = %v, want UTF-16 hint", err)
+	}
+}
+
+// TestLoad_DuplicateFiles tests that duplicate files in an entry are collapsed
+func TestLoad_DuplicateFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	dotsyncDir := filepath.Join(tmpDir, "dotsync")
+	if err := os.MkdirAll(dotsyncDir, 0755); err != nil {
+		t.Fatalf("failed to create dotsync dir: %v", err)
+	}
+
+	manifestData := `{
+  "version": 1,
+  "entries": {
+    "opencode": {
+      "root": "~/.config/opencode",
+      "files": ["config.json", "agents/review.md", "config.json"]
+    }
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dotsyncDir, ManifestFileName), []byte(manifestData), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	m, err := Load(tmpDir, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	files := m.GetEntry("opencode").Files
+	want := []string{"config.json", "agents/review.md"}
+	if len(files) != len(want) {
+		t.Fatalf("Files = %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("Files[%d] = %q, want %q", i, files[i], want[i])
+		}
+	}
 }
 
 // TestSaveLoad_RoundTrip tests saving and loading preserves data
@@ -241,13 +321,14 @@ func TestSaveLoad_RoundTrip(t *testing.T) {
 	original.AddFile("opencode", "~/.config/opencode", "agents/review.md")
 	original.AddFile("vscode", "~/.config/Code", "settings.json")
 	original.AddFile("zsh", "~", ".zshrc")
+	original.SetDescription("zsh", "shell config")
 
 	if err := original.Save(tmpDir); err != nil {
 		t.Fatalf("Save() failed: %v", err)
 	}
 
 	// Load it back
-	loaded, err := Load(tmpDir)
+	loaded, err := Load(tmpDir, "")
 	if err != nil {
 		t.Fatalf("Load() failed: %v", err)
 	}
@@ -272,6 +353,10 @@ func TestSaveLoad_RoundTrip(t *testing.T) {
 			t.Errorf("entry %q: Root = %q, want %q", name, loadedEntry.Root, origEntry.Root)
 		}
 
+		if loadedEntry.Description != origEntry.Description {
+			t.Errorf("entry %q: Description = %q, want %q", name, loadedEntry.Description, origEntry.Description)
+		}
+
 		if len(loadedEntry.Files) != len(origEntry.Files) {
 			t.Errorf("entry %q: expected %d files, got %d", name, len(origEntry.Files), len(loadedEntry.Files))
 			continue
@@ -285,12 +370,351 @@ func TestSaveLoad_RoundTrip(t *testing.T) {
 	}
 }
 
+// TestSave_StableOrder tests that saving writes entries in sorted order,
+// whatever order they were added in, so repeated saves are byte-identical
+func TestSave_StableOrder(t *testing.T) {
+	names := []string{"zsh", "alacritty", "opencode", "git"}
+	var saved [][]byte
+	for _, order := range [][]string{names, {"git", "opencode", "alacritty", "zsh"}} {
+		m := New()
+		for _, name := range order {
+			m.AddFile(name, "~/.config/"+name, "config")
+		}
+		dir := t.TempDir()
+		if err := m.Save(dir); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+		data, err := os.ReadFile(ManifestPath(dir, ""))
+		if err != nil {
+			t.Fatalf("failed to read manifest: %v", err)
+		}
+		saved = append(saved, data)
+	}
+
+	if string(saved[0]) != string(saved[1]) {
+		t.Errorf("saves differ:\n%s\n---\n%s", saved[0], saved[1])
+	}
+	last := -1
+	for _, name := range []string{"alacritty", "git", "opencode", "zsh"} {
+		i := strings.Index(string(saved[0]), `"`+name+`"`)
+		if i < last {
+			t.Errorf("entry %q out of order in saved manifest", name)
+		}
+		last = i
+	}
+}
+
+// TestSaveLoad_UnknownFields tests that fields written by a newer dotsync
+// survive a load/save cycle, both on the manifest and on entries
+func TestSaveLoad_UnknownFields(t *testing.T) {
+	tmpDir := t.TempDir()
+	manifestPath := ManifestPath(tmpDir, "")
+	os.MkdirAll(filepath.Dir(manifestPath), 0755)
+
+	content := `{
+  "version": 1,
+  "entries": {
+    "zsh": {
+      "root": "~",
+      "files": [".zshrc"],
+      "tags": ["shell"]
+    }
+  },
+  "hooks": {"postLink": "echo done"}
+}`
+	if err := os.WriteFile(manifestPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	m, err := Load(tmpDir, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	m.AddFile("zsh", "~", ".zprofile")
+	if err := m.Save(tmpDir); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var saved struct {
+		Entries map[string]struct {
+			Files []string `json:"files"`
+			Tags  []string `json:"tags"`
+		} `json:"entries"`
+		Hooks map[string]string `json:"hooks"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("saved manifest is invalid JSON: %v\n%s", err, data)
+	}
+
+	if got := saved.Hooks["postLink"]; got != "echo done" {
+		t.Errorf("hooks.postLink = %q, want %q", got, "echo done")
+	}
+	zsh := saved.Entries["zsh"]
+	if len(zsh.Tags) != 1 || zsh.Tags[0] != "shell" {
+		t.Errorf("entries.zsh.tags = %v, want [shell]", zsh.Tags)
+	}
+	if len(zsh.Files) != 2 {
+		t.Errorf("entries.zsh.files = %v, want 2 files", zsh.Files)
+	}
+
+	// Known fields are not duplicated into Extra
+	if _, ok := m.Extra["version"]; ok {
+		t.Error("Extra should not contain known fields")
+	}
+}
+
+// TestSaveLoad_UnknownFieldsCase tests that known fields written with
+// different casing are decoded, not kept as unknown fields and saved twice
+func TestSaveLoad_UnknownFieldsCase(t *testing.T) {
+	tmpDir := t.TempDir()
+	manifestPath := ManifestPath(tmpDir, "")
+	os.MkdirAll(filepath.Dir(manifestPath), 0755)
+
+	content := `{"Version": 1, "Entries": {"zsh": {"Root": "~", "FILES": [".zshrc"]}}}`
+	if err := os.WriteFile(manifestPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	m, err := Load(tmpDir, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	zsh := m.GetEntry("zsh")
+	if zsh == nil || zsh.Root != "~" || len(zsh.Files) != 1 {
+		t.Fatalf("zsh entry = %+v, want root ~ with 1 file", zsh)
+	}
+	if len(m.Extra) != 0 || len(zsh.Extra) != 0 {
+		t.Errorf("Extra = %v, entry Extra = %v, want none", m.Extra, zsh.Extra)
+	}
+
+	if err := m.Save(tmpDir); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	for _, key := range []string{`"Version"`, `"Entries"`, `"Root"`, `"FILES"`} {
+		if strings.Contains(string(data), key) {
+			t.Errorf("saved manifest still contains %s:\n%s", key, data)
+		}
+	}
+}
+
+// TestSaveLoad_Meta tests that arbitrary metadata, including keys this
+// version doesn't use, survives a load/save cycle unchanged
+func TestSaveLoad_Meta(t *testing.T) {
+	tmpDir := t.TempDir()
+	manifestPath := ManifestPath(tmpDir, "")
+	os.MkdirAll(filepath.Dir(manifestPath), 0755)
+
+	meta := map[string]string{
+		"syncedBy":      "laptop",
+		"future.key":    `value with "quotes" and \\ backslashes`,
+		"unicode-ключ":  "值",
+		"empty-looking": " ",
+	}
+	content, _ := json.Marshal(map[string]any{
+		"version": 1,
+		"entries": map[string]any{
+			"zsh": map[string]any{"root": "~", "files": []string{".zshrc"}, "meta": meta},
+		},
+	})
+	if err := os.WriteFile(manifestPath, content, 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	for i := range 2 {
+		m, err := Load(tmpDir, "")
+		if err != nil {
+			t.Fatalf("Load() #%d failed: %v", i+1, err)
+		}
+		if got := m.GetEntry("zsh").Meta; !maps.Equal(got, meta) {
+			t.Fatalf("Meta after load #%d = %v, want %v", i+1, got, meta)
+		}
+		if _, ok := m.Entries["zsh"].Extra["meta"]; ok {
+			t.Error("meta should not be duplicated into Extra")
+		}
+		m.AddFile("zsh", "~", ".zprofile")
+		if err := m.Save(tmpDir); err != nil {
+			t.Fatalf("Save() #%d failed: %v", i+1, err)
+		}
+	}
+}
+
+// TestSaveLoad_Comments tests that hand-written comments survive a
+// load/save cycle as written, with the manifest's comments first
+func TestSaveLoad_Comments(t *testing.T) {
+	tmpDir := t.TempDir()
+	manifestPath := ManifestPath(tmpDir, "")
+	os.MkdirAll(filepath.Dir(manifestPath), 0755)
+
+	content := `{
+  "version": 1,
+  "_comments": ["Shared by laptop and desktop", "Ask before adding secrets"],
+  "entries": {
+    "zsh": {
+      "root": "~",
+      "files": [".zshrc"],
+      "_comment": "History stays local"
+    },
+    "git": {
+      "_comment": {"owner": "alice", "reviewed": true},
+      "root": "~",
+      "files": [".gitconfig"]
+    }
+  }
+}`
+	if err := os.WriteFile(manifestPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	m, err := Load(tmpDir, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(m.Extra) != 0 || len(m.Entries["zsh"].Extra) != 0 {
+		t.Errorf("comments should not end up in Extra: %v, %v", m.Extra, m.Entries["zsh"].Extra)
+	}
+	m.AddFile("zsh", "~", ".zprofile")
+	if err := m.Save(tmpDir); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var saved struct {
+		Comments []string `json:"_comments"`
+		Entries  map[string]struct {
+			Comment json.RawMessage `json:"_comment"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("saved manifest is invalid JSON: %v\n%s", err, data)
+	}
+
+	if len(saved.Comments) != 2 || saved.Comments[1] != "Ask before adding secrets" {
+		t.Errorf("_comments = %v, want both comments", saved.Comments)
+	}
+	if got := string(saved.Entries["zsh"].Comment); got != `"History stays local"` {
+		t.Errorf("zsh _comment = %s, want \"History stays local\"", got)
+	}
+	var gitComment map[string]any
+	if err := json.Unmarshal(saved.Entries["git"].Comment, &gitComment); err != nil || gitComment["owner"] != "alice" {
+		t.Errorf("git _comment = %s, want the object as written", saved.Entries["git"].Comment)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(string(data)), "{\n  \"_comments\"") {
+		t.Errorf("_comments should be written first:\n%s", data)
+	}
+}
+
+// TestLoad_NoDescription tests that manifests without descriptions still
+// load, and that no description isn't written out
+func TestLoad_NoDescription(t *testing.T) {
+	tmpDir := t.TempDir()
+	dotsyncDir := filepath.Join(tmpDir, "dotsync")
+	os.MkdirAll(dotsyncDir, 0755)
+
+	content := `{"version": 1, "entries": {"zsh": {"root": "~", "files": [".zshrc"]}}}`
+	os.WriteFile(filepath.Join(dotsyncDir, ManifestFileName), []byte(content), 0644)
+
+	m, err := Load(tmpDir, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if d := m.GetEntry("zsh").Description; d != "" {
+		t.Errorf("Description = %q, want empty", d)
+	}
+
+	if err := m.Save(tmpDir); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dotsyncDir, ManifestFileName))
+	if strings.Contains(string(data), "description") {
+		t.Errorf("empty description should be omitted, got %s", data)
+	}
+}
+
+// TestLoad_StorageMigration tests that entries without a storage folder get
+// their name, and explicit folders are kept
+func TestLoad_StorageMigration(t *testing.T) {
+	tmpDir := t.TempDir()
+	dotsyncDir := filepath.Join(tmpDir, "dotsync")
+	os.MkdirAll(dotsyncDir, 0755)
+
+	content := `{"version": 1, "entries": {
+		"zsh": {"root": "~", "files": [".zshrc"]},
+		"nvim": {"root": "~/.config/nvim", "storage": "editors\\nvim", "files": ["init.lua"]}
+	}}`
+	os.WriteFile(filepath.Join(dotsyncDir, ManifestFileName), []byte(content), 0644)
+
+	m, err := Load(tmpDir, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if s := m.GetEntry("zsh").Storage; s != "zsh" {
+		t.Errorf("zsh Storage = %q, want %q", s, "zsh")
+	}
+	if s := m.GetEntry("nvim").Storage; s != "editors/nvim" {
+		t.Errorf("nvim Storage = %q, want %q", s, "editors/nvim")
+	}
+
+	if err := m.Save(tmpDir); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dotsyncDir, ManifestFileName))
+	if !strings.Contains(string(data), `"storage": "zsh"`) {
+		t.Errorf("migrated storage folder should be saved, got %s", data)
+	}
+}
+
+// TestLoad_InvalidStorage tests that storage folders leaving the dotsync
+// folder or using dotsync's own names are refused
+func TestLoad_InvalidStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{"nested folder", `"app": {"root": "~", "storage": "tools/app"}`, false},
+		{"dot-prefixed name without storage", `".vim": {"root": "~/.vim"}`, false},
+		{"dot-prefixed name as storage", `".vim": {"root": "~/.vim", "storage": ".vim"}`, false},
+		{"absolute", `"app": {"root": "~", "storage": "/etc"}`, true},
+		{"parent", `"app": {"root": "~", "storage": "../outside"}`, true},
+		{"parent inside", `"app": {"root": "~", "storage": "tools/../../outside"}`, true},
+		{"dotsync folder", `"app": {"root": "~", "storage": ".backups"}`, true},
+		{"dot-prefixed segment", `"app": {"root": "~", "storage": "tools/.hidden"}`, true},
+		{"name with parent", `"..": {"root": "~"}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			manifestPath := ManifestPath(tmpDir, "")
+			os.MkdirAll(filepath.Dir(manifestPath), 0755)
+			content := `{"version": 1, "entries": {` + tt.entry + `}}`
+			os.WriteFile(manifestPath, []byte(content), 0644)
+
+			_, err := Load(tmpDir, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 // TestExists tests manifest existence checking
 func TestExists(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	// Should not exist initially
-	if Exists(tmpDir) {
+	if Exists(tmpDir, "") {
 		t.Error("Exists() returned true for non-existent manifest")
 	}
 
@@ -301,7 +725,7 @@ func TestExists(t *testing.T) {
 	}
 
 	// Should exist now
-	if !Exists(tmpDir) {
+	if !Exists(tmpDir, "") {
 		t.Error("Exists() returned false for existing manifest")
 	}
 }
@@ -311,7 +735,7 @@ func TestManifestPath(t *testing.T) {
 	storagePath := "/path/to/storage"
 	expected := filepath.Join(storagePath, "dotsync", ManifestFileName)
 
-	got := ManifestPath(storagePath)
+	got := ManifestPath(storagePath, "")
 	if got != expected {
 		t.Errorf("ManifestPath() = %q, want %q", got, expected)
 	}
@@ -326,3 +750,171 @@ func TestErrVersionTooNew(t *testing.T) {
 		t.Errorf("Error() = %q, want %q", err.Error(), expected)
 	}
 }
+
+// TestLoad_WindowsSeparators tests that a manifest written on Windows loads
+// with forward slashes and maps back to OS paths
+func TestLoad_WindowsSeparators(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	dotsyncDir := filepath.Join(tmpDir, "dotsync")
+	if err := os.MkdirAll(dotsyncDir, 0755); err != nil {
+		t.Fatalf("failed to create dotsync dir: %v", err)
+	}
+
+	manifestData := `{
+  "version": 1,
+  "entries": {
+    "opencode": {
+      "root": "~\\.config\\opencode",
+      "files": ["config.json", "agents\\review.md"],
+      "modes": {"agents\\review.md": "copy"}
+    }
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dotsyncDir, ManifestFileName), []byte(manifestData), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	m, err := Load(tmpDir, "")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	entry := m.GetEntry("opencode")
+	if entry.Root != "~/.config/opencode" {
+		t.Errorf("Root = %q, want %q", entry.Root, "~/.config/opencode")
+	}
+	if entry.Files[1] != "agents/review.md" {
+		t.Errorf("Files[1] = %q, want %q", entry.Files[1], "agents/review.md")
+	}
+	if entry.FileMode("agents/review.md") != ModeCopy {
+		t.Error("mode override lost when normalizing separators")
+	}
+
+	// Round trip: saved with forward slashes, read back identically
+	if err := m.Save(tmpDir); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dotsyncDir, ManifestFileName))
+	if strings.Contains(string(data), `\\`) {
+		t.Errorf("saved manifest still contains backslashes:\n%s", data)
+	}
+
+	want := filepath.Join("agents", "review.md")
+	if got := FromStorageSlash(entry.Files[1]); got != want {
+		t.Errorf("FromStorageSlash(%q) = %q, want %q", entry.Files[1], got, want)
+	}
+}
+
+// TestCleanSubpath tests normalizing and rejecting storage subpaths
+func TestCleanSubpath(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"alice", "alice", false},
+		{`team\alice/`, "team/alice", false},
+		{"/abs", "", true},
+		{"../other", "", true},
+		{".backups", "", true},
+		{"team//alice", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := CleanSubpath(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("CleanSubpath(%q) = %q, %v, want %q, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestSubpath tests that the manifest and cloud files move below the subpath
+func TestSubpath(t *testing.T) {
+	storagePath := t.TempDir()
+
+	m := New()
+	m.Subpath = "team/alice"
+	m.AddFile("app", "~/.config/app", "config.json")
+	if err := m.Save(storagePath); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	dir := filepath.Join(storagePath, "dotsync", "team", "alice")
+	if _, err := os.Stat(filepath.Join(dir, ManifestFileName)); err != nil {
+		t.Errorf("manifest not saved below the subpath: %v", err)
+	}
+	if got, want := m.CloudPath(storagePath, "app", "config.json"), filepath.Join(dir, "app", "config.json"); got != want {
+		t.Errorf("CloudPath() = %q, want %q", got, want)
+	}
+
+	if Exists(storagePath, "") {
+		t.Error("manifest without subpath should not exist")
+	}
+
+	loaded, err := Load(storagePath, "team/alice")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if loaded.Subpath != "team/alice" || !loaded.HasEntry("app") {
+		t.Errorf("Load() = subpath %q, entries %v; want the saved manifest", loaded.Subpath, loaded.Names())
+	}
+}
+
+// TestNestedTrees tests finding storage subpaths below a dotsync folder
+func TestNestedTrees(t *testing.T) {
+	storagePath := t.TempDir()
+	for _, subpath := range []string{"", "alice", "team/bob", "team/bob/deeper"} {
+		m := New()
+		m.Subpath = subpath
+		if err := m.Save(storagePath); err != nil {
+			t.Fatalf("Save(%q) failed: %v", subpath, err)
+		}
+	}
+	// Entry folders and dotsync's own folders aren't subpaths
+	os.MkdirAll(filepath.Join(storagePath, "dotsync", "app"), 0755)
+	os.MkdirAll(filepath.Join(storagePath, "dotsync", ".backups", "x"), 0755)
+	os.WriteFile(filepath.Join(storagePath, "dotsync", ".backups", "x", ManifestFileName), []byte("{}"), 0644)
+
+	got, err := NestedTrees(DotsyncDir(storagePath, ""))
+	if err != nil {
+		t.Fatalf("NestedTrees() failed: %v", err)
+	}
+	want := []string{"alice", "team/bob"}
+	if !slices.Equal(got, want) {
+		t.Errorf("NestedTrees() = %v, want %v", got, want)
+	}
+
+	if got, err := NestedTrees(filepath.Join(storagePath, "missing")); err != nil || len(got) != 0 {
+		t.Errorf("NestedTrees(missing) = %v, %v, want none", got, err)
+	}
+}
+
+// TestCheckSubpath tests refusing a subpath that shares an entry's folder
+func TestCheckSubpath(t *testing.T) {
+	storagePath := t.TempDir()
+	m := New()
+	m.AddFile("alice", "~/.config/alice", "config.json")
+	m.Entries["nvim"] = Entry{Root: "~/.config/nvim", Storage: "editors/nvim", Files: []string{"init.lua"}}
+	if err := m.Save(storagePath); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	tests := []struct {
+		subpath string
+		wantErr bool
+	}{
+		{"", false},
+		{"bob", false},
+		{"alice", true},
+		{"alice/laptop", true},
+		{"editors", true},
+		{"editors/vim", false},
+	}
+	for _, tt := range tests {
+		if err := CheckSubpath(storagePath, tt.subpath); (err != nil) != tt.wantErr {
+			t.Errorf("CheckSubpath(%q) error = %v, wantErr %v", tt.subpath, err, tt.wantErr)
+		}
+	}
+}
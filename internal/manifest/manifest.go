@@ -2,29 +2,174 @@
 // The manifest tracks all entries (apps/tools) and their associated files.
 package manifest
 
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // CurrentVersion is the current manifest schema version.
 const CurrentVersion = 1
 
+// CaseInsensitive reports whether paths differing only in case name the
+// same file, as on the default macOS and Windows filesystems. It's a
+// variable so tests can exercise both behaviors.
+var CaseInsensitive = runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+
+// SamePath reports whether two paths name the same file, ignoring case
+// when CaseInsensitive is set.
+func SamePath(a, b string) bool {
+	if CaseInsensitive {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// pathKey returns the form of p used to detect duplicates.
+func pathKey(p string) string {
+	if CaseInsensitive {
+		return strings.ToLower(p)
+	}
+	return p
+}
+
+// UserHomeDir returns the home directory roots are contracted against.
+// It's a variable so pathutil.SetHome can override it.
+var UserHomeDir = os.UserHomeDir
+
 // Manifest represents the dotsync manifest file stored in cloud storage.
 // Location: <cloud-folder>/dotsync/.dotsync.json
 type Manifest struct {
+	// Comments is a note for people editing the manifest by hand. It is
+	// kept as written (any JSON value) and never read by dotsync
+	// e.g., ["Shared by laptop and desktop", "Ask before adding secrets"]
+	Comments json.RawMessage `json:"_comments,omitempty"`
+
 	// Version is the schema version for forward compatibility
 	Version int `json:"version"`
 
 	// Entries maps entry names to their configuration
 	// Key is the entry name (e.g., "opencode", "zsh", "cursor")
 	Entries map[string]Entry `json:"entries"`
+
+	// Ignore lists glob patterns of paths that must never be tracked
+	// e.g., ["~/.ssh/id_*", "*.key"]
+	Ignore []string `json:"ignore,omitempty"`
+
+	// Extra holds fields this version doesn't know (e.g. written by a newer
+	// dotsync), so saving the manifest doesn't drop them
+	Extra map[string]json.RawMessage `json:"-"`
+
+	// Subpath is the storage subpath the manifest is kept under (see
+	// DotsyncDir), with forward slashes. It comes from the config, not the
+	// file: Load sets it, and manifests created with New must set it
+	// before saving.
+	Subpath string `json:"-"`
 }
 
 // Entry represents a tracked application/tool configuration.
 type Entry struct {
+	// Comment is a note on the entry for people editing the manifest by
+	// hand, kept as written like Manifest.Comments
+	// e.g., "Only used on the work laptop"
+	Comment json.RawMessage `json:"_comment,omitempty"`
+
 	// Root is the original parent directory (uses ~ for home)
 	// e.g., "~/.config/opencode" or "~"
 	Root string `json:"root"`
 
-	// Files are relative paths from Root
+	// Storage is the entry's folder inside <cloud-folder>/dotsync, with
+	// forward slashes. Load and AddFile set it to the entry name when
+	// missing, so cloud paths don't depend on the name, and refuses folders
+	// outside <cloud-folder>/dotsync or starting with "."
+	// e.g., "opencode"
+	Storage string `json:"storage,omitempty"`
+
+	// Files are relative paths from Root, always with forward slashes
+	// (see ToStorageSlash) so manifests are portable across platforms
 	// e.g., ["config.json", "agents/review.md"]
 	Files []string `json:"files"`
+
+	// Description is an optional note on why the entry is tracked
+	// e.g., "work email config"
+	Description string `json:"description,omitempty"`
+
+	// Modes overrides the link mode for individual files, keyed by relative path.
+	// Files not listed use ModeSymlink.
+	// e.g., {"com.app.plist": "copy"}
+	Modes map[string]LinkMode `json:"modes,omitempty"`
+
+	// Disabled entries stay tracked but are skipped by link. Entries are
+	// enabled by default, so only disabled ones store it (see Enabled)
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Meta holds small per-entry settings as key/value pairs, so features
+	// can store data without a new field. Older versions keep keys they
+	// don't use on load/save. Core data still gets typed fields
+	// e.g., {"syncedBy": "laptop"}
+	Meta map[string]string `json:"meta,omitempty"`
+
+	// Extra holds fields this version doesn't know, see Manifest.Extra
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// LinkMode describes how a tracked file is placed at its original location.
+type LinkMode string
+
+const (
+	// ModeSymlink places a symlink pointing to cloud storage (default)
+	ModeSymlink LinkMode = "symlink"
+	// ModeCopy places a regular copy of the cloud file, for files that
+	// can't be symlinks (e.g. macOS plist files)
+	ModeCopy LinkMode = "copy"
+)
+
+// FileMode returns the link mode for a file in the entry.
+func (e Entry) FileMode(relPath string) LinkMode {
+	relPath = ToStorageSlash(relPath)
+	if mode, ok := e.Modes[relPath]; ok && mode != "" {
+		return mode
+	}
+	return ModeSymlink
+}
+
+// Enabled reports whether the entry is linked by link.
+func (e Entry) Enabled() bool {
+	return !e.Disabled
+}
+
+// StorageDir returns the entry's folder inside <cloud-folder>/dotsync, with
+// forward slashes. Entries without an explicit Storage use their name.
+func (e Entry) StorageDir(name string) string {
+	if e.Storage != "" {
+		return e.Storage
+	}
+	return name
+}
+
+// StorageRelPath returns the path of a file relative to <cloud-folder>/dotsync,
+// with the OS separator, e.g. "opencode/agents/review.md".
+func (e Entry) StorageRelPath(name, relPath string) string {
+	return filepath.Join(FromStorageSlash(e.StorageDir(name)), FromStorageSlash(relPath))
+}
+
+// CloudPath returns the path of a file's cloud copy inside dotsyncDir (see
+// DotsyncDir): <dotsyncDir>/<storage dir>/<relPath>.
+func (e Entry) CloudPath(dotsyncDir, name, relPath string) string {
+	return filepath.Join(dotsyncDir, e.StorageRelPath(name, relPath))
+}
+
+// CloudPath returns the path of the cloud copy of relPath in entry name:
+// <storagePath>/dotsync/[<Subpath>/]<storage dir>/<relPath>. Entries not in
+// the manifest yet use their name as storage folder.
+func (m *Manifest) CloudPath(storagePath, name, relPath string) string {
+	return m.Entries[name].CloudPath(m.DotsyncDir(storagePath), name, relPath)
 }
 
 // New creates a new empty manifest with the current version.
@@ -35,20 +180,59 @@ func New() *Manifest {
 	}
 }
 
+// NormalizeRoot returns the canonical form of an entry root: cleaned,
+// home-contracted (~), and using forward slashes, so the same directory
+// is always stored and compared the same way across platforms.
+// e.g., "/home/user/.config/app/", "~/.config/./app" -> "~/.config/app"
+func NormalizeRoot(root string) string {
+	if root == "" {
+		return root
+	}
+
+	if !strings.HasPrefix(root, "~") && filepath.IsAbs(root) {
+		root = filepath.Clean(root)
+		if home, err := UserHomeDir(); err == nil {
+			if root == home {
+				root = "~"
+			} else if strings.HasPrefix(root, home+string(filepath.Separator)) {
+				root = "~" + root[len(home):]
+			}
+		}
+	}
+
+	return path.Clean(ToStorageSlash(root))
+}
+
+// ToStorageSlash converts a path to the forward-slash form stored in the
+// manifest. Backslashes are converted on every platform, so a manifest
+// written on Windows still works on Linux and macOS.
+func ToStorageSlash(p string) string {
+	return strings.ReplaceAll(filepath.ToSlash(p), `\`, "/")
+}
+
+// FromStorageSlash converts a path stored in the manifest to the OS separator.
+func FromStorageSlash(p string) string {
+	return filepath.FromSlash(p)
+}
+
 // AddFile adds a file to an entry. Creates the entry if it doesn't exist.
-// Returns true if the file was added, false if it was already tracked.
+// The root is normalized with NormalizeRoot and relPath with ToStorageSlash.
+// Returns true if the file was added, false if it was already tracked
+// (ignoring case when CaseInsensitive is set).
 func (m *Manifest) AddFile(name, root, relPath string) bool {
+	relPath = ToStorageSlash(relPath)
 	entry, exists := m.Entries[name]
 	if !exists {
 		entry = Entry{
-			Root:  root,
-			Files: []string{},
+			Root:    NormalizeRoot(root),
+			Storage: name,
+			Files:   []string{},
 		}
 	}
 
 	// Check if file is already tracked
 	for _, f := range entry.Files {
-		if f == relPath {
+		if SamePath(f, relPath) {
 			return false
 		}
 	}
@@ -58,6 +242,274 @@ func (m *Manifest) AddFile(name, root, relPath string) bool {
 	return true
 }
 
+// Dedup removes duplicate files within each entry, keeping the first
+// occurrence. Duplicates only appear in hand-edited or badly merged
+// manifests, or when paths differing in case were tracked on a
+// case-insensitive filesystem. Returns the number of duplicates removed.
+func (m *Manifest) Dedup() int {
+	var removed int
+	for name, entry := range m.Entries {
+		seen := make(map[string]bool, len(entry.Files))
+		files := make([]string, 0, len(entry.Files))
+		for _, f := range entry.Files {
+			if seen[pathKey(f)] {
+				removed++
+				continue
+			}
+			seen[pathKey(f)] = true
+			files = append(files, f)
+		}
+		if len(files) != len(entry.Files) {
+			entry.Files = files
+			m.Entries[name] = entry
+		}
+	}
+	return removed
+}
+
+// RemoveFile removes a file from an entry. Removes the entry if it becomes empty.
+// Returns true if the file was removed, false if it wasn't tracked.
+func (m *Manifest) RemoveFile(name, relPath string) bool {
+	relPath = ToStorageSlash(relPath)
+	entry, exists := m.Entries[name]
+	if !exists {
+		return false
+	}
+
+	// Match like AddFile, so a file tracked under another case is removed
+	files := make([]string, 0, len(entry.Files))
+	var removed []string
+	for _, f := range entry.Files {
+		if SamePath(f, relPath) {
+			removed = append(removed, f)
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(removed) == 0 {
+		return false
+	}
+
+	if len(files) == 0 {
+		delete(m.Entries, name)
+		return true
+	}
+
+	entry.Files = files
+	if len(entry.Modes) > 0 {
+		modes := make(map[string]LinkMode, len(entry.Modes))
+		for k, v := range entry.Modes {
+			if !SamePath(k, relPath) {
+				modes[k] = v
+			}
+		}
+		if len(modes) == 0 {
+			modes = nil
+		}
+		entry.Modes = modes
+	}
+	m.Entries[name] = entry
+	for _, f := range removed {
+		m.SetParentMode(name, f, 0)
+	}
+	return true
+}
+
+// SetFileMode sets the link mode for a file in an existing entry.
+// Returns false if the entry or file doesn't exist.
+func (m *Manifest) SetFileMode(name, relPath string, mode LinkMode) bool {
+	relPath = ToStorageSlash(relPath)
+	entry, exists := m.Entries[name]
+	if !exists {
+		return false
+	}
+
+	found := false
+	for _, f := range entry.Files {
+		if f == relPath {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	// Symlink is the default, so only overrides are stored
+	if mode == ModeSymlink || mode == "" {
+		delete(entry.Modes, relPath)
+		if len(entry.Modes) == 0 {
+			entry.Modes = nil
+		}
+	} else {
+		modes := make(map[string]LinkMode, len(entry.Modes)+1)
+		for k, v := range entry.Modes {
+			modes[k] = v
+		}
+		modes[relPath] = mode
+		entry.Modes = modes
+	}
+
+	m.Entries[name] = entry
+	return true
+}
+
+// SetDescription sets the description of an existing entry. An empty
+// description removes it. Returns false if the entry doesn't exist.
+func (m *Manifest) SetDescription(name, description string) bool {
+	entry, exists := m.Entries[name]
+	if !exists {
+		return false
+	}
+	entry.Description = strings.TrimSpace(description)
+	m.Entries[name] = entry
+	return true
+}
+
+// SetEnabled enables or disables an existing entry. Returns false if the
+// entry doesn't exist.
+func (m *Manifest) SetEnabled(name string, enabled bool) bool {
+	entry, exists := m.Entries[name]
+	if !exists {
+		return false
+	}
+	entry.Disabled = !enabled
+	m.Entries[name] = entry
+	return true
+}
+
+// SetMeta sets a metadata key of an existing entry. An empty value removes
+// the key. Returns false if the entry doesn't exist.
+func (m *Manifest) SetMeta(name, key, value string) bool {
+	entry, exists := m.Entries[name]
+	if !exists {
+		return false
+	}
+	if value == "" {
+		delete(entry.Meta, key)
+		if len(entry.Meta) == 0 {
+			entry.Meta = nil
+		}
+	} else {
+		meta := make(map[string]string, len(entry.Meta)+1)
+		for k, v := range entry.Meta {
+			meta[k] = v
+		}
+		meta[key] = value
+		entry.Meta = meta
+	}
+	m.Entries[name] = entry
+	return true
+}
+
+// parentModeKey prefixes the Meta keys holding the mode of a file's parent
+// directory, e.g. "parentMode:conf.d/a.conf": "0750".
+const parentModeKey = "parentMode:"
+
+// ParentMode returns the permissions the file's parent directory had when
+// it was added, so link can recreate it the same way. Returns false if
+// none was recorded.
+func (e Entry) ParentMode(relPath string) (os.FileMode, bool) {
+	value, ok := e.Meta[parentModeKey+ToStorageSlash(relPath)]
+	if !ok {
+		return 0, false
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return 0, false
+	}
+	return os.FileMode(mode), true
+}
+
+// SetParentMode records the permissions of a file's parent directory in
+// the entry's Meta. A mode of 0 removes it. Returns false if the entry
+// doesn't exist.
+func (m *Manifest) SetParentMode(name, relPath string, mode os.FileMode) bool {
+	value := ""
+	if mode != 0 {
+		value = fmt.Sprintf("%04o", mode.Perm())
+	}
+	return m.SetMeta(name, parentModeKey+ToStorageSlash(relPath), value)
+}
+
+// FileRef identifies a tracked file by entry name and relative path.
+type FileRef struct {
+	Entry   string
+	RelPath string
+}
+
+func (f FileRef) String() string {
+	return f.Entry + "/" + f.RelPath
+}
+
+// DuplicateTarget is an original path that several tracked files resolve to.
+type DuplicateTarget struct {
+	// Path is the original path with ~ for home
+	// e.g., "~/.config/app/config.json"
+	Path  string
+	Files []FileRef
+}
+
+// DuplicateTargets returns the original paths that more than one tracked
+// file resolves to, sorted by path. dotsync add never creates these, but
+// hand-edited or merged manifests can, e.g. root "~" with file
+// ".config/app/x" and root "~/.config/app" with file "x".
+func (m *Manifest) DuplicateTargets() []DuplicateTarget {
+	byPath := make(map[string][]FileRef)
+	for name, entry := range m.Entries {
+		root := NormalizeRoot(entry.Root)
+		for _, f := range entry.Files {
+			target := path.Join(root, ToStorageSlash(f))
+			byPath[target] = append(byPath[target], FileRef{Entry: name, RelPath: f})
+		}
+	}
+
+	var dups []DuplicateTarget
+	for target, files := range byPath {
+		if len(files) < 2 {
+			continue
+		}
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].String() < files[j].String()
+		})
+		dups = append(dups, DuplicateTarget{Path: target, Files: files})
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		return dups[i].Path < dups[j].Path
+	})
+	return dups
+}
+
+// Validate checks the manifest for inconsistencies dotsync can't resolve
+// on its own. Currently this is tracked files sharing an original path.
+func (m *Manifest) Validate() error {
+	dups := m.DuplicateTargets()
+	if len(dups) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(dups))
+	for _, d := range dups {
+		refs := make([]string, 0, len(d.Files))
+		for _, f := range d.Files {
+			refs = append(refs, f.String())
+		}
+		lines = append(lines, fmt.Sprintf("  %s is tracked as %s", d.Path, strings.Join(refs, ", ")))
+	}
+	return fmt.Errorf("several tracked files map to the same path:\n%s\nRemove all but one from the manifest", strings.Join(lines, "\n"))
+}
+
+// Names returns the entry names sorted, so commands walk entries in the
+// same order on every run.
+func (m *Manifest) Names() []string {
+	names := make([]string, 0, len(m.Entries))
+	for name := range m.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // HasEntry returns true if an entry with the given name exists.
 func (m *Manifest) HasEntry(name string) bool {
 	_, exists := m.Entries[name]
@@ -1,6 +1,10 @@
 package manifest
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -39,6 +43,10 @@ func TestAddFile_NewEntry(t *testing.T) {
 		t.Errorf("Root = %q, want %q", entry.Root, "~/.config/opencode")
 	}
 
+	if entry.Storage != "opencode" {
+		t.Errorf("Storage = %q, want %q", entry.Storage, "opencode")
+	}
+
 	if len(entry.Files) != 1 {
 		t.Fatalf("expected 1 file, got %d", len(entry.Files))
 	}
@@ -48,6 +56,34 @@ func TestAddFile_NewEntry(t *testing.T) {
 	}
 }
 
+// TestCloudPath tests resolving cloud paths through the entry's storage folder
+func TestCloudPath(t *testing.T) {
+	m := New()
+	m.Entries["nvim"] = Entry{Root: "~/.config/nvim", Storage: "editors/nvim", Files: []string{"lua/plugins.lua"}}
+	m.Entries["zsh"] = Entry{Root: "~", Files: []string{".zshrc"}}
+
+	storagePath := filepath.Join("cloud", "storage")
+	tests := []struct {
+		name    string
+		relPath string
+		want    string
+	}{
+		{"nvim", "lua/plugins.lua", filepath.Join(storagePath, "dotsync", "editors", "nvim", "lua", "plugins.lua")},
+		{"zsh", ".zshrc", filepath.Join(storagePath, "dotsync", "zsh", ".zshrc")},
+		{"new", "config.json", filepath.Join(storagePath, "dotsync", "new", "config.json")},
+	}
+
+	for _, tt := range tests {
+		if got := m.CloudPath(storagePath, tt.name, tt.relPath); got != tt.want {
+			t.Errorf("CloudPath(%q, %q) = %q, want %q", tt.name, tt.relPath, got, tt.want)
+		}
+	}
+
+	if got, want := m.Entries["nvim"].StorageRelPath("nvim", "init.lua"), filepath.Join("editors", "nvim", "init.lua"); got != want {
+		t.Errorf("StorageRelPath() = %q, want %q", got, want)
+	}
+}
+
 // TestAddFile_ExistingEntry tests adding a file to an existing entry
 func TestAddFile_ExistingEntry(t *testing.T) {
 	m := New()
@@ -95,6 +131,94 @@ func TestAddFile_Duplicate(t *testing.T) {
 	}
 }
 
+// useCaseInsensitive sets CaseInsensitive for the rest of the test.
+func useCaseInsensitive(t *testing.T, v bool) {
+	t.Helper()
+	orig := CaseInsensitive
+	CaseInsensitive = v
+	t.Cleanup(func() { CaseInsensitive = orig })
+}
+
+// TestAddFile_CaseCollision tests that a path differing only in case is
+// the same file on case-insensitive filesystems only
+func TestAddFile_CaseCollision(t *testing.T) {
+	tests := []struct {
+		caseInsensitive bool
+		wantFiles       int
+	}{
+		{caseInsensitive: true, wantFiles: 1},
+		{caseInsensitive: false, wantFiles: 2},
+	}
+
+	for _, tt := range tests {
+		useCaseInsensitive(t, tt.caseInsensitive)
+
+		m := New()
+		m.AddFile("opencode", "~/.config/opencode", "config.json")
+		added := m.AddFile("opencode", "~/.config/opencode", "Config.json")
+
+		if added != (tt.wantFiles == 2) {
+			t.Errorf("caseInsensitive=%v: AddFile() = %v", tt.caseInsensitive, added)
+		}
+		if got := len(m.GetEntry("opencode").Files); got != tt.wantFiles {
+			t.Errorf("caseInsensitive=%v: got %d files, want %d", tt.caseInsensitive, got, tt.wantFiles)
+		}
+	}
+}
+
+// TestRemoveFile_CaseCollision tests that a path differing only in case
+// removes the tracked file on case-insensitive filesystems only
+func TestRemoveFile_CaseCollision(t *testing.T) {
+	tests := []struct {
+		caseInsensitive bool
+		wantRemoved     bool
+	}{
+		{caseInsensitive: true, wantRemoved: true},
+		{caseInsensitive: false, wantRemoved: false},
+	}
+
+	for _, tt := range tests {
+		useCaseInsensitive(t, tt.caseInsensitive)
+
+		m := New()
+		m.AddFile("opencode", "~/.config/opencode", "config.json")
+		m.AddFile("opencode", "~/.config/opencode", "Plugin.plist")
+		m.SetFileMode("opencode", "Plugin.plist", ModeCopy)
+		m.SetParentMode("opencode", "Plugin.plist", 0700)
+
+		if got := m.RemoveFile("opencode", "plugin.plist"); got != tt.wantRemoved {
+			t.Errorf("caseInsensitive=%v: RemoveFile() = %v, want %v", tt.caseInsensitive, got, tt.wantRemoved)
+		}
+		entry := m.GetEntry("opencode")
+		if tt.wantRemoved {
+			if len(entry.Files) != 1 || entry.Modes != nil {
+				t.Errorf("caseInsensitive=%v: Files = %v, Modes = %v, want only config.json", tt.caseInsensitive, entry.Files, entry.Modes)
+			}
+			if _, ok := entry.ParentMode("Plugin.plist"); ok {
+				t.Errorf("caseInsensitive=%v: parent mode of removed file kept", tt.caseInsensitive)
+			}
+		} else if len(entry.Files) != 2 {
+			t.Errorf("caseInsensitive=%v: Files = %v, want both kept", tt.caseInsensitive, entry.Files)
+		}
+	}
+}
+
+// TestDedup_CaseCollision tests collapsing files tracked under different case
+func TestDedup_CaseCollision(t *testing.T) {
+	useCaseInsensitive(t, true)
+
+	m := New()
+	m.Entries["opencode"] = Entry{Root: "~/.config/opencode", Files: []string{"config.json", "Config.json", "agents/a.md"}}
+
+	if removed := m.Dedup(); removed != 1 {
+		t.Errorf("Dedup() = %d, want 1", removed)
+	}
+	files := m.GetEntry("opencode").Files
+	if len(files) != 2 || files[0] != "config.json" {
+		t.Errorf("Files = %v, want the first spelling kept", files)
+	}
+}
+
 // TestAddFile_MultipleEntries tests adding files to multiple entries
 func TestAddFile_MultipleEntries(t *testing.T) {
 	m := New()
@@ -135,6 +259,326 @@ func TestAddFile_MultipleEntries(t *testing.T) {
 	}
 }
 
+// TestRemoveFile tests removing files and emptied entries
+func TestRemoveFile(t *testing.T) {
+	m := New()
+	m.AddFile("opencode", "~/.config/opencode", "config.json")
+	m.AddFile("opencode", "~/.config/opencode", "agents/review.md")
+	m.SetFileMode("opencode", "agents/review.md", ModeCopy)
+
+	if !m.RemoveFile("opencode", "agents/review.md") {
+		t.Fatal("RemoveFile() returned false, expected true")
+	}
+
+	entry := m.GetEntry("opencode")
+	if entry == nil {
+		t.Fatal("entry removed while it still has files")
+	}
+	if len(entry.Files) != 1 || entry.Files[0] != "config.json" {
+		t.Errorf("Files = %v, want [config.json]", entry.Files)
+	}
+	if entry.Modes != nil {
+		t.Errorf("Modes = %v, want nil", entry.Modes)
+	}
+
+	if m.RemoveFile("opencode", "missing.json") {
+		t.Error("RemoveFile() on untracked file returned true")
+	}
+	if m.RemoveFile("missing", "config.json") {
+		t.Error("RemoveFile() on missing entry returned true")
+	}
+
+	// Removing the last file removes the entry
+	m.RemoveFile("opencode", "config.json")
+	if m.HasEntry("opencode") {
+		t.Error("empty entry should be removed")
+	}
+}
+
+// TestSetFileMode tests per-file link mode overrides
+func TestSetFileMode(t *testing.T) {
+	m := New()
+	m.AddFile("app", "~/Library/Preferences", "com.app.plist")
+	m.AddFile("app", "~/Library/Preferences", "other.json")
+
+	if !m.SetFileMode("app", "com.app.plist", ModeCopy) {
+		t.Fatal("SetFileMode() returned false, expected true")
+	}
+
+	entry := m.GetEntry("app")
+	if got := entry.FileMode("com.app.plist"); got != ModeCopy {
+		t.Errorf("FileMode(com.app.plist) = %q, want %q", got, ModeCopy)
+	}
+	if got := entry.FileMode("other.json"); got != ModeSymlink {
+		t.Errorf("FileMode(other.json) = %q, want %q", got, ModeSymlink)
+	}
+
+	// Setting back to symlink removes the override
+	m.SetFileMode("app", "com.app.plist", ModeSymlink)
+	entry = m.GetEntry("app")
+	if entry.Modes != nil {
+		t.Errorf("Modes = %v, want nil after resetting to default", entry.Modes)
+	}
+}
+
+// TestSetFileMode_Unknown tests that modes can't be set for untracked files
+func TestSetFileMode_Unknown(t *testing.T) {
+	m := New()
+	m.AddFile("app", "~/.config/app", "config.json")
+
+	if m.SetFileMode("missing", "config.json", ModeCopy) {
+		t.Error("SetFileMode() on missing entry returned true")
+	}
+	if m.SetFileMode("app", "missing.json", ModeCopy) {
+		t.Error("SetFileMode() on missing file returned true")
+	}
+}
+
+// TestSetDescription tests setting, trimming and clearing descriptions
+func TestSetDescription(t *testing.T) {
+	m := New()
+	m.AddFile("app", "~/.config/app", "config.json")
+
+	if !m.SetDescription("app", "  work email config ") {
+		t.Fatal("SetDescription() returned false for existing entry")
+	}
+	if got := m.GetEntry("app").Description; got != "work email config" {
+		t.Errorf("Description = %q, want %q", got, "work email config")
+	}
+
+	m.SetDescription("app", "")
+	if got := m.GetEntry("app").Description; got != "" {
+		t.Errorf("Description = %q, want empty", got)
+	}
+
+	if m.SetDescription("missing", "x") {
+		t.Error("SetDescription() on missing entry returned true")
+	}
+}
+
+// TestSetEnabled tests disabling and re-enabling an entry
+func TestSetEnabled(t *testing.T) {
+	m := New()
+	m.AddFile("app", "~/.config/app", "config.json")
+
+	if !m.GetEntry("app").Enabled() {
+		t.Fatal("new entry is disabled, want enabled")
+	}
+	if !m.SetEnabled("app", false) {
+		t.Fatal("SetEnabled() returned false for existing entry")
+	}
+	if m.GetEntry("app").Enabled() {
+		t.Error("Enabled() = true after disabling")
+	}
+
+	data, err := json.Marshal(m.Entries["app"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"disabled":true`) {
+		t.Errorf("disabled entry JSON = %s, want \"disabled\":true", data)
+	}
+
+	m.SetEnabled("app", true)
+	data, _ = json.Marshal(m.Entries["app"])
+	if strings.Contains(string(data), "disabled") {
+		t.Errorf("enabled entry JSON = %s, want no disabled field", data)
+	}
+
+	if m.SetEnabled("missing", false) {
+		t.Error("SetEnabled() on missing entry returned true")
+	}
+}
+
+// TestSetMeta tests setting, overwriting and removing metadata keys
+func TestSetMeta(t *testing.T) {
+	m := New()
+	m.AddFile("app", "~/.config/app", "config.json")
+
+	if !m.SetMeta("app", "syncedBy", "laptop") {
+		t.Fatal("SetMeta() returned false for existing entry")
+	}
+	before := *m.GetEntry("app")
+	m.SetMeta("app", "syncedBy", "desktop")
+	m.SetMeta("app", "owner", "alice")
+
+	if got := m.GetEntry("app").Meta; got["syncedBy"] != "desktop" || got["owner"] != "alice" {
+		t.Errorf("Meta = %v, want syncedBy=desktop owner=alice", got)
+	}
+	if got := before.Meta["syncedBy"]; got != "laptop" {
+		t.Errorf("earlier copy of the entry changed: syncedBy = %q, want laptop", got)
+	}
+
+	m.SetMeta("app", "syncedBy", "")
+	m.SetMeta("app", "owner", "")
+	if got := m.GetEntry("app").Meta; got != nil {
+		t.Errorf("Meta = %v, want nil after removing every key", got)
+	}
+	data, _ := json.Marshal(m.Entries["app"])
+	if strings.Contains(string(data), "meta") {
+		t.Errorf("entry JSON = %s, want no meta field", data)
+	}
+
+	if m.SetMeta("missing", "k", "v") {
+		t.Error("SetMeta() on missing entry returned true")
+	}
+}
+
+// TestParentMode tests recording parent directory modes in Meta
+func TestParentMode(t *testing.T) {
+	m := New()
+	m.AddFile("app", "~/.config/app", `conf.d\a.conf`)
+	m.AddFile("app", "~/.config/app", "b.conf")
+
+	if _, ok := m.GetEntry("app").ParentMode("conf.d/a.conf"); ok {
+		t.Error("ParentMode() found a mode before one was recorded")
+	}
+	m.SetParentMode("app", `conf.d\a.conf`, 0750)
+	if got := m.GetEntry("app").Meta["parentMode:conf.d/a.conf"]; got != "0750" {
+		t.Errorf("Meta value = %q, want 0750", got)
+	}
+	if mode, ok := m.GetEntry("app").ParentMode("conf.d/a.conf"); !ok || mode != 0750 {
+		t.Errorf("ParentMode() = %04o, %v, want 0750, true", mode, ok)
+	}
+
+	// Hand-edited garbage is ignored
+	m.SetMeta("app", "parentMode:b.conf", "rwx")
+	if _, ok := m.GetEntry("app").ParentMode("b.conf"); ok {
+		t.Error("ParentMode() accepted an invalid value")
+	}
+
+	// Removing the file drops its mode
+	m.RemoveFile("app", "conf.d/a.conf")
+	if _, ok := m.GetEntry("app").Meta["parentMode:conf.d/a.conf"]; ok {
+		t.Error("parent mode kept after RemoveFile()")
+	}
+}
+
+// TestDuplicateTargets tests detecting files that map to the same path
+func TestDuplicateTargets(t *testing.T) {
+	m := New()
+	m.AddFile("home", "~", ".config/app/config.json")
+	m.AddFile("app", "~/.config/app", "config.json")
+	m.AddFile("app", "~/.config/app", "other.json")
+	// Hand-edited entry whose root only differs in form
+	m.Entries["copy"] = Entry{Root: "~/.config/./app/", Files: []string{"other.json"}}
+	m.AddFile("zsh", "~", ".zshrc")
+
+	dups := m.DuplicateTargets()
+	want := []DuplicateTarget{
+		{Path: "~/.config/app/config.json", Files: []FileRef{{"app", "config.json"}, {"home", ".config/app/config.json"}}},
+		{Path: "~/.config/app/other.json", Files: []FileRef{{"app", "other.json"}, {"copy", "other.json"}}},
+	}
+	if len(dups) != len(want) {
+		t.Fatalf("DuplicateTargets() = %v, want %v", dups, want)
+	}
+	for i := range want {
+		if dups[i].Path != want[i].Path {
+			t.Errorf("dups[%d].Path = %q, want %q", i, dups[i].Path, want[i].Path)
+		}
+		if len(dups[i].Files) != len(want[i].Files) {
+			t.Errorf("dups[%d].Files = %v, want %v", i, dups[i].Files, want[i].Files)
+			continue
+		}
+		for j := range want[i].Files {
+			if dups[i].Files[j] != want[i].Files[j] {
+				t.Errorf("dups[%d].Files[%d] = %v, want %v", i, j, dups[i].Files[j], want[i].Files[j])
+			}
+		}
+	}
+
+	err := m.Validate()
+	if err == nil {
+		t.Fatal("Validate() should fail with duplicate targets")
+	}
+	if !strings.Contains(err.Error(), "~/.config/app/config.json is tracked as app/config.json, home/.config/app/config.json") {
+		t.Errorf("Validate() error = %q, want both files listed", err)
+	}
+}
+
+// TestValidate_OK tests that distinct paths pass validation
+func TestValidate_OK(t *testing.T) {
+	m := New()
+	m.AddFile("app", "~/.config/app", "config.json")
+	m.AddFile("zsh", "~", ".zshrc")
+
+	if err := m.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
+
+// TestNormalizeRoot tests that equivalent root forms normalize identically
+func TestNormalizeRoot(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("failed to get home dir: %v", err)
+	}
+
+	inputs := []string{
+		"~/.config/opencode",
+		"~/.config/opencode/",
+		"~/.config/./opencode",
+		filepath.Join("~", ".config", "opencode"),
+		filepath.Join(home, ".config", "opencode"),
+		filepath.Join(home, ".config", "opencode") + string(filepath.Separator),
+	}
+
+	for _, input := range inputs {
+		if got := NormalizeRoot(input); got != "~/.config/opencode" {
+			t.Errorf("NormalizeRoot(%q) = %q, want %q", input, got, "~/.config/opencode")
+		}
+	}
+
+	if got := NormalizeRoot(home); got != "~" {
+		t.Errorf("NormalizeRoot(home) = %q, want %q", got, "~")
+	}
+	if got := NormalizeRoot("~"); got != "~" {
+		t.Errorf("NormalizeRoot(~) = %q, want %q", got, "~")
+	}
+	if got := NormalizeRoot(""); got != "" {
+		t.Errorf("NormalizeRoot(\"\") = %q, want empty", got)
+	}
+}
+
+// TestAddFile_NormalizesRoot tests that mixed root forms map to one entry root
+func TestAddFile_NormalizesRoot(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("failed to get home dir: %v", err)
+	}
+
+	roots := []string{
+		"~/.config/opencode/",
+		filepath.Join(home, ".config", "opencode"),
+		filepath.Join("~", ".config", "opencode"),
+	}
+	for i, root := range roots {
+		m := New()
+		m.AddFile("opencode", root, "config.json")
+		if got := m.GetEntry("opencode").Root; got != "~/.config/opencode" {
+			t.Errorf("roots[%d]: Root = %q, want %q", i, got, "~/.config/opencode")
+		}
+	}
+}
+
+// TestNames tests that entry names come back sorted
+func TestNames(t *testing.T) {
+	m := New()
+	for _, name := range []string{"zsh", "alacritty", "opencode", "git"} {
+		m.AddFile(name, "~/.config/"+name, "config")
+	}
+
+	got := m.Names()
+	want := []string{"alacritty", "git", "opencode", "zsh"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	if got := New().Names(); len(got) != 0 {
+		t.Errorf("Names() on empty manifest = %v, want empty", got)
+	}
+}
+
 // TestHasEntry tests entry existence checking
 func TestHasEntry(t *testing.T) {
 	m := New()
@@ -212,3 +656,26 @@ func TestManifest_EmptyEntries(t *testing.T) {
 		t.Error("HasEntry on empty manifest returned true")
 	}
 }
+
+// TestAddFile_NormalizesSeparators tests that relpaths are stored with forward slashes
+func TestAddFile_NormalizesSeparators(t *testing.T) {
+	m := New()
+	m.AddFile("opencode", "~/.config/opencode", `agents\review.md`)
+	m.AddFile("opencode", "~/.config/opencode", filepath.Join("agents", "other.md"))
+
+	files := m.GetEntry("opencode").Files
+	if files[0] != "agents/review.md" || files[1] != "agents/other.md" {
+		t.Errorf("Files = %v, want [agents/review.md agents/other.md]", files)
+	}
+
+	// Both separators refer to the same tracked file
+	if m.AddFile("opencode", "~/.config/opencode", "agents/review.md") {
+		t.Error("AddFile() added the same file with a different separator")
+	}
+	if !m.SetFileMode("opencode", `agents\review.md`, ModeCopy) {
+		t.Error("SetFileMode() didn't find the file with backslashes")
+	}
+	if !m.RemoveFile("opencode", `agents\review.md`) {
+		t.Error("RemoveFile() didn't find the file with backslashes")
+	}
+}
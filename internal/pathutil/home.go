@@ -0,0 +1,27 @@
+package pathutil
+
+import (
+	"os"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+// homeOverride replaces the user's home directory when set.
+var homeOverride string
+
+// SetHome makes HomeDir return dir instead of the user's home directory,
+// e.g. for the --home flag in containers, or in tests. An empty dir
+// restores the default.
+func SetHome(dir string) {
+	homeOverride = dir
+	manifest.UserHomeDir = HomeDir
+}
+
+// HomeDir returns the home directory dotsync works in: the SetHome
+// override if set, otherwise the user's home directory.
+func HomeDir() (string, error) {
+	if homeOverride != "" {
+		return homeOverride, nil
+	}
+	return os.UserHomeDir()
+}
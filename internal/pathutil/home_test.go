@@ -0,0 +1,38 @@
+package pathutil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+// TestSetHome tests that path helpers and manifest roots use the override
+func TestSetHome(t *testing.T) {
+	home := useTempHome(t)
+
+	if got, err := HomeDir(); err != nil || got != home {
+		t.Errorf("HomeDir() = %q, %v, want %q", got, err, home)
+	}
+
+	absPath := filepath.Join(home, ".config", "app", "config.json")
+	if got := ExpandHome("~/.config/app/config.json"); got != absPath {
+		t.Errorf("ExpandHome() = %q, want %q", got, absPath)
+	}
+	if got := ContractHome(absPath); got != filepath.Join("~", ".config", "app", "config.json") {
+		t.Errorf("ContractHome() = %q, want ~ path", got)
+	}
+	if got := manifest.NormalizeRoot(filepath.Join(home, ".config", "app")); got != "~/.config/app" {
+		t.Errorf("NormalizeRoot() = %q, want %q", got, "~/.config/app")
+	}
+
+	result := InferFromPath(absPath)
+	if result == nil || result.Name != "app" {
+		t.Errorf("InferFromPath() = %+v, want entry 'app'", result)
+	}
+
+	SetHome("")
+	if got := ExpandHome("~"); got == home {
+		t.Error("SetHome(\"\") should restore the default home")
+	}
+}
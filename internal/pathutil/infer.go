@@ -2,6 +2,7 @@
 package pathutil
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -16,12 +17,39 @@ type InferResult struct {
 	Root string
 	// RelPath is the relative path from Root to the file
 	RelPath string
+	// Pattern describes the layout that matched (e.g., "~/.config/<name>/*")
+	Pattern string
+	// Container is the container directory Root was split from (e.g.,
+	// "~/.config/systemd"), empty unless the path is below one of
+	// ContainerConfigDirs
+	Container string
+}
+
+// ContainerConfigDirs lists config directories whose children are separate
+// things worth tracking on their own. Files below them are inferred one
+// level deeper, e.g. ~/.config/systemd/user/foo.service becomes entry
+// "systemd-user" rooted at ~/.config/systemd/user.
+var ContainerConfigDirs = []string{"systemd"}
+
+// configEntry splits the parts of a path relative to a config directory
+// into an entry name and the number of leading parts forming its root.
+// Returns depth 0 if the parts don't reach a file inside an entry.
+func configEntry(parts []string) (name string, depth int) {
+	for _, dir := range ContainerConfigDirs {
+		if parts[0] == dir && len(parts) >= 3 {
+			return parts[0] + "-" + parts[1], 2
+		}
+	}
+	if len(parts) >= 2 {
+		return parts[0], 1
+	}
+	return "", 0
 }
 
 // InferFromPath attempts to infer entry name and root from a file path.
 // Returns nil if the path doesn't match any known pattern.
 func InferFromPath(absPath string) *InferResult {
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
 		return nil
 	}
@@ -29,33 +57,36 @@ func InferFromPath(absPath string) *InferResult {
 	// Normalize the path
 	absPath = filepath.Clean(absPath)
 
-	// Check if path is under home directory
-	if !strings.HasPrefix(absPath, home) {
-		return nil
+	// Pattern 0: $XDG_CONFIG_HOME/<name>/* when XDG_CONFIG_HOME is customized
+	if result := inferFromXDGConfig(absPath, home); result != nil {
+		return result
 	}
 
-	// Get path relative to home
-	relToHome, err := filepath.Rel(home, absPath)
+	// Get path relative to home, if it's under home
+	relToHome, err := SafeRel(home, absPath)
 	if err != nil {
 		return nil
 	}
 
 	// Split into parts
-	parts := strings.Split(relToHome, string(filepath.Separator))
-	if len(parts) == 0 {
-		return nil
-	}
+	parts := strings.Split(relToHome, "/")
 
-	// Pattern 1: ~/.config/<name>/*
+	// Pattern 1: ~/.config/<name>/* (or ~/.config/<container>/<name>/*)
 	if parts[0] == ".config" && len(parts) >= 3 {
-		name := parts[1]
-		root := filepath.Join(home, ".config", name)
-		relPath := filepath.Join(parts[2:]...)
-		return &InferResult{
+		name, depth := configEntry(parts[1:])
+		root := filepath.Join(append([]string{home, ".config"}, parts[1:1+depth]...)...)
+		relPath := filepath.Join(parts[1+depth:]...)
+		result := &InferResult{
 			Name:    name,
 			Root:    contractHome(root, home),
 			RelPath: relPath,
+			Pattern: "~/.config/<name>/*",
 		}
+		if depth == 2 {
+			result.Pattern = "~/.config/<container>/<name>/*"
+			result.Container = contractHome(filepath.Dir(root), home)
+		}
+		return result
 	}
 
 	// Pattern 2: ~/Library/Application Support/<name>/* (macOS)
@@ -67,6 +98,7 @@ func InferFromPath(absPath string) *InferResult {
 			Name:    name,
 			Root:    contractHome(root, home),
 			RelPath: relPath,
+			Pattern: "~/Library/Application Support/<name>/*",
 		}
 	}
 
@@ -79,6 +111,7 @@ func InferFromPath(absPath string) *InferResult {
 			Name:    name,
 			Root:    contractHome(root, home),
 			RelPath: relPath,
+			Pattern: "~/.<name>/*",
 		}
 	}
 
@@ -95,12 +128,53 @@ func InferFromPath(absPath string) *InferResult {
 			Name:    name,
 			Root:    "~",
 			RelPath: parts[0],
+			Pattern: "~/.<name>",
 		}
 	}
 
 	return nil
 }
 
+// inferFromXDGConfig infers an entry for files under a custom XDG_CONFIG_HOME,
+// e.g. ~/.dotfiles/config/nvim/init.lua -> "nvim" with root ~/.dotfiles/config/nvim.
+// Returns nil if XDG_CONFIG_HOME is unset, relative, or doesn't contain absPath.
+func inferFromXDGConfig(absPath, home string) *InferResult {
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" || !filepath.IsAbs(xdg) {
+		return nil
+	}
+	xdg = filepath.Clean(xdg)
+
+	// The default location is handled by the ~/.config pattern
+	if xdg == filepath.Join(home, ".config") {
+		return nil
+	}
+
+	relToXDG, err := SafeRel(xdg, absPath)
+	if err != nil {
+		return nil
+	}
+
+	parts := strings.Split(relToXDG, "/")
+	name, depth := configEntry(parts)
+	if depth == 0 {
+		return nil
+	}
+
+	root := filepath.Join(append([]string{xdg}, parts[:depth]...)...)
+	result := &InferResult{
+		Name:    name,
+		Root:    contractHome(root, home),
+		RelPath: filepath.Join(parts[depth:]...),
+		Pattern: "$XDG_CONFIG_HOME/<name>/*",
+	}
+	if depth == 2 {
+		result.Pattern = "$XDG_CONFIG_HOME/<container>/<name>/*"
+		result.Container = contractHome(filepath.Dir(root), home)
+	}
+	return result
+}
+
 // contractHome replaces the home directory with ~ in a path.
 func contractHome(path, home string) string {
 	if path == home {
@@ -118,7 +192,7 @@ func ExpandHome(path string) string {
 		return path
 	}
 
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
 		return path
 	}
@@ -136,7 +210,7 @@ func ExpandHome(path string) string {
 
 // ContractHome replaces the home directory with ~ in a path.
 func ContractHome(path string) string {
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
 		return path
 	}
@@ -145,7 +219,7 @@ func ContractHome(path string) string {
 
 // IsUnderHome checks if a path is under the user's home directory.
 func IsUnderHome(absPath string) bool {
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
 		return false
 	}
@@ -157,3 +231,35 @@ func AbsolutePath(path string) (string, error) {
 	expanded := ExpandHome(path)
 	return filepath.Abs(expanded)
 }
+
+// CleanInput normalizes a path as typed by the user: repeated separators,
+// "." and ".." segments and trailing separators are removed, so
+// "~/.config/app//config.json" and "~/.config/app/./config.json/" both
+// become "~/.config/app/config.json". Paths starting with ~ keep it, and
+// ".." after ~ is resolved against the real home directory.
+func CleanInput(path string) string {
+	if path == "" {
+		return path
+	}
+	cleaned := filepath.Clean(ExpandHome(path))
+	if strings.HasPrefix(path, "~") && filepath.IsAbs(cleaned) {
+		return ContractHome(cleaned)
+	}
+	return cleaned
+}
+
+// SafeRel returns the path of absPath relative to root, with forward
+// slashes as stored in the manifest. Fails if the relative path can't be
+// computed (e.g. different volumes on Windows) or if absPath isn't inside
+// root, so the result never escapes root. root itself isn't inside root.
+func SafeRel(root, absPath string) (string, error) {
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil {
+		return "", fmt.Errorf("computing path relative to %s: %w", root, err)
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s is not under %s", absPath, root)
+	}
+	return rel, nil
+}
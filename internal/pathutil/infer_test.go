@@ -1,18 +1,24 @@
 package pathutil
 
 import (
-	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
 )
 
+// useTempHome points HomeDir at a temporary directory for the rest of the
+// test, so results don't depend on the real home.
+func useTempHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	SetHome(home)
+	t.Cleanup(func() { SetHome("") })
+	return home
+}
+
 // TestInferFromPath_XDGConfig tests inference for ~/.config/<name>/* pattern
 func TestInferFromPath_XDGConfig(t *testing.T) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Fatalf("failed to get home dir: %v", err)
-	}
+	home := useTempHome(t)
 
 	tests := []struct {
 		name     string
@@ -42,6 +48,96 @@ func TestInferFromPath_XDGConfig(t *testing.T) {
 			wantRoot: filepath.Join("~", ".config", "Code"),
 			wantRel:  filepath.Join("User", "settings.json"),
 		},
+		{
+			name:     "systemd user unit is named after its container child",
+			path:     filepath.Join(home, ".config", "systemd", "user", "foo.service"),
+			wantName: "systemd-user",
+			wantRoot: filepath.Join("~", ".config", "systemd", "user"),
+			wantRel:  "foo.service",
+		},
+		{
+			name:     "nested file below a container child",
+			path:     filepath.Join(home, ".config", "systemd", "user", "default.target.wants", "foo.service"),
+			wantName: "systemd-user",
+			wantRoot: filepath.Join("~", ".config", "systemd", "user"),
+			wantRel:  filepath.Join("default.target.wants", "foo.service"),
+		},
+		{
+			name:     "file directly in a container dir",
+			path:     filepath.Join(home, ".config", "systemd", "journald.conf"),
+			wantName: "systemd",
+			wantRoot: filepath.Join("~", ".config", "systemd"),
+			wantRel:  "journald.conf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := InferFromPath(tt.path)
+			if result == nil {
+				t.Fatal("expected non-nil result")
+			}
+			if result.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", result.Name, tt.wantName)
+			}
+			if result.Root != tt.wantRoot {
+				t.Errorf("Root = %q, want %q", result.Root, tt.wantRoot)
+			}
+			if result.RelPath != tt.wantRel {
+				t.Errorf("RelPath = %q, want %q", result.RelPath, tt.wantRel)
+			}
+		})
+	}
+}
+
+// TestInferFromPath_CustomXDGConfigHome tests inference under a custom XDG_CONFIG_HOME
+func TestInferFromPath_CustomXDGConfigHome(t *testing.T) {
+	home := useTempHome(t)
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".dotfiles", "config"))
+
+	tests := []struct {
+		name     string
+		path     string
+		wantName string
+		wantRoot string
+		wantRel  string
+	}{
+		{
+			name:     "file under custom XDG_CONFIG_HOME",
+			path:     filepath.Join(home, ".dotfiles", "config", "nvim", "init.lua"),
+			wantName: "nvim",
+			wantRoot: filepath.Join("~", ".dotfiles", "config", "nvim"),
+			wantRel:  "init.lua",
+		},
+		{
+			name:     "nested file under custom XDG_CONFIG_HOME",
+			path:     filepath.Join(home, ".dotfiles", "config", "nvim", "lua", "plugins.lua"),
+			wantName: "nvim",
+			wantRoot: filepath.Join("~", ".dotfiles", "config", "nvim"),
+			wantRel:  filepath.Join("lua", "plugins.lua"),
+		},
+		{
+			name:     "container dir under custom XDG_CONFIG_HOME",
+			path:     filepath.Join(home, ".dotfiles", "config", "systemd", "user", "foo.service"),
+			wantName: "systemd-user",
+			wantRoot: filepath.Join("~", ".dotfiles", "config", "systemd", "user"),
+			wantRel:  "foo.service",
+		},
+		{
+			name:     "default ~/.config still inferred",
+			path:     filepath.Join(home, ".config", "opencode", "config.json"),
+			wantName: "opencode",
+			wantRoot: filepath.Join("~", ".config", "opencode"),
+			wantRel:  "config.json",
+		},
+		{
+			name:     "file directly in XDG_CONFIG_HOME falls back to hidden dir pattern",
+			path:     filepath.Join(home, ".dotfiles", "config", "loose.conf"),
+			wantName: "dotfiles",
+			wantRoot: filepath.Join("~", ".dotfiles"),
+			wantRel:  filepath.Join("config", "loose.conf"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -65,10 +161,7 @@ func TestInferFromPath_XDGConfig(t *testing.T) {
 
 // TestInferFromPath_HiddenDir tests inference for ~/.<name>/* pattern
 func TestInferFromPath_HiddenDir(t *testing.T) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Fatalf("failed to get home dir: %v", err)
-	}
+	home := useTempHome(t)
 
 	tests := []struct {
 		name     string
@@ -121,10 +214,7 @@ func TestInferFromPath_HiddenDir(t *testing.T) {
 
 // TestInferFromPath_Dotfile tests inference for ~/.<name> pattern (single dotfiles)
 func TestInferFromPath_Dotfile(t *testing.T) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Fatalf("failed to get home dir: %v", err)
-	}
+	home := useTempHome(t)
 
 	tests := []struct {
 		name     string
@@ -188,10 +278,7 @@ func TestInferFromPath_macOSApplicationSupport(t *testing.T) {
 		t.Skip("skipping macOS-specific test")
 	}
 
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Fatalf("failed to get home dir: %v", err)
-	}
+	home := useTempHome(t)
 
 	tests := []struct {
 		name     string
@@ -235,12 +322,35 @@ func TestInferFromPath_macOSApplicationSupport(t *testing.T) {
 	}
 }
 
+// TestInferFromPath_Pattern tests that the matched pattern is reported
+func TestInferFromPath_Pattern(t *testing.T) {
+	home := useTempHome(t)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(home, ".config", "nvim", "init.lua"), "~/.config/<name>/*"},
+		{filepath.Join(home, ".config", "systemd", "user", "foo.service"), "~/.config/<container>/<name>/*"},
+		{filepath.Join(home, ".aws", "config"), "~/.<name>/*"},
+		{filepath.Join(home, ".zshrc"), "~/.<name>"},
+	}
+
+	for _, tt := range tests {
+		result := InferFromPath(tt.path)
+		if result == nil {
+			t.Fatalf("InferFromPath(%q) returned nil", tt.path)
+		}
+		if result.Pattern != tt.want {
+			t.Errorf("InferFromPath(%q).Pattern = %q, want %q", tt.path, result.Pattern, tt.want)
+		}
+	}
+}
+
 // TestInferFromPath_EdgeCases tests edge cases
 func TestInferFromPath_EdgeCases(t *testing.T) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Fatalf("failed to get home dir: %v", err)
-	}
+	home := useTempHome(t)
 
 	tests := []struct {
 		name    string
@@ -260,6 +370,12 @@ func TestInferFromPath_EdgeCases(t *testing.T) {
 			wantNil: true,
 			descr:   "non-hidden paths should return nil",
 		},
+		{
+			name:    "sibling of home sharing its prefix",
+			path:    filepath.Join(home+"2", ".config", "app", "config.json"),
+			wantNil: true,
+			descr:   "paths next to home should not be treated as inside it",
+		},
 	}
 
 	for _, tt := range tests {
@@ -277,10 +393,7 @@ func TestInferFromPath_EdgeCases(t *testing.T) {
 
 // TestExpandHome tests home directory expansion
 func TestExpandHome(t *testing.T) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Fatalf("failed to get home dir: %v", err)
-	}
+	home := useTempHome(t)
 
 	tests := []struct {
 		name string
@@ -321,10 +434,7 @@ func TestExpandHome(t *testing.T) {
 
 // TestContractHome tests home directory contraction
 func TestContractHome(t *testing.T) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Fatalf("failed to get home dir: %v", err)
-	}
+	home := useTempHome(t)
 
 	tests := []struct {
 		name string
@@ -360,10 +470,7 @@ func TestContractHome(t *testing.T) {
 
 // TestIsUnderHome tests home directory checking
 func TestIsUnderHome(t *testing.T) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Fatalf("failed to get home dir: %v", err)
-	}
+	home := useTempHome(t)
 
 	tests := []struct {
 		name string
@@ -404,10 +511,7 @@ func TestIsUnderHome(t *testing.T) {
 
 // TestAbsolutePath tests absolute path conversion
 func TestAbsolutePath(t *testing.T) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Fatalf("failed to get home dir: %v", err)
-	}
+	home := useTempHome(t)
 
 	tests := []struct {
 		name    string
@@ -442,3 +546,85 @@ func TestAbsolutePath(t *testing.T) {
 		})
 	}
 }
+
+// TestCleanInput tests normalizing messy input paths
+func TestCleanInput(t *testing.T) {
+	home := useTempHome(t)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~/.config/app/config.json", filepath.Join("~", ".config", "app", "config.json")},
+		{"~/.config/app//config.json", filepath.Join("~", ".config", "app", "config.json")},
+		{"~/.config/app/./config.json", filepath.Join("~", ".config", "app", "config.json")},
+		{"~/.config/app/config.json/", filepath.Join("~", ".config", "app", "config.json")},
+		{"~/.config/other/../app/config.json", filepath.Join("~", ".config", "app", "config.json")},
+		{"~/.config/app/.", filepath.Join("~", ".config", "app")},
+		{"~/", "~"},
+		{filepath.Join(home, ".zshrc") + string(filepath.Separator), filepath.Join(home, ".zshrc")},
+		{"./dir//file", filepath.Join("dir", "file")},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := CleanInput(tt.path); got != tt.want {
+			t.Errorf("CleanInput(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// Messy and clean forms resolve to the same absolute path
+	messy, _ := AbsolutePath(CleanInput("~/.config//app/./config.json/"))
+	clean, _ := AbsolutePath("~/.config/app/config.json")
+	if messy != clean {
+		t.Errorf("AbsolutePath(messy) = %q, want %q", messy, clean)
+	}
+}
+
+// TestSafeRel tests relative path computation and not-under-root detection
+func TestSafeRel(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator)+"home", "user", ".config", "app")
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"direct child", filepath.Join(root, "config.json"), "config.json", false},
+		{"nested", filepath.Join(root, "sub", "a.json"), "sub/a.json", false},
+		{"deeply nested", filepath.Join(root, "a", "b", "c", "d.json"), "a/b/c/d.json", false},
+		{"unclean path inside", root + string(filepath.Separator) + filepath.Join("sub", "..", "x.json"), "x.json", false},
+		{"unclean path escaping", filepath.Join(root, "sub", "..", "..", "x.json"), "", true},
+		{"grandparent", filepath.Dir(filepath.Dir(root)), "", true},
+		{"unrelated tree", filepath.Join(string(filepath.Separator)+"etc", "hosts"), "", true},
+		{"root itself", root, "", true},
+		{"sibling with shared prefix", root + "2" + string(filepath.Separator) + "config.json", "", true},
+		{"parent", filepath.Join(filepath.Dir(root), "other.json"), "", true},
+		{"dotdot-prefixed name", filepath.Join(root, "..config"), "..config", false},
+		{"dotdot-prefixed directory", filepath.Join(root, "..d", "a.json"), "..d/a.json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SafeRel(root, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SafeRel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("SafeRel(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSafeRel_CrossVolume tests that paths on different Windows volumes fail
+func TestSafeRel_CrossVolume(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("volumes only exist on Windows")
+	}
+
+	if _, err := SafeRel(`C:\Users\user\.config\app`, `D:\app\config.json`); err == nil {
+		t.Error("SafeRel() should fail across volumes")
+	}
+}
@@ -0,0 +1,87 @@
+package pathutil
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ScanResult describes a file found while scanning an existing dotfiles layout.
+type ScanResult struct {
+	InferResult
+	// SourcePath is the absolute path of the file inside the scanned directory
+	SourcePath string
+}
+
+// ScanLayout walks a directory that mirrors the home directory layout
+// (e.g. ~/dotfiles/.zshrc, ~/dotfiles/.config/nvim/init.lua) and infers
+// an entry for each file as if it lived at the same place under home.
+// Returns the files that could be inferred and the ones that could not.
+// VCS metadata directories like .git are skipped.
+func ScanLayout(dir string) ([]ScanResult, []string, error) {
+	home, err := HomeDir()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting home directory: %w", err)
+	}
+
+	dir = filepath.Clean(dir)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("checking directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("not a directory: %s", dir)
+	}
+
+	var found []ScanResult
+	var unmatched []string
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && isVCSDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Only regular files can be tracked
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		inferred := InferFromPath(filepath.Join(home, rel))
+		if inferred == nil {
+			unmatched = append(unmatched, path)
+			return nil
+		}
+
+		found = append(found, ScanResult{
+			InferResult: *inferred,
+			SourcePath:  path,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("scanning directory: %w", err)
+	}
+
+	return found, unmatched, nil
+}
+
+// isVCSDir returns true for version control metadata directories.
+func isVCSDir(name string) bool {
+	switch name {
+	case ".git", ".hg", ".svn":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,77 @@
+package pathutil
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// TestScanLayout tests inference over a directory mirroring the home layout
+func TestScanLayout(t *testing.T) {
+	dir := t.TempDir()
+
+	files := []string{
+		".zshrc",
+		filepath.Join(".config", "nvim", "init.lua"),
+		filepath.Join(".aws", "config"),
+		"README.md",
+		filepath.Join(".git", "HEAD"),
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	found, unmatched, err := ScanLayout(dir)
+	if err != nil {
+		t.Fatalf("ScanLayout() failed: %v", err)
+	}
+
+	names := make([]string, 0, len(found))
+	for _, r := range found {
+		names = append(names, r.Name)
+		if !filepath.IsAbs(r.SourcePath) {
+			t.Errorf("SourcePath %q is not absolute", r.SourcePath)
+		}
+	}
+	sort.Strings(names)
+
+	want := []string{"aws", "nvim", "zsh"}
+	if len(names) != len(want) {
+		t.Fatalf("found entries = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("found[%d] = %q, want %q", i, names[i], want[i])
+		}
+	}
+
+	if len(unmatched) != 1 || filepath.Base(unmatched[0]) != "README.md" {
+		t.Errorf("unmatched = %v, want [README.md]", unmatched)
+	}
+}
+
+// TestScanLayout_NotDirectory tests that scanning a file fails
+func TestScanLayout_NotDirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(file, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if _, _, err := ScanLayout(file); err == nil {
+		t.Error("ScanLayout() should fail for a file")
+	}
+}
+
+// TestScanLayout_NotExist tests that scanning a missing directory fails
+func TestScanLayout_NotExist(t *testing.T) {
+	if _, _, err := ScanLayout(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ScanLayout() should fail for a missing directory")
+	}
+}
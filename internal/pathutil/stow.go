@@ -0,0 +1,182 @@
+package pathutil
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StowPattern is the Pattern of files found by ScanStow.
+const StowPattern = "stow package"
+
+// ScanStow walks a GNU Stow directory, where each top-level directory is a
+// package mirroring the home layout (e.g. ~/dotfiles/zsh/.zshrc,
+// ~/dotfiles/nvim/.config/nvim/init.lua). Each package becomes an entry
+// named after it and rooted at the deepest directory holding all of its
+// files, e.g. "nvim" rooted at ~/.config/nvim.
+//
+// Only the given packages are scanned, or all of them if none are given.
+// With dotfiles set, "dot-" prefixes are turned into "." like stow's
+// --dotfiles option. Files stow ignores by default (VCS metadata, editor
+// backups, and README/LICENSE at the top of a package) are skipped.
+func ScanStow(stowDir string, packages []string, dotfiles bool) ([]ScanResult, error) {
+	stowDir = filepath.Clean(stowDir)
+	info, err := os.Stat(stowDir)
+	if err != nil {
+		return nil, fmt.Errorf("checking directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", stowDir)
+	}
+
+	if len(packages) == 0 {
+		dirEntries, err := os.ReadDir(stowDir)
+		if err != nil {
+			return nil, fmt.Errorf("reading stow directory: %w", err)
+		}
+		for _, d := range dirEntries {
+			if d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+				packages = append(packages, d.Name())
+			}
+		}
+	}
+	sort.Strings(packages)
+
+	var found []ScanResult
+	for _, pkg := range packages {
+		results, err := scanStowPackage(filepath.Join(stowDir, pkg), pkg, dotfiles)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, results...)
+	}
+	return found, nil
+}
+
+// scanStowPackage returns the files of one stow package, relative to the
+// package's common root.
+func scanStowPackage(pkgDir, pkg string, dotfiles bool) ([]ScanResult, error) {
+	info, err := os.Stat(pkgDir)
+	if err != nil {
+		return nil, fmt.Errorf("checking package %s: %w", pkg, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("package %s is not a directory", pkg)
+	}
+
+	var sources, homeRels []string
+	err = filepath.WalkDir(pkgDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == pkgDir {
+			return nil
+		}
+		rel, err := SafeRel(pkgDir, p)
+		if err != nil {
+			return err
+		}
+		topLevel := !strings.Contains(rel, "/")
+		if stowIgnored(d.Name(), topLevel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Only regular files can be tracked
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+
+		homeRel := rel
+		if dotfiles {
+			homeRel = undotStow(homeRel)
+		}
+		sources = append(sources, p)
+		homeRels = append(homeRels, homeRel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning package %s: %w", pkg, err)
+	}
+
+	root := commonDir(homeRels)
+	results := make([]ScanResult, 0, len(sources))
+	for i, source := range sources {
+		relPath := homeRels[i]
+		entryRoot := "~"
+		if root != "" {
+			relPath = strings.TrimPrefix(relPath, root+"/")
+			entryRoot = "~/" + root
+		}
+		results = append(results, ScanResult{
+			InferResult: InferResult{
+				Name:    pkg,
+				Root:    entryRoot,
+				RelPath: relPath,
+				Pattern: StowPattern,
+			},
+			SourcePath: source,
+		})
+	}
+	return results, nil
+}
+
+// stowIgnored reports whether stow skips a file or directory by default.
+// README, LICENSE and COPYING are only ignored at the top of a package.
+func stowIgnored(name string, topLevel bool) bool {
+	if isVCSDir(name) {
+		return true
+	}
+	switch {
+	case name == ".gitignore", name == ".cvsignore", name == ".stow-local-ignore":
+		return true
+	case strings.HasSuffix(name, "~"), strings.HasPrefix(name, ".#"):
+		return true
+	case strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#"):
+		return true
+	}
+	if topLevel {
+		upper := strings.ToUpper(name)
+		return upper == "COPYING" || strings.HasPrefix(upper, "README") || strings.HasPrefix(upper, "LICENSE")
+	}
+	return false
+}
+
+// undotStow turns "dot-" prefixes of every path element into ".", e.g.
+// "dot-config/nvim/init.lua" -> ".config/nvim/init.lua".
+func undotStow(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if rest, ok := strings.CutPrefix(part, "dot-"); ok && rest != "" {
+			parts[i] = "." + rest
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// commonDir returns the deepest directory containing all of the given
+// slash-separated relative paths, or "" if it's their common base.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	common := strings.Split(path.Dir(paths[0]), "/")
+	for _, p := range paths[1:] {
+		parts := strings.Split(path.Dir(p), "/")
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	dir := strings.Join(common, "/")
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,88 @@
+package pathutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestScanStow tests mapping stow packages to entries
+func TestScanStow(t *testing.T) {
+	dir := t.TempDir()
+
+	files := []string{
+		filepath.Join("zsh", ".zshrc"),
+		filepath.Join("zsh", ".zshenv"),
+		filepath.Join("zsh", "README.md"),
+		filepath.Join("nvim", ".config", "nvim", "init.lua"),
+		filepath.Join("nvim", ".config", "nvim", "lua", "plugins.lua"),
+		filepath.Join("nvim", ".config", "nvim", "init.lua~"),
+		filepath.Join("git", "dot-gitconfig"),
+		filepath.Join(".git", "HEAD"),
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	found, err := ScanStow(dir, nil, true)
+	if err != nil {
+		t.Fatalf("ScanStow() failed: %v", err)
+	}
+
+	type file struct{ name, root, relPath string }
+	want := []file{
+		{"git", "~", ".gitconfig"},
+		{"nvim", "~/.config/nvim", "init.lua"},
+		{"nvim", "~/.config/nvim", "lua/plugins.lua"},
+		{"zsh", "~", ".zshenv"},
+		{"zsh", "~", ".zshrc"},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("ScanStow() found %d files (%v), want %d", len(found), found, len(want))
+	}
+	for i, w := range want {
+		got := file{found[i].Name, found[i].Root, found[i].RelPath}
+		if got != w {
+			t.Errorf("found[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+
+	// Only the named packages are scanned
+	found, err = ScanStow(dir, []string{"zsh"}, false)
+	if err != nil {
+		t.Fatalf("ScanStow() failed: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("ScanStow(zsh) found %d files, want 2", len(found))
+	}
+
+	if _, err := ScanStow(dir, []string{"missing"}, false); err == nil {
+		t.Error("ScanStow() should fail for a missing package")
+	}
+}
+
+// TestCommonDir tests finding the deepest shared directory
+func TestCommonDir(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{".zshrc", ".zshenv"}, ""},
+		{[]string{".config/nvim/init.lua", ".config/nvim/lua/a.lua"}, ".config/nvim"},
+		{[]string{".config/starship.toml"}, ".config"},
+		{[]string{".config/a/x", ".config/b/y"}, ".config"},
+		{[]string{".config/a/x", ".zshrc"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := commonDir(tt.paths); got != tt.want {
+			t.Errorf("commonDir(%v) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
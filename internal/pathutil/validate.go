@@ -1,10 +1,13 @@
 package pathutil
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/wtfzambo/dotsync/internal/manifest"
@@ -15,15 +18,33 @@ type ValidationError struct {
 	Path    string
 	Message string
 	IsWarn  bool // If true, this is a warning, not a fatal error
+	// NeedsCopy is set when the file can't be a symlink but can be tracked in copy mode
+	NeedsCopy bool
+	// OutsideHome is set for the warning about files outside the home directory
+	OutsideHome bool
 }
 
 func (e ValidationError) Error() string {
 	return e.Message
 }
 
-// ValidateForAdd checks if a file can be added to dotsync.
+// DefaultMaxFileSize is the size above which adding a file needs confirmation.
+const DefaultMaxFileSize = 50 << 20
+
+// ValidateForAdd checks if a file can be added to dotsync. Files matching
+// one of the ignore patterns (see MatchIgnore) are always refused, and files
+// larger than maxSize bytes produce a warning (0 disables the check).
 // Returns an error if validation fails, or a warning ValidationError if there's a non-fatal issue.
-func ValidateForAdd(absPath string) error {
+// When several warnings apply they are returned together with errors.Join.
+func ValidateForAdd(absPath string, ignore []string, maxSize int64) error {
+	// Check the global ignore list first
+	if pattern := MatchIgnore(absPath, ignore); pattern != "" {
+		return ValidationError{
+			Path:    absPath,
+			Message: fmt.Sprintf("%s matches ignore pattern %q in the manifest and can't be tracked", ContractHome(absPath), pattern),
+		}
+	}
+
 	// Check if file exists
 	info, err := os.Lstat(absPath) // Use Lstat to detect symlinks
 	if os.IsNotExist(err) {
@@ -40,7 +61,7 @@ func ValidateForAdd(absPath string) error {
 	if info.Mode()&os.ModeSymlink != 0 {
 		return ValidationError{
 			Path:    absPath,
-			Message: "cannot track symlinks. If this is already synced elsewhere, unlink it first",
+			Message: "cannot track symlinks. If this is already synced elsewhere, unlink it first, or use --follow-symlinks to track its target",
 		}
 	}
 
@@ -55,26 +76,140 @@ func ValidateForAdd(absPath string) error {
 	// Check for macOS plist files
 	if runtime.GOOS == "darwin" && isPlistFile(absPath) {
 		return ValidationError{
-			Path:    absPath,
-			Message: "macOS 14+ does NOT support symlinks for plist files. Use Mackup Copy mode (https://github.com/lra/mackup?tab=readme-ov-file#copy-mode) for these files",
+			Path:      absPath,
+			Message:   "macOS 14+ does NOT support symlinks for plist files. Use 'dotsync add --copy' to track a copy instead",
+			NeedsCopy: true,
 		}
 	}
 
-	// Check if outside home directory (warning, not error)
-	if !IsUnderHome(absPath) {
-		return ValidationError{
+	// Collect warnings, so that one doesn't hide another
+	var warnings []error
+
+	// Check the file size (warning, not error)
+	if maxSize > 0 && info.Size() > maxSize {
+		warnings = append(warnings, ValidationError{
 			Path:    absPath,
-			Message: "file is outside home directory. Symlinks may not work as expected if paths differ across machines",
+			Message: fmt.Sprintf("%s is %s, above the %s limit. Large files bloat cloud storage and slow down sync", ContractHome(absPath), FormatSize(info.Size()), FormatSize(maxSize)),
 			IsWarn:  true,
-		}
+		})
+	}
+
+	// Check if outside home directory (warning, not error)
+	if !IsUnderHome(absPath) {
+		warnings = append(warnings, ValidationError{
+			Path:        absPath,
+			Message:     "file is outside home directory. Symlinks may not work as expected if paths differ across machines",
+			IsWarn:      true,
+			OutsideHome: true,
+		})
+	}
+
+	switch len(warnings) {
+	case 0:
+		return nil
+	case 1:
+		return warnings[0]
+	}
+	return errors.Join(warnings...)
+}
+
+// ValidateNotState refuses files inside one of stateDirs, the directories
+// holding dotsync's own state (its config directory and <storage>/dotsync).
+// Tracking those would make dotsync manage itself. Symlinked directories
+// are resolved on both sides.
+func ValidateNotState(absPath string, stateDirs []string) error {
+	paths := []string{filepath.Clean(absPath)}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(absPath)); err == nil {
+		paths = append(paths, filepath.Join(dir, filepath.Base(absPath)))
 	}
 
+	for _, stateDir := range stateDirs {
+		dirs := []string{filepath.Clean(stateDir)}
+		if resolved, err := filepath.EvalSymlinks(stateDir); err == nil {
+			dirs = append(dirs, resolved)
+		}
+		for _, p := range paths {
+			for _, d := range dirs {
+				if p == d || strings.HasPrefix(p, d+string(filepath.Separator)) {
+					return ValidationError{
+						Path:    absPath,
+						Message: fmt.Sprintf("%s is inside %s, where dotsync keeps its own state, and can't be tracked", ContractHome(absPath), ContractHome(stateDir)),
+					}
+				}
+			}
+		}
+	}
 	return nil
 }
 
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "50MB", "512KB", "1GB" or a plain number
+// of bytes. Units are binary (1KB = 1024 bytes) and case-insensitive.
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(str, u.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, u.suffix))
+			mult = u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 50MB, 512KB or 1GB)", s)
+	}
+	if n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return n * mult, nil
+}
+
+// FormatSize renders a byte count using the largest unit that fits.
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits {
+		if n >= u.bytes && u.bytes > 1 {
+			v := strconv.FormatFloat(float64(n)/float64(u.bytes), 'f', 1, 64)
+			return strings.TrimSuffix(v, ".0") + u.suffix
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// MatchIgnore returns the first pattern that matches absPath, or empty
+// string if none does. Patterns use filepath.Match syntax. A pattern
+// containing a path separator is matched against the full path (~ is
+// expanded); otherwise it is matched against the file name alone.
+func MatchIgnore(absPath string, patterns []string) string {
+	absPath = filepath.Clean(absPath)
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		glob, target := pattern, filepath.Base(absPath)
+		if strings.ContainsAny(pattern, `/\`) {
+			glob = filepath.Clean(ExpandHome(filepath.FromSlash(pattern)))
+			target = absPath
+		}
+		if ok, _ := filepath.Match(glob, target); ok {
+			return pattern
+		}
+	}
+	return ""
+}
+
 // isPlistFile checks if a path is a macOS plist file in ~/Library/Preferences/
 func isPlistFile(absPath string) bool {
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
 		return false
 	}
@@ -90,7 +225,7 @@ func isPlistFile(absPath string) bool {
 // CheckEntryConflict checks if adding a file would conflict with an existing entry.
 // Returns the conflicting entry name if there's a conflict, empty string otherwise.
 func CheckEntryConflict(absPath string, explicitName string, m *manifest.Manifest) (string, error) {
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
 		return "", err
 	}
@@ -111,7 +246,7 @@ func CheckEntryConflict(absPath string, explicitName string, m *manifest.Manifes
 			// Bug #4 fix: If no explicit name, check if inferred root matches this entry's root
 			if explicitName == "" {
 				inferred := InferFromPath(absPath)
-				if inferred != nil && inferred.Root != entry.Root {
+				if inferred != nil && manifest.NormalizeRoot(inferred.Root) != manifest.NormalizeRoot(entry.Root) {
 					// Inferred root differs from existing entry's root - this is a conflict
 					return name, nil
 				}
@@ -123,7 +258,7 @@ func CheckEntryConflict(absPath string, explicitName string, m *manifest.Manifes
 
 		// Check if any of the entry's files match this path
 		for _, f := range entry.Files {
-			fullPath := filepath.Join(entryRoot, f)
+			fullPath := filepath.Join(entryRoot, manifest.FromStorageSlash(f))
 			if fullPath == absPath {
 				// File is already tracked
 				return name, nil
@@ -149,6 +284,35 @@ func CheckEntryConflict(absPath string, explicitName string, m *manifest.Manifes
 	return "", nil
 }
 
+// CheckNesting checks if absPath overlaps with an already-tracked file without
+// being that file: either a tracked file lives under absPath (absPath is a parent
+// directory of tracked files), or absPath lives under a tracked path.
+// Tracking both would create overlapping symlinks that shadow each other.
+// Returns the entry name and the tracked path involved, or empty strings.
+func CheckNesting(absPath string, m *manifest.Manifest) (string, string) {
+	absPath = filepath.Clean(absPath)
+
+	names := m.Names()
+
+	for _, name := range names {
+		entry := m.Entries[name]
+		entryRoot := ExpandHome(entry.Root)
+		for _, f := range entry.Files {
+			trackedPath := filepath.Join(entryRoot, manifest.FromStorageSlash(f))
+			if isSubPath(trackedPath, absPath) || isSubPath(absPath, trackedPath) {
+				return name, trackedPath
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// isSubPath returns true if path is strictly under parent.
+func isSubPath(path, parent string) bool {
+	return strings.HasPrefix(path, parent+string(filepath.Separator))
+}
+
 // CheckWritePermission checks if a directory is writable.
 // Returns an error if the directory doesn't have write permissions.
 func CheckWritePermission(dir string) error {
@@ -162,14 +326,15 @@ func CheckWritePermission(dir string) error {
 		return fmt.Errorf("not a directory: %s", dir)
 	}
 
-	// Try creating a temporary file to test write permission
-	testFile := filepath.Join(dir, ".dotsync-write-test")
-	f, err := os.Create(testFile)
+	// Try creating a uniquely named temporary file to test write permission
+	f, err := os.CreateTemp(dir, ".dotsync-write-*")
 	if err != nil {
 		return fmt.Errorf("directory is not writable: %w", err)
 	}
-	f.Close()
-	os.Remove(testFile)
+	defer os.Remove(f.Name())
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("directory is not writable: %w", err)
+	}
 
 	return nil
 }
@@ -180,8 +345,8 @@ func IsAlreadyTracked(absPath string, m *manifest.Manifest) string {
 	for name, entry := range m.Entries {
 		entryRoot := ExpandHome(entry.Root)
 		for _, f := range entry.Files {
-			fullPath := filepath.Join(entryRoot, f)
-			if fullPath == absPath {
+			fullPath := filepath.Join(entryRoot, manifest.FromStorageSlash(f))
+			if manifest.SamePath(fullPath, absPath) {
 				return name
 			}
 		}
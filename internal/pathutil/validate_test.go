@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/wtfzambo/dotsync/internal/manifest"
@@ -14,7 +15,7 @@ func TestValidateForAdd_FileNotFound(t *testing.T) {
 	tmpDir := t.TempDir()
 	nonExistent := filepath.Join(tmpDir, "nonexistent.txt")
 
-	err := ValidateForAdd(nonExistent)
+	err := ValidateForAdd(nonExistent, nil, 0)
 	if err == nil {
 		t.Fatal("expected error for non-existent file")
 	}
@@ -45,7 +46,7 @@ func TestValidateForAdd_Symlink(t *testing.T) {
 		t.Fatalf("failed to create symlink: %v", err)
 	}
 
-	err := ValidateForAdd(symlinkFile)
+	err := ValidateForAdd(symlinkFile, nil, 0)
 	if err == nil {
 		t.Fatal("expected error for symlink")
 	}
@@ -69,7 +70,7 @@ func TestValidateForAdd_Directory(t *testing.T) {
 		t.Fatalf("failed to create test directory: %v", err)
 	}
 
-	err := ValidateForAdd(testDir)
+	err := ValidateForAdd(testDir, nil, 0)
 	if err == nil {
 		t.Fatal("expected error for directory")
 	}
@@ -114,7 +115,7 @@ func TestValidateForAdd_PlistFile(t *testing.T) {
 		t.Skip("temp dir not under home, skipping")
 	}
 
-	err = ValidateForAdd(plistFile)
+	err = ValidateForAdd(plistFile, nil, 0)
 	if err == nil {
 		t.Fatal("expected error for plist file")
 	}
@@ -129,7 +130,7 @@ func TestValidateForAdd_OutsideHome(t *testing.T) {
 		t.Fatalf("failed to create test file: %v", err)
 	}
 
-	err := ValidateForAdd(testFile)
+	err := ValidateForAdd(testFile, nil, 0)
 	if err == nil {
 		// If tmpDir happens to be under home, skip this test
 		home, _ := os.UserHomeDir()
@@ -149,6 +150,75 @@ func TestValidateForAdd_OutsideHome(t *testing.T) {
 	}
 }
 
+// TestValidateForAdd_Ignored tests that files matching an ignore pattern are refused
+func TestValidateForAdd_Ignored(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("failed to get home dir: %v", err)
+	}
+
+	tmpDir := filepath.Join(home, ".cache", "dotsync-test-ignore")
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		t.Fatalf("failed to create test dir: %v", err)
+	}
+
+	testFile := filepath.Join(tmpDir, "id_rsa")
+	if err := os.WriteFile(testFile, []byte("secret"), 0600); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	err = ValidateForAdd(testFile, []string{"*.key", "~/.cache/dotsync-test-ignore/id_*"}, 0)
+	if err == nil {
+		t.Fatal("expected error for ignored file")
+	}
+	valErr, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("expected ValidationError, got %T", err)
+	}
+	if valErr.IsWarn {
+		t.Error("ignored file should be an error, not a warning")
+	}
+	if !strings.Contains(valErr.Message, "~/.cache/dotsync-test-ignore/id_*") {
+		t.Errorf("message %q should name the matched pattern", valErr.Message)
+	}
+
+	if err := ValidateForAdd(testFile, []string{"*.key"}, 0); err != nil {
+		t.Errorf("expected no error for non-matching patterns, got: %v", err)
+	}
+}
+
+// TestMatchIgnore tests glob matching against full paths and file names
+func TestMatchIgnore(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("failed to get home dir: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		patterns []string
+		want     string
+	}{
+		{"name pattern", filepath.Join(home, "certs", "server.key"), []string{"*.key"}, "*.key"},
+		{"home pattern", filepath.Join(home, ".ssh", "id_ed25519"), []string{"~/.ssh/id_*"}, "~/.ssh/id_*"},
+		{"absolute pattern", "/etc/secret.conf", []string{"/etc/*.conf"}, "/etc/*.conf"},
+		{"path pattern doesn't match other dirs", filepath.Join(home, "backup", ".ssh", "id_rsa"), []string{"~/.ssh/id_*"}, ""},
+		{"no patterns", filepath.Join(home, ".zshrc"), nil, ""},
+		{"first match wins", filepath.Join(home, "a.key"), []string{"", "*.pem", "a.*", "*.key"}, "a.*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchIgnore(tt.path, tt.patterns); got != tt.want {
+				t.Errorf("MatchIgnore(%q, %v) = %q, want %q", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
 // TestValidateForAdd_ValidFile tests validation passes for valid files
 func TestValidateForAdd_ValidFile(t *testing.T) {
 	home, err := os.UserHomeDir()
@@ -168,12 +238,111 @@ func TestValidateForAdd_ValidFile(t *testing.T) {
 		t.Fatalf("failed to create test file: %v", err)
 	}
 
-	err = ValidateForAdd(testFile)
+	err = ValidateForAdd(testFile, nil, 0)
 	if err != nil {
 		t.Errorf("expected no error for valid file, got: %v", err)
 	}
 }
 
+// TestValidateForAdd_MaxSize tests the warning for files above the size limit
+func TestValidateForAdd_MaxSize(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	testFile := filepath.Join(home, "big.bin")
+	if err := os.WriteFile(testFile, make([]byte, 2048), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	err := ValidateForAdd(testFile, nil, 1024)
+	valErr, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if !valErr.IsWarn {
+		t.Error("expected a warning for a file above the limit")
+	}
+	if !strings.Contains(valErr.Message, "2KB") || !strings.Contains(valErr.Message, "1KB") {
+		t.Errorf("message should mention both sizes, got: %s", valErr.Message)
+	}
+
+	if err := ValidateForAdd(testFile, nil, 4096); err != nil {
+		t.Errorf("expected no error below the limit, got: %v", err)
+	}
+	if err := ValidateForAdd(testFile, nil, 0); err != nil {
+		t.Errorf("expected no error with the check disabled, got: %v", err)
+	}
+
+	// A large file outside home gets both warnings
+	outside := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(outside, make([]byte, 2048), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	joined, ok := ValidateForAdd(outside, nil, 1024).(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatal("expected both warnings for a large file outside home")
+	}
+	errs := joined.Unwrap()
+	if len(errs) != 2 {
+		t.Fatalf("got %d warnings, want 2: %v", len(errs), errs)
+	}
+	if valErr, ok := errs[1].(ValidationError); !ok || !valErr.IsWarn || !valErr.OutsideHome {
+		t.Errorf("second warning = %#v, want the outside-home warning", errs[1])
+	}
+}
+
+// TestParseSize tests size parsing
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "50MB", want: 50 << 20},
+		{in: "512kb", want: 512 << 10},
+		{in: "1GB", want: 1 << 30},
+		{in: "100", want: 100},
+		{in: "10B", want: 10},
+		{in: "0", want: 0},
+		{in: "1.5MB", wantErr: true},
+		{in: "-1MB", wantErr: true},
+		{in: "big", wantErr: true},
+		{in: "9223372036854775807GB", wantErr: true},
+		{in: "8589934592GB", wantErr: true},
+		{in: "8589934591GB", want: 8589934591 << 30},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestFormatSize tests size formatting
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{in: 500, want: "500B"},
+		{in: 2048, want: "2KB"},
+		{in: 50 << 20, want: "50MB"},
+		{in: 3 << 29, want: "1.5GB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSize(tt.in); got != tt.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
 // TestCheckEntryConflict tests entry conflict detection
 func TestCheckEntryConflict(t *testing.T) {
 	home, err := os.UserHomeDir()
@@ -277,6 +446,27 @@ func TestIsAlreadyTracked(t *testing.T) {
 	}
 }
 
+// TestIsAlreadyTracked_CaseInsensitive tests that a differently cased path
+// counts as tracked on case-insensitive filesystems
+func TestIsAlreadyTracked_CaseInsensitive(t *testing.T) {
+	home := useTempHome(t)
+	orig := manifest.CaseInsensitive
+	t.Cleanup(func() { manifest.CaseInsensitive = orig })
+
+	m := manifest.New()
+	m.AddFile("opencode", filepath.Join("~", ".config", "opencode"), "config.json")
+	path := filepath.Join(home, ".config", "opencode", "Config.json")
+
+	manifest.CaseInsensitive = true
+	if got := IsAlreadyTracked(path, m); got != "opencode" {
+		t.Errorf("IsAlreadyTracked() = %q, want %q", got, "opencode")
+	}
+	manifest.CaseInsensitive = false
+	if got := IsAlreadyTracked(path, m); got != "" {
+		t.Errorf("IsAlreadyTracked() = %q, want empty on a case-sensitive filesystem", got)
+	}
+}
+
 // TestValidationError tests ValidationError type
 func TestValidationError(t *testing.T) {
 	tests := []struct {
@@ -392,3 +582,96 @@ func TestCheckEntryConflict_OverlappingRoots_DetectsConflict(t *testing.T) {
 		t.Logf("Conflict correctly detected with entry: %s", conflict)
 	}
 }
+
+// TestCheckNesting tests detection of paths overlapping tracked files
+func TestCheckNesting(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("failed to get home dir: %v", err)
+	}
+
+	m := manifest.New()
+	m.AddFile("app", filepath.Join("~", ".config", "app"), "config.json")
+	m.AddFile("tool", filepath.Join("~", ".config", "tool"), "settings")
+
+	tests := []struct {
+		name        string
+		path        string
+		wantEntry   string
+		wantTracked string
+	}{
+		{
+			name:        "directory containing tracked file",
+			path:        filepath.Join(home, ".config", "app"),
+			wantEntry:   "app",
+			wantTracked: filepath.Join(home, ".config", "app", "config.json"),
+		},
+		{
+			name:        "ancestor directory containing tracked file",
+			path:        filepath.Join(home, ".config"),
+			wantEntry:   "app",
+			wantTracked: filepath.Join(home, ".config", "app", "config.json"),
+		},
+		{
+			name:        "path under tracked path",
+			path:        filepath.Join(home, ".config", "tool", "settings", "user.json"),
+			wantEntry:   "tool",
+			wantTracked: filepath.Join(home, ".config", "tool", "settings"),
+		},
+		{
+			name: "tracked file itself",
+			path: filepath.Join(home, ".config", "app", "config.json"),
+		},
+		{
+			name: "sibling with shared prefix",
+			path: filepath.Join(home, ".config", "app", "config.json.bak"),
+		},
+		{
+			name: "unrelated path",
+			path: filepath.Join(home, ".config", "other", "config.json"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEntry, gotTracked := CheckNesting(tt.path, m)
+			if gotEntry != tt.wantEntry {
+				t.Errorf("entry = %q, want %q", gotEntry, tt.wantEntry)
+			}
+			if gotTracked != tt.wantTracked {
+				t.Errorf("tracked path = %q, want %q", gotTracked, tt.wantTracked)
+			}
+		})
+	}
+}
+
+// TestValidateNotState tests refusing files in dotsync's own directories
+func TestValidateNotState(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, ".config", "dotsync")
+	dotsyncDir := filepath.Join(tmpDir, "storage", "dotsync")
+	os.MkdirAll(configDir, 0755)
+	os.MkdirAll(dotsyncDir, 0755)
+	// A symlink to the storage folder must not be a way around the check
+	os.Symlink(filepath.Join(tmpDir, "storage"), filepath.Join(tmpDir, "cloud"))
+	stateDirs := []string{configDir, dotsyncDir}
+
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{filepath.Join(configDir, "config.json"), true},
+		{filepath.Join(dotsyncDir, ".dotsync.json"), true},
+		{filepath.Join(dotsyncDir, "app", "config.json"), true},
+		{filepath.Join(tmpDir, "cloud", "dotsync", ".dotsync.json"), true},
+		{filepath.Join(tmpDir, ".config", "dotsync-other", "config.json"), false},
+		{filepath.Join(tmpDir, "storage", "notes.txt"), false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateNotState(tt.path, stateDirs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateNotState(%q) = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Package snapshot records the manifest and file hashes at a point in time
+// so tracked files can later be compared against it.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+	"github.com/wtfzambo/dotsync/internal/pathutil"
+	"github.com/wtfzambo/dotsync/internal/symlink"
+)
+
+// Snapshot is a saved copy of the manifest plus the content hash of every
+// tracked file in cloud storage.
+type Snapshot struct {
+	Name     string             `json:"name"`
+	Created  time.Time          `json:"created"`
+	Manifest *manifest.Manifest `json:"manifest"`
+	// Hashes maps "<entry>/<relPath>" to the SHA-256 of the cloud copy.
+	// Files missing from cloud storage are not included.
+	Hashes map[string]string `json:"hashes"`
+	// Stats maps the same keys to the size and modification time of the
+	// cloud copy, for QuickDiff. Snapshots saved by older versions have none.
+	// They live here rather than in the manifest, which every machine
+	// shares and which would change on every edit of a tracked file.
+	Stats map[string]FileStat `json:"stats,omitempty"`
+}
+
+// FileStat is the size and modification time of a file, which change with
+// its content in practice and are much cheaper to read than a hash.
+type FileStat struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// same reports whether two stats describe an unchanged file.
+func (f FileStat) same(o FileStat) bool {
+	return f.Size == o.Size && f.ModTime.Equal(o.ModTime)
+}
+
+// Dir returns the path to the snapshot directory.
+// Default: ~/.cache/dotsync/snapshots/
+func Dir() (string, error) {
+	home, err := pathutil.HomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	return filepath.Join(home, ".cache", "dotsync", "snapshots"), nil
+}
+
+// Path returns the path of the snapshot file with the given name.
+func Path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// Take hashes every file tracked in m and returns a snapshot of it.
+func Take(name, storagePath string, m *manifest.Manifest) (*Snapshot, error) {
+	stats, err := statFiles(storagePath, m)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := hashFiles(storagePath, m)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Name:     name,
+		Created:  time.Now(),
+		Manifest: m,
+		Hashes:   hashes,
+		Stats:    stats,
+	}, nil
+}
+
+// hashFiles hashes the cloud copy of every file tracked in m.
+func hashFiles(storagePath string, m *manifest.Manifest) (map[string]string, error) {
+	hashes := make(map[string]string)
+	for _, name := range m.Names() {
+		for _, relPath := range m.Entries[name].Files {
+			cloudPath := m.CloudPath(storagePath, name, relPath)
+			hash, err := symlink.HashFile(cloudPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, fmt.Errorf("hashing %s: %w", cloudPath, err)
+			}
+			hashes[key(name, relPath)] = hash
+		}
+	}
+	return hashes, nil
+}
+
+// statFiles records the size and modification time of the cloud copy of
+// every file tracked in m.
+func statFiles(storagePath string, m *manifest.Manifest) (map[string]FileStat, error) {
+	stats := make(map[string]FileStat)
+	for _, name := range m.Names() {
+		for _, relPath := range m.Entries[name].Files {
+			cloudPath := m.CloudPath(storagePath, name, relPath)
+			info, err := os.Stat(cloudPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, fmt.Errorf("checking %s: %w", cloudPath, err)
+			}
+			stats[key(name, relPath)] = FileStat{Size: info.Size(), ModTime: info.ModTime()}
+		}
+	}
+	return stats, nil
+}
+
+// key returns the Hashes key for a file.
+func key(name, relPath string) string {
+	return name + "/" + filepath.ToSlash(relPath)
+}
+
+// Save writes the snapshot to the snapshot directory.
+func (s *Snapshot) Save() error {
+	path, err := Path(s.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Load reads the snapshot with the given name.
+func Load(name string) (*Snapshot, error) {
+	path, err := Path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("snapshot '%s' not found", name)
+		}
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing snapshot: %w", err)
+	}
+	if s.Hashes == nil {
+		s.Hashes = make(map[string]string)
+	}
+
+	return &s, nil
+}
+
+// ChangeKind describes how a file changed since a snapshot.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"    // Tracked now, not in the snapshot
+	ChangeRemoved  ChangeKind = "removed"  // In the snapshot, gone now
+	ChangeModified ChangeKind = "modified" // Content differs
+)
+
+// Change is a single file difference between a snapshot and the current state.
+type Change struct {
+	// File is "<entry>/<relPath>"
+	File string
+	Kind ChangeKind
+}
+
+// Diff compares the snapshot with the current cloud storage content of the
+// files tracked in m. Changes are sorted by file.
+func (s *Snapshot) Diff(storagePath string, m *manifest.Manifest) ([]Change, error) {
+	current, err := hashFiles(storagePath, m)
+	if err != nil {
+		return nil, err
+	}
+	return diffFiles(s.Hashes, current, func(a, b string) bool { return a == b }), nil
+}
+
+// QuickDiff is like Diff but compares only the size and modification time
+// of each file, without reading it. It is much faster on cloud mounts but
+// can report a file whose mtime was touched as modified, or miss a change
+// that kept both; use Diff to be sure.
+func (s *Snapshot) QuickDiff(storagePath string, m *manifest.Manifest) ([]Change, error) {
+	if len(s.Stats) == 0 && len(s.Hashes) > 0 {
+		return nil, fmt.Errorf("snapshot '%s' has no file sizes and times recorded, save it again or compare without --quick", s.Name)
+	}
+	current, err := statFiles(storagePath, m)
+	if err != nil {
+		return nil, err
+	}
+	return diffFiles(s.Stats, current, FileStat.same), nil
+}
+
+// diffFiles compares the per-file values recorded in a snapshot with the
+// current ones. Changes are sorted by file.
+func diffFiles[V any](old, current map[string]V, same func(a, b V) bool) []Change {
+	var changes []Change
+	for file, v := range current {
+		o, ok := old[file]
+		switch {
+		case !ok:
+			changes = append(changes, Change{File: file, Kind: ChangeAdded})
+		case !same(o, v):
+			changes = append(changes, Change{File: file, Kind: ChangeModified})
+		}
+	}
+	for file := range old {
+		if _, ok := current[file]; !ok {
+			changes = append(changes, Change{File: file, Kind: ChangeRemoved})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].File < changes[j].File
+	})
+	return changes
+}
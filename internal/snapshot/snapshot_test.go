@@ -0,0 +1,202 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wtfzambo/dotsync/internal/manifest"
+)
+
+// writeCloudFile creates a file in the storage layout
+func writeCloudFile(t *testing.T, storagePath, name, relPath, content string) {
+	t.Helper()
+	path := filepath.Join(storagePath, "dotsync", name, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+// TestSaveLoad_RoundTrip tests saving and loading a snapshot
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	storagePath := t.TempDir()
+
+	m := manifest.New()
+	m.AddFile("zsh", "~", ".zshrc")
+	writeCloudFile(t, storagePath, "zsh", ".zshrc", "export A=1")
+
+	s, err := Take("weekly", storagePath, m)
+	if err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	loaded, err := Load("weekly")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if loaded.Name != "weekly" {
+		t.Errorf("Name = %q, want %q", loaded.Name, "weekly")
+	}
+	if !loaded.Created.Equal(s.Created) {
+		t.Errorf("Created = %v, want %v", loaded.Created, s.Created)
+	}
+	if loaded.Hashes["zsh/.zshrc"] != s.Hashes["zsh/.zshrc"] || loaded.Hashes["zsh/.zshrc"] == "" {
+		t.Errorf("Hashes = %v, want %v", loaded.Hashes, s.Hashes)
+	}
+	if stat := loaded.Stats["zsh/.zshrc"]; stat.Size != int64(len("export A=1")) || !stat.same(s.Stats["zsh/.zshrc"]) {
+		t.Errorf("Stats = %v, want %v", loaded.Stats, s.Stats)
+	}
+	if !loaded.Manifest.HasEntry("zsh") {
+		t.Error("loaded manifest is missing entry 'zsh'")
+	}
+}
+
+// TestLoad_NotFound tests loading a missing snapshot
+func TestLoad_NotFound(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := Load("missing"); err == nil {
+		t.Error("Load() should fail for a missing snapshot")
+	}
+}
+
+// TestDiff tests detection of added, removed and modified files
+func TestDiff(t *testing.T) {
+	storagePath := t.TempDir()
+
+	m := manifest.New()
+	m.AddFile("zsh", "~", ".zshrc")
+	m.AddFile("nvim", "~/.config/nvim", "init.lua")
+	m.AddFile("nvim", "~/.config/nvim", "lua/plugins.lua")
+	writeCloudFile(t, storagePath, "zsh", ".zshrc", "export A=1")
+	writeCloudFile(t, storagePath, "nvim", "init.lua", "vim.o.number = true")
+	writeCloudFile(t, storagePath, "nvim", "lua/plugins.lua", "return {}")
+
+	s, err := Take("before", storagePath, m)
+	if err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	// Modify one file, remove one, add one
+	writeCloudFile(t, storagePath, "zsh", ".zshrc", "export A=2")
+	m.RemoveFile("nvim", "lua/plugins.lua")
+	m.AddFile("git", "~", ".gitconfig")
+	writeCloudFile(t, storagePath, "git", ".gitconfig", "[user]")
+
+	changes, err := s.Diff(storagePath, m)
+	if err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+
+	want := []Change{
+		{File: "git/.gitconfig", Kind: ChangeAdded},
+		{File: "nvim/lua/plugins.lua", Kind: ChangeRemoved},
+		{File: "zsh/.zshrc", Kind: ChangeModified},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("changes[%d] = %v, want %v", i, changes[i], want[i])
+		}
+	}
+}
+
+// TestDiff_NoChanges tests that an unchanged tree reports nothing
+func TestDiff_NoChanges(t *testing.T) {
+	storagePath := t.TempDir()
+
+	m := manifest.New()
+	m.AddFile("zsh", "~", ".zshrc")
+	writeCloudFile(t, storagePath, "zsh", ".zshrc", "export A=1")
+
+	s, err := Take("now", storagePath, m)
+	if err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	changes, err := s.Diff(storagePath, m)
+	if err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes = %v, want none", changes)
+	}
+}
+
+// TestQuickDiff tests that size and mtime changes are detected without
+// hashing
+func TestQuickDiff(t *testing.T) {
+	storagePath := t.TempDir()
+
+	m := manifest.New()
+	m.AddFile("zsh", "~", ".zshrc")
+	m.AddFile("git", "~", ".gitconfig")
+	m.AddFile("nvim", "~/.config/nvim", "init.lua")
+	writeCloudFile(t, storagePath, "zsh", ".zshrc", "export A=1")
+	writeCloudFile(t, storagePath, "git", ".gitconfig", "[user]")
+	writeCloudFile(t, storagePath, "nvim", "init.lua", "vim.o.number = true")
+
+	s, err := Take("before", storagePath, m)
+	if err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	changes, err := s.QuickDiff(storagePath, m)
+	if err != nil {
+		t.Fatalf("QuickDiff() failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("changes = %v, want none", changes)
+	}
+
+	// Grow one file, and rewrite another with the same size but a new mtime
+	writeCloudFile(t, storagePath, "zsh", ".zshrc", "export A=10")
+	gitPath := filepath.Join(storagePath, "dotsync", "git", ".gitconfig")
+	later := s.Stats["git/.gitconfig"].ModTime.Add(time.Hour)
+	if err := os.Chtimes(gitPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err = s.QuickDiff(storagePath, m)
+	if err != nil {
+		t.Fatalf("QuickDiff() failed: %v", err)
+	}
+	want := []Change{
+		{File: "git/.gitconfig", Kind: ChangeModified},
+		{File: "zsh/.zshrc", Kind: ChangeModified},
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+}
+
+// TestQuickDiff_NoStats tests that snapshots saved without sizes and times
+// can't be compared quickly
+func TestQuickDiff_NoStats(t *testing.T) {
+	storagePath := t.TempDir()
+
+	m := manifest.New()
+	m.AddFile("zsh", "~", ".zshrc")
+	writeCloudFile(t, storagePath, "zsh", ".zshrc", "export A=1")
+
+	s, err := Take("old", storagePath, m)
+	if err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+	s.Stats = nil
+
+	if _, err := s.QuickDiff(storagePath, m); err == nil {
+		t.Error("QuickDiff() should fail without recorded stats")
+	}
+}
@@ -1,16 +1,27 @@
 package storage
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/wtfzambo/dotsync/internal/pathutil"
 )
 
 // DetectPath attempts to find the cloud storage path for a provider.
+// If the provider's environment variable (see PathEnvVar) is set, only
+// that path is checked; otherwise the known paths for the current platform.
 // Returns the detected path or empty string if not found.
 func DetectPath(provider Provider) string {
+	if override := os.Getenv(PathEnvVar(provider)); override != "" {
+		// Don't fall back to a known path the user chose not to use
+		return findPath(override)
+	}
+
 	paths := KnownPaths()
 	if paths == nil {
 		return ""
@@ -36,6 +47,88 @@ func DetectPath(provider Provider) string {
 	return ""
 }
 
+// DetectTimeout is how long detection waits for a provider's paths to be
+// checked. A stat on a disconnected network mount can block far longer.
+const DetectTimeout = 3 * time.Second
+
+// ErrDetectTimeout is returned when a provider's paths can't be checked in time.
+var ErrDetectTimeout = errors.New("detection timed out")
+
+// detectPath checks a provider's paths. Replaced in tests to simulate a slow mount.
+var detectPath = DetectPath
+
+// DetectPathTimeout is DetectPath with a deadline. Returns ErrDetectTimeout
+// if the check doesn't finish within timeout; the check itself keeps
+// running in the background, since a blocked stat can't be cancelled.
+func DetectPathTimeout(provider Provider, timeout time.Duration) (string, error) {
+	found := make(chan string, 1)
+	go func() {
+		found <- detectPath(provider)
+	}()
+
+	select {
+	case path := <-found:
+		return path, nil
+	case <-time.After(timeout):
+		return "", ErrDetectTimeout
+	}
+}
+
+// Detected is a cloud storage provider found on this machine.
+type Detected struct {
+	Provider Provider
+	Path     string
+}
+
+// DetectAll returns every supported provider found on this machine,
+// sorted by provider name so the order is stable. Providers are checked
+// concurrently, each with the given timeout; those that time out are
+// returned separately.
+func DetectAll(timeout time.Duration) (found []Detected, timedOut []Provider) {
+	providers := SupportedProviders()
+	sort.Slice(providers, func(i, j int) bool { return providers[i] < providers[j] })
+
+	paths := make([]string, len(providers))
+	errs := make([]error, len(providers))
+	done := make(chan struct{})
+	for i, p := range providers {
+		go func() {
+			paths[i], errs[i] = DetectPathTimeout(p, timeout)
+			done <- struct{}{}
+		}()
+	}
+	for range providers {
+		<-done
+	}
+
+	for i, p := range providers {
+		switch {
+		case errs[i] != nil:
+			timedOut = append(timedOut, p)
+		case paths[i] != "":
+			found = append(found, Detected{Provider: p, Path: paths[i]})
+		}
+	}
+	return found, timedOut
+}
+
+// PathEnvVar returns the environment variable that overrides the detected
+// path of a provider: DOTSYNC_<PROVIDER>_PATH, e.g. DOTSYNC_GDRIVE_PATH.
+func PathEnvVar(provider Provider) string {
+	return "DOTSYNC_" + strings.ToUpper(string(provider)) + "_PATH"
+}
+
+// CheckPathEnv returns an error if the provider's environment variable
+// (see PathEnvVar) is set to a path that doesn't exist. DetectPath finds
+// nothing for the provider then.
+func CheckPathEnv(provider Provider) error {
+	override := os.Getenv(PathEnvVar(provider))
+	if override == "" || findPath(override) != "" {
+		return nil
+	}
+	return fmt.Errorf("%s is set to %s, which doesn't exist", PathEnvVar(provider), override)
+}
+
 // findPath expands and checks if a path exists.
 // Supports glob patterns and ~ expansion.
 func findPath(pattern string) string {
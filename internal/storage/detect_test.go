@@ -1,10 +1,13 @@
 package storage
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestDetectPath tests cloud storage path detection
@@ -31,6 +34,141 @@ func TestDetectPath(t *testing.T) {
 	}
 }
 
+// TestDetectPath_EnvOverride tests that DOTSYNC_<PROVIDER>_PATH wins over
+// the known paths, and is ignored when it doesn't point to a directory
+func TestDetectPath_EnvOverride(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	t.Setenv("DOTSYNC_GDRIVE_PATH", tmpDir)
+	if got := DetectPath(ProviderGoogleDrive); got != tmpDir {
+		t.Errorf("DetectPath() = %q, want %q", got, tmpDir)
+	}
+
+	// Providers without known paths can be overridden too
+	t.Setenv("DOTSYNC_NONEXISTENT_PATH", tmpDir)
+	if got := DetectPath("nonexistent"); got != tmpDir {
+		t.Errorf("DetectPath(nonexistent) = %q, want %q", got, tmpDir)
+	}
+
+	missing := filepath.Join(tmpDir, "missing")
+	t.Setenv("DOTSYNC_NONEXISTENT_PATH", missing)
+	if got := DetectPath("nonexistent"); got != "" {
+		t.Errorf("DetectPath() = %q, want empty for a missing directory", got)
+	}
+	if err := CheckPathEnv("nonexistent"); err == nil || !strings.Contains(err.Error(), "DOTSYNC_NONEXISTENT_PATH") {
+		t.Errorf("CheckPathEnv() = %v, want an error naming the variable", err)
+	}
+
+	// A missing override doesn't fall back to the known paths
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, "Dropbox"), 0755)
+	t.Setenv("DOTSYNC_DROPBOX_PATH", "")
+	if DetectPath(ProviderDropbox) == "" {
+		t.Skip("Dropbox isn't found in ~/Dropbox on this platform")
+	}
+	t.Setenv("DOTSYNC_DROPBOX_PATH", missing)
+	if got := DetectPath(ProviderDropbox); got != "" {
+		t.Errorf("DetectPath() = %q, want no fallback for a missing override", got)
+	}
+	if err := CheckPathEnv(ProviderDropbox); err == nil {
+		t.Error("CheckPathEnv() should report the missing override")
+	}
+}
+
+// TestDetectAll tests that every provider found is returned in a stable order
+func TestDetectAll(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	gdrive, dropbox := t.TempDir(), t.TempDir()
+	t.Setenv("DOTSYNC_GDRIVE_PATH", gdrive)
+	t.Setenv("DOTSYNC_DROPBOX_PATH", dropbox)
+	t.Setenv("DOTSYNC_ICLOUD_PATH", "")
+
+	supported := map[Provider]bool{}
+	for _, p := range SupportedProviders() {
+		supported[p] = true
+	}
+	if !supported[ProviderGoogleDrive] || !supported[ProviderDropbox] {
+		t.Skip("Google Drive and Dropbox are not both supported on this platform")
+	}
+
+	found, timedOut := DetectAll(DetectTimeout)
+	if len(timedOut) > 0 {
+		t.Errorf("DetectAll() timed out for %v", timedOut)
+	}
+	var got []Detected
+	for _, d := range found {
+		if d.Provider != ProviderICloud {
+			got = append(got, d)
+		}
+	}
+	want := []Detected{{ProviderDropbox, dropbox}, {ProviderGoogleDrive, gdrive}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("DetectAll() = %v, want %v", found, want)
+	}
+}
+
+// TestDetectPathTimeout tests that a slow path check gives up after the timeout
+func TestDetectPathTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	orig := detectPath
+	t.Cleanup(func() { detectPath = orig })
+	detectPath = func(p Provider) string {
+		if p == ProviderGoogleDrive {
+			// Simulate a stat blocked on an unresponsive network mount
+			<-release
+		}
+		return "/mnt/" + string(p)
+	}
+
+	start := time.Now()
+	if _, err := DetectPathTimeout(ProviderGoogleDrive, 50*time.Millisecond); !errors.Is(err, ErrDetectTimeout) {
+		t.Errorf("DetectPathTimeout() error = %v, want ErrDetectTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DetectPathTimeout() took %v, should give up after the timeout", elapsed)
+	}
+
+	if got, err := DetectPathTimeout(ProviderDropbox, time.Second); err != nil || got != "/mnt/dropbox" {
+		t.Errorf("DetectPathTimeout() = %q, %v, want %q", got, err, "/mnt/dropbox")
+	}
+
+	found, timedOut := DetectAll(50 * time.Millisecond)
+	if len(found)+len(timedOut) != len(SupportedProviders()) {
+		t.Errorf("DetectAll() = %v, %v, want every supported provider", found, timedOut)
+	}
+	for _, d := range found {
+		if d.Provider == ProviderGoogleDrive {
+			t.Error("DetectAll() should not report a provider that timed out")
+		}
+	}
+	for _, p := range timedOut {
+		if p != ProviderGoogleDrive {
+			t.Errorf("DetectAll() timedOut = %v, want only gdrive", timedOut)
+		}
+	}
+}
+
+// TestPathEnvVar tests the environment variable naming convention
+func TestPathEnvVar(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		want     string
+	}{
+		{ProviderGoogleDrive, "DOTSYNC_GDRIVE_PATH"},
+		{ProviderDropbox, "DOTSYNC_DROPBOX_PATH"},
+		{ProviderICloud, "DOTSYNC_ICLOUD_PATH"},
+	}
+
+	for _, tt := range tests {
+		if got := PathEnvVar(tt.provider); got != tt.want {
+			t.Errorf("PathEnvVar(%q) = %q, want %q", tt.provider, got, tt.want)
+		}
+	}
+}
+
 // TestFindPath tests path finding with various patterns
 func TestFindPath(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitRepo returns the git repository holding dir, looking for a .git entry
+// in dir and its parents up to and including stopAt. Returns "" if there is
+// none, e.g. storage synced by a cloud provider instead of git.
+func GitRepo(dir, stopAt string) string {
+	stopAt = filepath.Clean(stopAt)
+	for dir = filepath.Clean(dir); ; dir = filepath.Dir(dir) {
+		// .git is a file in worktrees and submodules
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if dir == stopAt || filepath.Dir(dir) == dir {
+			return ""
+		}
+	}
+}
+
+// GitAdd stages paths in the git repository at repo.
+func GitAdd(repo string, paths ...string) error {
+	args := append([]string{"-C", repo, "add", "--"}, paths...)
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git add: %w: %s", err, msg)
+		}
+		return fmt.Errorf("git add: %w", err)
+	}
+	return nil
+}
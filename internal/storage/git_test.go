@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGitRepo tests finding the repository holding the dotsync directory
+func TestGitRepo(t *testing.T) {
+	storagePath := t.TempDir()
+	dotsyncDir := filepath.Join(storagePath, "dotsync", "alice")
+	if err := os.MkdirAll(dotsyncDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := GitRepo(dotsyncDir, storagePath); got != "" {
+		t.Errorf("GitRepo() without .git = %q, want empty", got)
+	}
+
+	// A repository above storage is not considered
+	if err := os.Mkdir(filepath.Join(filepath.Dir(storagePath), ".git"), 0755); err == nil {
+		defer os.Remove(filepath.Join(filepath.Dir(storagePath), ".git"))
+		if got := GitRepo(dotsyncDir, storagePath); got != "" {
+			t.Errorf("GitRepo() with .git above storage = %q, want empty", got)
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(storagePath, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := GitRepo(dotsyncDir, storagePath); got != storagePath {
+		t.Errorf("GitRepo() = %q, want %q", got, storagePath)
+	}
+
+	// .git is a file in worktrees and submodules
+	if err := os.WriteFile(filepath.Join(dotsyncDir, ".git"), []byte("gitdir: elsewhere\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := GitRepo(dotsyncDir, storagePath); got != dotsyncDir {
+		t.Errorf("GitRepo() with .git file = %q, want %q", got, dotsyncDir)
+	}
+}
+
+// TestGitAdd tests staging files in a repository
+func TestGitAdd(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "-C", repo, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	file := filepath.Join(repo, "dotsync", "app", "config")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GitAdd(repo, file); err != nil {
+		t.Fatalf("GitAdd() error = %v", err)
+	}
+	out, err := exec.Command("git", "-C", repo, "diff", "--cached", "--name-only").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "dotsync/app/config" {
+		t.Errorf("staged = %q, want dotsync/app/config", got)
+	}
+
+	if err := GitAdd(repo, filepath.Join(repo, "missing")); err == nil {
+		t.Error("GitAdd() of a missing file should fail")
+	}
+}
@@ -4,10 +4,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/wtfzambo/dotsync/internal/manifest"
 	"github.com/wtfzambo/dotsync/internal/pathutil"
 )
 
+// writeTestPattern names the temp file ValidatePath writes to check that
+// storage is writable.
+const writeTestPattern = ".dotsync-write-*"
+
 // ValidatePath checks if a path exists and is writable.
 func ValidatePath(path string) error {
 	// Expand home directory
@@ -28,25 +35,102 @@ func ValidatePath(path string) error {
 		return fmt.Errorf("path is not a directory: %s", path)
 	}
 
-	// Check if writable by attempting to create a temp file
-	testFile := filepath.Join(expanded, ".dotsync-write-test")
-	f, err := os.Create(testFile)
+	// Check if writable by attempting to create a temp file. The name is
+	// unique so concurrent runs (or machines) don't remove each other's file
+	f, err := os.CreateTemp(expanded, writeTestPattern)
 	if err != nil {
 		return fmt.Errorf("cannot write to storage path: %s", path)
 	}
-	f.Close()
-	os.Remove(testFile)
+	defer os.Remove(f.Name())
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("cannot write to storage path: %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// dataDirs are hidden directories under home that hold application data
+// and caches rather than config, e.g. ~/.local/share/<provider> used by
+// some cloud clients on Linux. Storage may live inside them.
+var dataDirs = []string{".local", ".cache"}
+
+// CheckLocation checks that a storage path doesn't overlap the places where
+// tracked config files live: the home directory itself (or an ancestor of it),
+// hidden directories under home like ~/.config (except dataDirs), and macOS
+// app config folders. Storage in those locations could end up managing itself.
+func CheckLocation(path string) error {
+	abs, err := filepath.Abs(ExpandPath(path))
+	if err != nil {
+		return fmt.Errorf("resolving path: %w", err)
+	}
+
+	home, err := pathutil.HomeDir()
+	if err != nil {
+		return fmt.Errorf("getting home directory: %w", err)
+	}
+
+	if isWithin(home, abs) {
+		return fmt.Errorf("storage path %s contains the home directory", path)
+	}
+
+	if !isWithin(abs, home) {
+		return nil
+	}
+
+	rel, err := pathutil.SafeRel(home, abs)
+	if err != nil {
+		return nil
+	}
+	parts := strings.Split(rel, "/")
+
+	if strings.HasPrefix(parts[0], ".") && !slices.Contains(dataDirs, parts[0]) {
+		return fmt.Errorf("storage path %s is inside ~/%s, where tracked config files live", path, parts[0])
+	}
+
+	if len(parts) >= 2 && parts[0] == "Library" && (parts[1] == "Application Support" || parts[1] == "Preferences") {
+		return fmt.Errorf("storage path %s is inside ~/Library/%s, where tracked config files live", path, parts[1])
+	}
+
+	return nil
+}
+
+// CheckRootOverlap checks that a storage path is neither inside nor a parent
+// of any entry root. Roots may use ~ for the home directory.
+func CheckRootOverlap(path string, roots []string) error {
+	abs, err := filepath.Abs(ExpandPath(path))
+	if err != nil {
+		return fmt.Errorf("resolving path: %w", err)
+	}
+
+	for _, root := range roots {
+		expandedRoot := filepath.Clean(pathutil.ExpandHome(root))
+		if isWithin(abs, expandedRoot) || isWithin(expandedRoot, abs) {
+			return fmt.Errorf("storage path %s overlaps entry root %s", path, root)
+		}
+	}
 
 	return nil
 }
 
-// EnsureDotsyncDir ensures the dotsync directory exists within the storage path.
+// isWithin returns true if path is dir or is under dir.
+func isWithin(path, dir string) bool {
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
+
+// EnsureDotsyncDir ensures the dotsync directory for the given storage
+// subpath ("" for none) exists within the storage path.
 // Returns the full path to the dotsync directory.
-func EnsureDotsyncDir(storagePath string) (string, error) {
+func EnsureDotsyncDir(storagePath, subpath string) (string, error) {
 	expanded := pathutil.ExpandHome(storagePath)
 	expanded = os.ExpandEnv(expanded)
 
-	dotsyncDir := filepath.Join(expanded, "dotsync")
+	dotsyncDir := manifest.DotsyncDir(expanded, subpath)
 	if err := os.MkdirAll(dotsyncDir, 0755); err != nil {
 		return "", fmt.Errorf("creating dotsync directory: %w", err)
 	}
@@ -63,11 +147,26 @@ func IsAvailable(storagePath string) bool {
 	return err == nil
 }
 
-// DotsyncDir returns the full path to the dotsync directory within storage.
-func DotsyncDir(storagePath string) string {
+// IsPopulated checks if the storage path holds a dotsync folder, which
+// every storage gets at init. An available storage path without one is
+// usually an empty mount point (the drive or share isn't mounted) or a
+// provider folder that hasn't synced yet, not storage with nothing in it.
+// The folder shared by every subpath is checked, so a machine that hasn't
+// added anything under its own subpath still counts as populated.
+func IsPopulated(storagePath string) bool {
+	expanded := pathutil.ExpandHome(storagePath)
+	expanded = os.ExpandEnv(expanded)
+
+	info, err := os.Stat(filepath.Join(expanded, "dotsync"))
+	return err == nil && info.IsDir()
+}
+
+// DotsyncDir returns the full path to the dotsync directory for the given
+// storage subpath within storage.
+func DotsyncDir(storagePath, subpath string) string {
 	expanded := pathutil.ExpandHome(storagePath)
 	expanded = os.ExpandEnv(expanded)
-	return filepath.Join(expanded, "dotsync")
+	return manifest.DotsyncDir(expanded, subpath)
 }
 
 // ExpandPath expands ~ and environment variables in a path.
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"testing"
 )
 
@@ -111,7 +112,7 @@ func TestValidatePath_WithTilde(t *testing.T) {
 func TestEnsureDotsyncDir(t *testing.T) {
 	tmpDir := t.TempDir()
 
-	dotsyncDir, err := EnsureDotsyncDir(tmpDir)
+	dotsyncDir, err := EnsureDotsyncDir(tmpDir, "")
 	if err != nil {
 		t.Fatalf("EnsureDotsyncDir() failed: %v", err)
 	}
@@ -147,7 +148,7 @@ func TestEnsureDotsyncDir_AlreadyExists(t *testing.T) {
 	}
 
 	// Should succeed even if already exists
-	result, err := EnsureDotsyncDir(tmpDir)
+	result, err := EnsureDotsyncDir(tmpDir, "")
 	if err != nil {
 		t.Errorf("EnsureDotsyncDir() failed: %v", err)
 	}
@@ -205,7 +206,7 @@ func TestDotsyncDir(t *testing.T) {
 	storagePath := "/path/to/storage"
 	expected := filepath.Join(storagePath, "dotsync")
 
-	got := DotsyncDir(storagePath)
+	got := DotsyncDir(storagePath, "")
 	if got != expected {
 		t.Errorf("DotsyncDir() = %q, want %q", got, expected)
 	}
@@ -221,7 +222,7 @@ func TestDotsyncDir_WithTilde(t *testing.T) {
 	storagePath := "~/storage"
 	expected := filepath.Join(home, "storage", "dotsync")
 
-	got := DotsyncDir(storagePath)
+	got := DotsyncDir(storagePath, "")
 	if got != expected {
 		t.Errorf("DotsyncDir() = %q, want %q", got, expected)
 	}
@@ -282,9 +283,35 @@ func TestValidatePath_WritableCheck(t *testing.T) {
 	}
 
 	// Verify test file was cleaned up
-	testFile := filepath.Join(writableDir, ".dotsync-write-test")
-	if _, err := os.Stat(testFile); !os.IsNotExist(err) {
-		t.Error("test file was not cleaned up")
+	if left, _ := filepath.Glob(filepath.Join(writableDir, writeTestPattern)); len(left) > 0 {
+		t.Errorf("test files were not cleaned up: %v", left)
+	}
+}
+
+// TestValidatePath_Concurrent tests that concurrent runs on the same
+// directory don't remove each other's test file
+func TestValidatePath_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- ValidatePath(dir)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("ValidatePath() failed: %v", err)
+		}
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, writeTestPattern)); len(left) > 0 {
+		t.Errorf("test files were not cleaned up: %v", left)
 	}
 }
 
@@ -302,7 +329,7 @@ func TestEnsureDotsyncDir_WithEnvVars(t *testing.T) {
 	os.MkdirAll(expandedPath, 0755)
 
 	// This should expand the env var
-	result, err := EnsureDotsyncDir(testPath)
+	result, err := EnsureDotsyncDir(testPath, "")
 	if err != nil {
 		t.Errorf("EnsureDotsyncDir() failed: %v", err)
 	}
@@ -312,3 +339,96 @@ func TestEnsureDotsyncDir_WithEnvVars(t *testing.T) {
 		t.Errorf("EnsureDotsyncDir() = %q, want %q", result, expected)
 	}
 }
+
+// TestCheckLocation tests detection of storage paths overlapping config locations
+func TestCheckLocation(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("failed to get home dir: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"home directory", home, true},
+		{"home with tilde", "~", true},
+		{"ancestor of home", filepath.Dir(home), true},
+		{"inside ~/.config", filepath.Join(home, ".config", "cloud"), true},
+		{"inside hidden dir", "~/.dotfiles/storage", true},
+		{"inside ~/.ssh", filepath.Join(home, ".ssh", "cloud"), true},
+		{"inside ~/.local/share", filepath.Join(home, ".local", "share", "pcloud"), false},
+		{"inside ~/.cache", filepath.Join(home, ".cache", "cloud"), false},
+		{"inside Application Support", filepath.Join(home, "Library", "Application Support", "cloud"), true},
+		{"regular cloud folder", filepath.Join(home, "Dropbox"), false},
+		{"macOS CloudStorage", filepath.Join(home, "Library", "CloudStorage", "GoogleDrive-x", "My Drive"), false},
+		{"outside home", filepath.Join(t.TempDir(), "storage"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckLocation(tt.path)
+			if tt.wantErr && err == nil {
+				t.Error("CheckLocation() should return error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("CheckLocation() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestCheckRootOverlap tests detection of storage paths overlapping entry roots
+func TestCheckRootOverlap(t *testing.T) {
+	tmpDir := t.TempDir()
+	roots := []string{filepath.Join(tmpDir, "app"), "~/.config/opencode"}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"inside entry root", filepath.Join(tmpDir, "app", "storage"), true},
+		{"same as entry root", filepath.Join(tmpDir, "app"), true},
+		{"parent of entry root", tmpDir, true},
+		{"parent of tilde root", "~/.config", true},
+		{"sibling with shared prefix", filepath.Join(tmpDir, "app-storage"), false},
+		{"unrelated", filepath.Join(tmpDir, "storage"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRootOverlap(tt.path, roots)
+			if tt.wantErr && err == nil {
+				t.Error("CheckRootOverlap() should return error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("CheckRootOverlap() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestIsPopulated tests telling an empty mount point from initialized storage
+func TestIsPopulated(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if IsPopulated(filepath.Join(tmpDir, "nonexistent")) {
+		t.Error("IsPopulated() = true for a missing path")
+	}
+	if IsPopulated(tmpDir) {
+		t.Error("IsPopulated() = true for an empty directory")
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "dotsync"), []byte("not a dir"), 0644)
+	if IsPopulated(tmpDir) {
+		t.Error("IsPopulated() = true when dotsync is a file")
+	}
+	os.Remove(filepath.Join(tmpDir, "dotsync"))
+
+	os.Mkdir(filepath.Join(tmpDir, "dotsync"), 0755)
+	if !IsPopulated(tmpDir) {
+		t.Error("IsPopulated() = false with a dotsync folder")
+	}
+}
@@ -0,0 +1,66 @@
+package symlink
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// PermRule sets the mode of sensitive files. Pattern is a path.Match
+// pattern on the path relative to home, with forward slashes (e.g.
+// ".ssh/*"). A pattern matching a directory also covers everything in it.
+type PermRule struct {
+	Pattern string
+	Mode    os.FileMode
+}
+
+// DefaultPermRules are the modes tools like ssh and gpg insist on. The
+// first matching rule wins, so specific patterns come first.
+var DefaultPermRules = []PermRule{
+	{Pattern: ".ssh/*.pub", Mode: 0644},
+	{Pattern: ".ssh/known_hosts", Mode: 0644},
+	{Pattern: ".ssh/*", Mode: 0600},
+	{Pattern: ".gnupg/*", Mode: 0600},
+	{Pattern: ".aws/credentials", Mode: 0600},
+	{Pattern: ".netrc", Mode: 0600},
+	{Pattern: ".pgpass", Mode: 0600},
+}
+
+// PrivateDirMode is the mode of private directories like ~/.ssh.
+const PrivateDirMode os.FileMode = 0700
+
+// WantedMode returns the mode of the first rule matching homeRel, a path
+// relative to home. Returns false if no rule matches.
+func WantedMode(homeRel string, rules []PermRule) (os.FileMode, bool) {
+	homeRel = filepath.ToSlash(homeRel)
+	for _, r := range rules {
+		if matchPathOrParent(r.Pattern, homeRel) {
+			return r.Mode, true
+		}
+	}
+	return 0, false
+}
+
+// matchPathOrParent reports whether pattern matches p or one of its parent
+// directories.
+func matchPathOrParent(pattern, p string) bool {
+	for ; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PrivateDir returns the private directory (like ~/.ssh) containing p, or
+// "" if p isn't inside one.
+func PrivateDir(p string) string {
+	for dir := filepath.Dir(filepath.Clean(p)); ; dir = filepath.Dir(dir) {
+		if privateDirs[filepath.Base(dir)] {
+			return dir
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package symlink
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWantedMode tests picking the mode of sensitive files
+func TestWantedMode(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   os.FileMode
+		wantOK bool
+	}{
+		{".ssh/id_ed25519", 0600, true},
+		{".ssh/id_ed25519.pub", 0644, true},
+		{".ssh/known_hosts", 0644, true},
+		{".gnupg/private-keys-v1.d/key.key", 0600, true},
+		{".aws/credentials", 0600, true},
+		{".aws/config", 0, false},
+		{".zshrc", 0, false},
+		{filepath.Join(".ssh", "config"), 0600, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := WantedMode(tt.path, DefaultPermRules)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("WantedMode(%q) = %04o, %v, want %04o, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// Overrides listed first win
+	rules := append([]PermRule{{Pattern: ".ssh/config", Mode: 0644}}, DefaultPermRules...)
+	if got, _ := WantedMode(".ssh/config", rules); got != 0644 {
+		t.Errorf("WantedMode() with override = %04o, want 0644", got)
+	}
+}
+
+// TestPrivateDir tests finding the private directory around a path
+func TestPrivateDir(t *testing.T) {
+	home := filepath.Join("/home", "user")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(home, ".ssh", "id_ed25519"), filepath.Join(home, ".ssh")},
+		{filepath.Join(home, ".gnupg", "private-keys-v1.d", "a.key"), filepath.Join(home, ".gnupg")},
+		{filepath.Join(home, ".config", "app", "config.json"), ""},
+	}
+
+	for _, tt := range tests {
+		if got := PrivateDir(tt.path); got != tt.want {
+			t.Errorf("PrivateDir(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
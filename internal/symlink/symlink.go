@@ -2,26 +2,163 @@
 package symlink
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// ErrNoSymlinkPrivilege is returned by Create when the user isn't allowed to
+// create symlinks, i.e. on Windows without Developer Mode or Administrator.
+var ErrNoSymlinkPrivilege = errors.New("not allowed to create symlinks")
+
+// ErrSymlinkUnsupported is returned by Create when the filesystem doesn't
+// support symlinks, e.g. FAT drives and some network mounts.
+var ErrSymlinkUnsupported = errors.New("filesystem doesn't support symlinks")
+
+// ErrBrokenParentSymlink is returned by Create and MoveFile when a parent
+// directory of the path is a symlink whose target doesn't exist, e.g.
+// ~/.config pointing into an unmounted drive.
+var ErrBrokenParentSymlink = errors.New("parent directory is a broken symlink")
+
+// ErrLinkLoop is returned by CheckLoop when an original path and its cloud
+// copy overlap, so the symlink would point at itself or into itself.
+var ErrLinkLoop = errors.New("original and cloud paths overlap")
+
+// errorPrivilegeNotHeld is the Windows ERROR_PRIVILEGE_NOT_HELD error code.
+// It's defined here because the syscall constant only exists on Windows.
+const errorPrivilegeNotHeld = syscall.Errno(1314)
+
+// isPrivilegeError reports whether err is the Windows error returned when
+// creating a symlink without the required privilege.
+func isPrivilegeError(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && errno == errorPrivilegeNotHeld
+}
+
+// isUnsupportedError reports whether err is returned when creating a symlink
+// on a filesystem without symlink support. Linux reports EPERM for these,
+// other systems and FUSE mounts ENOTSUP or ENOSYS.
+func isUnsupportedError(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	return errno == syscall.EPERM || errno == syscall.ENOTSUP || errno == syscall.EOPNOTSUPP || errno == syscall.ENOSYS
+}
+
+// privateDirs are directories that tools expect to be accessible only by
+// their owner. ssh, for example, refuses keys in a group-readable ~/.ssh.
+var privateDirs = map[string]bool{
+	".ssh":   true,
+	".gnupg": true,
+}
+
+// IsPrivatePath returns true if path is inside a private directory like
+// ~/.ssh, where files should only be accessible by their owner.
+func IsPrivatePath(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(filepath.Clean(path)), "/") {
+		if privateDirs[part] {
+			return true
+		}
+	}
+	return false
+}
+
+// DirMode returns the permissions a missing directory is created with:
+// 0700 inside a private directory like ~/.ssh, 0755 otherwise.
+func DirMode(dir string) os.FileMode {
+	if IsPrivatePath(dir) {
+		return 0700
+	}
+	return 0755
+}
+
+// mkdirParents creates dir and any missing parents like os.MkdirAll, but
+// picks the permissions of each created directory with DirMode so that a
+// removed ~/.ssh is recreated as 0700 instead of 0755.
+//
+// Parents that are symlinks to directories (e.g. ~/.config linked into a
+// dotfiles repo) are followed, so directories are created in the target.
+// A parent that is a broken symlink returns ErrBrokenParentSymlink rather
+// than creating anything through it.
+func mkdirParents(dir string) error {
+	return mkdirParentsMode(dir, 0)
+}
+
+// mkdirParentsMode is mkdirParents, but creates dir itself with mode if it
+// isn't 0. The mode is set exactly, regardless of the umask, since it is
+// meant to restore a directory as it was.
+func mkdirParentsMode(dir string, mode os.FileMode) error {
+	dir = filepath.Clean(dir)
+	if info, err := os.Stat(dir); err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("not a directory: %s", dir)
+		}
+		return nil
+	}
+
+	if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		target, _ := os.Readlink(dir)
+		return fmt.Errorf("%w: %s -> %s", ErrBrokenParentSymlink, dir, target)
+	}
+
+	if parent := filepath.Dir(dir); parent != dir {
+		if err := mkdirParents(parent); err != nil {
+			return err
+		}
+	}
+
+	exact := mode != 0
+	if !exact {
+		mode = DirMode(dir)
+	}
+	if err := os.Mkdir(dir, mode); err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+	if exact {
+		return os.Chmod(dir, mode)
+	}
+	return nil
+}
+
 // Create creates a symlink at linkPath pointing to targetPath.
 // Creates parent directories if needed.
+// Returns an error matching ErrNoSymlinkPrivilege if the user isn't allowed
+// to create symlinks, or ErrSymlinkUnsupported if the filesystem can't hold
+// them.
 func Create(linkPath, targetPath string) error {
+	return CreateWithParentMode(linkPath, targetPath, 0)
+}
+
+// CreateWithParentMode is Create, but a missing parent directory of
+// linkPath is created with parentMode, e.g. the mode it had when the file
+// was added. Directories further up get DirMode, and an existing parent is
+// left alone. A parentMode of 0 behaves like Create.
+func CreateWithParentMode(linkPath, targetPath string, parentMode os.FileMode) error {
 	// Ensure parent directory exists
 	parentDir := filepath.Dir(linkPath)
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
+	if err := mkdirParentsMode(parentDir, parentMode); err != nil {
 		return fmt.Errorf("creating parent directory: %w", err)
 	}
 
 	// Create the symlink
-	if err := os.Symlink(targetPath, linkPath); err != nil {
-		if runtime.GOOS == "windows" {
-			return fmt.Errorf("creating symlink: %w\n\nWindows 10/11 requires Developer Mode or Administrator privileges to create symlinks.\nPlease enable Developer Mode in Settings > Privacy & Security > Developer Mode,\nor run this command as Administrator", err)
+	if err := symlinkFunc(targetPath, linkPath); err != nil {
+		if isPrivilegeError(err) {
+			return fmt.Errorf("creating symlink: %w: %w\n\nWindows 10/11 requires Developer Mode or Administrator privileges to create symlinks.\nPlease enable Developer Mode in Settings > Privacy & Security > Developer Mode,\nor run this command as Administrator,\nor use --windows-fallback to keep copies instead", ErrNoSymlinkPrivilege, err)
+		}
+		if isUnsupportedError(err) {
+			return fmt.Errorf("creating symlink: %w: %w", ErrSymlinkUnsupported, err)
 		}
 		return fmt.Errorf("creating symlink: %w", err)
 	}
@@ -68,11 +205,12 @@ func ReadTarget(linkPath string) (string, error) {
 type Status int
 
 const (
-	StatusNotExist  Status = iota // Path doesn't exist
-	StatusLinked                  // Symlink exists and points to correct target
-	StatusBroken                  // Symlink exists but target is missing
-	StatusIncorrect               // Symlink exists but points to wrong target
-	StatusNotLinked               // Regular file exists (not a symlink)
+	StatusNotExist      Status = iota // Path doesn't exist
+	StatusLinked                      // Symlink exists and points to correct target
+	StatusBroken                      // Symlink exists but target is missing
+	StatusIncorrect                   // Symlink exists but points to wrong target
+	StatusNotLinked                   // Regular file exists (not a symlink)
+	StatusParentMissing               // Path doesn't exist, and neither does its parent directory
 )
 
 // String returns a human-readable status.
@@ -88,16 +226,23 @@ func (s Status) String() string {
 		return "incorrect"
 	case StatusNotLinked:
 		return "not linked"
+	case StatusParentMissing:
+		return "parent missing"
 	default:
 		return "unknown"
 	}
 }
 
 // Check checks the status of a path that should be a symlink to expectedTarget.
+// A missing path is StatusParentMissing rather than StatusNotExist when its
+// parent directory is missing too, which usually means the app isn't installed.
 func Check(linkPath, expectedTarget string) (Status, string, error) {
 	info, err := os.Lstat(linkPath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Dir(linkPath)); os.IsNotExist(err) {
+				return StatusParentMissing, "", nil
+			}
 			return StatusNotExist, "", nil
 		}
 		return 0, "", err
@@ -124,10 +269,13 @@ func Check(linkPath, expectedTarget string) (Status, string, error) {
 
 	// Check if target matches
 	if actualTarget != expectedTarget {
-		// Also try resolving to absolute paths
+		// Also try resolving to absolute paths. A relative symlink target is
+		// relative to the link's directory, not the process working directory.
 		absExpected, _ := filepath.Abs(expectedTarget)
-		absActual, _ := filepath.Abs(actualTarget)
-		if absExpected != absActual {
+		absActual := resolveTarget(linkPath, actualTarget)
+		// Finally compare canonical forms, so that a home directory reached
+		// through a symlink (e.g. /home -> /var/home) still matches.
+		if absActual != absExpected && canonicalPath(absActual) != canonicalPath(absExpected) {
 			return StatusIncorrect, actualTarget, nil
 		}
 	}
@@ -135,24 +283,168 @@ func Check(linkPath, expectedTarget string) (Status, string, error) {
 	return StatusLinked, actualTarget, nil
 }
 
+// resolveTarget returns the absolute path a symlink target refers to,
+// resolving relative targets against the link's directory.
+func resolveTarget(linkPath, target string) string {
+	if filepath.IsAbs(target) {
+		return filepath.Clean(target)
+	}
+	abs, _ := filepath.Abs(filepath.Join(filepath.Dir(linkPath), target))
+	return abs
+}
+
+// canonicalPath resolves symlinks in the directory portion of path, keeping
+// the final element as is. Returns path unchanged if it can't be resolved.
+func canonicalPath(path string) string {
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return path
+	}
+	return filepath.Join(dir, filepath.Base(path))
+}
+
+// TargetWithin reports whether the symlink at linkPath points inside dir.
+// Relative targets are resolved against the link's directory, and symlinked
+// parents on either side (e.g. /var -> /private/var) don't matter.
+func TargetWithin(linkPath, dir string) (bool, error) {
+	target, err := ReadTarget(linkPath)
+	if err != nil {
+		return false, err
+	}
+	resolved := resolveTarget(linkPath, target)
+
+	dirs := []string{filepath.Clean(dir)}
+	if r, err := filepath.EvalSymlinks(dir); err == nil && r != dirs[0] {
+		dirs = append(dirs, r)
+	}
+	for _, t := range []string{resolved, canonicalPath(resolved)} {
+		for _, d := range dirs {
+			if t == d || strings.HasPrefix(t, d+string(filepath.Separator)) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ManagedAncestor returns the first parent directory of path that is a
+// symlink pointing inside managedDir (e.g. <storage>/dotsync), or empty
+// string if there is none. Creating a link under such a directory would
+// write into cloud storage through the directory symlink.
+func ManagedAncestor(path, managedDir string) string {
+	managed := []string{filepath.Clean(managedDir)}
+	if resolved, err := filepath.EvalSymlinks(managedDir); err == nil && resolved != managed[0] {
+		managed = append(managed, resolved)
+	}
+
+	for dir := filepath.Dir(filepath.Clean(path)); ; dir = filepath.Dir(dir) {
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(dir); err == nil {
+				resolved := resolveTarget(dir, target)
+				for _, m := range managed {
+					if resolved == m || strings.HasPrefix(resolved, m+string(filepath.Separator)) {
+						return dir
+					}
+				}
+			}
+		}
+
+		if parent := filepath.Dir(dir); parent == dir {
+			return ""
+		}
+	}
+}
+
+// CheckLoop returns an error wrapping ErrLinkLoop if a symlink at
+// originalPath pointing to cloudPath would be self-referential: both are
+// the same path, one is inside the other, or originalPath is inside
+// managedDir (e.g. <storage>/dotsync). Symlinked parent directories are
+// resolved on both sides.
+func CheckLoop(originalPath, cloudPath, managedDir string) error {
+	originals := pathForms(originalPath)
+	clouds := pathForms(cloudPath)
+	managed := []string{filepath.Clean(managedDir)}
+	if resolved, err := filepath.EvalSymlinks(managedDir); err == nil && resolved != managed[0] {
+		managed = append(managed, resolved)
+	}
+
+	for _, o := range originals {
+		for _, c := range clouds {
+			switch {
+			case o == c:
+				return fmt.Errorf("%w: %s is its own cloud copy", ErrLinkLoop, originalPath)
+			case isInside(c, o):
+				return fmt.Errorf("%w: cloud copy %s is inside %s", ErrLinkLoop, cloudPath, originalPath)
+			case isInside(o, c):
+				return fmt.Errorf("%w: %s is inside its cloud copy %s", ErrLinkLoop, originalPath, cloudPath)
+			}
+		}
+		for _, m := range managed {
+			if o == m || isInside(o, m) {
+				return fmt.Errorf("%w: %s is inside dotsync storage %s", ErrLinkLoop, originalPath, managedDir)
+			}
+		}
+	}
+	return nil
+}
+
+// pathForms returns path cleaned, plus with its parent directories resolved
+// if that differs.
+func pathForms(path string) []string {
+	forms := []string{filepath.Clean(path)}
+	if c := canonicalPath(forms[0]); c != forms[0] {
+		forms = append(forms, c)
+	}
+	return forms
+}
+
+// isInside reports whether path is strictly below dir.
+func isInside(path, dir string) bool {
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// rename and copyFileFunc are variables so tests can force and break the
+// cross-filesystem fallback in MoveFile. symlinkFunc lets tests make
+// Create fail.
+var (
+	rename       = os.Rename
+	copyFileFunc = copyFile
+	symlinkFunc  = os.Symlink
+)
+
 // MoveFile moves a file from src to dst, creating parent directories if needed.
+// When src and dst are on different filesystems the file is copied, keeping
+// its modification time, and src is only removed once the copy is verified
+// to match it.
 func MoveFile(src, dst string) error {
 	// Ensure destination directory exists
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+	if err := mkdirParents(filepath.Dir(dst)); err != nil {
 		return fmt.Errorf("creating destination directory: %w", err)
 	}
 
 	// Try rename first (fastest, works on same filesystem)
-	err := os.Rename(src, dst)
+	err := rename(src, dst)
 	if err == nil {
 		return nil
 	}
 
 	// Fall back to copy + delete (for cross-filesystem moves)
-	if err := copyFile(src, dst); err != nil {
+	if err := copyFileFunc(src, dst); err != nil {
+		os.Remove(dst)
 		return err
 	}
 
+	// A short copy (e.g. disk full) must not cost the original
+	same, err := SameContent(src, dst)
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("verifying copy: %w", err)
+	}
+	if !same {
+		os.Remove(dst)
+		return fmt.Errorf("verifying copy: %s doesn't match %s, original kept", dst, src)
+	}
+
 	if err := os.Remove(src); err != nil {
 		os.Remove(dst)
 		return fmt.Errorf("removing original file: %w", err)
@@ -161,12 +453,125 @@ func MoveFile(src, dst string) error {
 	return nil
 }
 
-// CopyFile copies a file from src to dst, preserving permissions.
+// Reattach turns the regular file at originalPath into a symlink to
+// cloudPath: the file is moved to cloudPath, overwriting it, and a symlink
+// is created in its place. If the symlink can't be created the file is
+// moved back, so originalPath keeps its content. The previous cloud file
+// is not restored; callers that need it must back it up first.
+func Reattach(originalPath, cloudPath string) error {
+	info, err := os.Lstat(originalPath)
+	if err != nil {
+		return fmt.Errorf("checking file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file: %s", originalPath)
+	}
+
+	if err := MoveFile(originalPath, cloudPath); err != nil {
+		return fmt.Errorf("moving file: %w", err)
+	}
+
+	if err := Create(originalPath, cloudPath); err != nil {
+		if mvErr := MoveFile(cloudPath, originalPath); mvErr != nil {
+			return fmt.Errorf("%w (moving the file back also failed, it is at %s: %v)", err, cloudPath, mvErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SameContent reports whether two files have identical content. Hardlinks
+// to the same file match without reading them, files of different sizes
+// are rejected without reading them, and the comparison stops at the
+// first difference.
+func SameContent(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if os.SameFile(infoA, infoB) {
+		return true, nil
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA := make([]byte, 32*1024)
+	bufB := make([]byte, 32*1024)
+	for {
+		n, errA := io.ReadFull(fa, bufA)
+		m, errB := io.ReadFull(fb, bufB)
+		if !bytes.Equal(bufA[:n], bufB[:m]) {
+			return false, nil
+		}
+		doneA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		doneB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !doneA {
+			return false, errA
+		}
+		if errB != nil && !doneB {
+			return false, errB
+		}
+		if doneA || doneB {
+			return doneA && doneB, nil
+		}
+	}
+}
+
+// HashFile returns the hex-encoded SHA-256 of a file's content.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CopyFile copies a file from src to dst, preserving permissions and the
+// modification time.
 func CopyFile(src, dst string) error {
 	return copyFile(src, dst)
 }
 
+// CopyFileWithHash copies src to dst like CopyFile and returns the
+// hex-encoded SHA-256 of the copied content, computed while copying so
+// the file isn't read a second time (slow on cloud mounts).
+func CopyFileWithHash(src, dst string) (string, error) {
+	h := sha256.New()
+	if err := copyFileTee(src, dst, h); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func copyFile(src, dst string) error {
+	return copyFileTee(src, dst, nil)
+}
+
+// copyFileTee copies src to dst, also writing the content to tee if non-nil.
+func copyFileTee(src, dst string, tee io.Writer) error {
 	sourceFile, err := os.Open(src)
 	if err != nil {
 		return err
@@ -179,7 +584,7 @@ func copyFile(src, dst string) error {
 	}
 
 	// Ensure destination directory exists
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+	if err := mkdirParents(filepath.Dir(dst)); err != nil {
 		return err
 	}
 
@@ -187,8 +592,21 @@ func copyFile(src, dst string) error {
 	if err != nil {
 		return err
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
-	return err
+	var w io.Writer = destFile
+	if tee != nil {
+		w = io.MultiWriter(destFile, tee)
+	}
+	_, err = io.Copy(w, sourceFile)
+	if closeErr := destFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Keep the source's mtime so tools comparing timestamps (make, rsync,
+	// backups) don't see the copy as changed. The access time isn't portable
+	// and reading src just touched it, so it's left as is.
+	return os.Chtimes(dst, time.Time{}, sourceInfo.ModTime())
 }
@@ -1,10 +1,18 @@
 package symlink
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 // TestCreate tests symlink creation
@@ -71,6 +79,45 @@ func TestCreate_WithNestedDirectories(t *testing.T) {
 	}
 }
 
+// TestCreate_SymlinkedParent tests that a symlinked parent directory is
+// followed, and a broken one is reported instead of built through
+func TestCreate_SymlinkedParent(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetFile := filepath.Join(tmpDir, "target.txt")
+	if err := os.WriteFile(targetFile, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create target: %v", err)
+	}
+
+	// ~/.config -> dotfiles/config
+	realConfig := filepath.Join(tmpDir, "dotfiles", "config")
+	os.MkdirAll(realConfig, 0755)
+	home := filepath.Join(tmpDir, "home")
+	os.MkdirAll(home, 0755)
+	if err := os.Symlink(realConfig, filepath.Join(home, ".config")); err != nil {
+		t.Fatalf("failed to symlink parent: %v", err)
+	}
+
+	linkPath := filepath.Join(home, ".config", "app", "config.json")
+	if err := Create(linkPath, targetFile); err != nil {
+		t.Fatalf("Create() through a symlinked parent failed: %v", err)
+	}
+	if isLink, err := IsSymlink(filepath.Join(realConfig, "app", "config.json")); err != nil || !isLink {
+		t.Errorf("symlink should be created inside the parent's target (err: %v)", err)
+	}
+
+	// ~/.local -> a drive that isn't mounted
+	if err := os.Symlink(filepath.Join(tmpDir, "unmounted", "local"), filepath.Join(home, ".local")); err != nil {
+		t.Fatalf("failed to symlink parent: %v", err)
+	}
+	linkPath = filepath.Join(home, ".local", "share", "app", "data.json")
+	if err := Create(linkPath, targetFile); !errors.Is(err, ErrBrokenParentSymlink) {
+		t.Errorf("Create() error = %v, want ErrBrokenParentSymlink", err)
+	}
+	if _, err := os.Lstat(filepath.Join(tmpDir, "unmounted")); !os.IsNotExist(err) {
+		t.Error("nothing should be created behind a broken parent symlink")
+	}
+}
+
 // TestCreate_ParentDirectoryPermissions tests that parent directories are created with 755 permissions
 func TestCreate_ParentDirectoryPermissions(t *testing.T) {
 	if runtime.GOOS == "windows" {
@@ -116,6 +163,90 @@ func TestCreate_ParentDirectoryPermissions(t *testing.T) {
 	}
 }
 
+// TestCreate_PrivateParentDirectory tests that a missing ~/.ssh is recreated as 0700
+func TestCreate_PrivateParentDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping permission test on Windows - permissions work differently")
+	}
+
+	tmpDir := t.TempDir()
+	targetFile := filepath.Join(tmpDir, "target.txt")
+	sshDir := filepath.Join(tmpDir, "home", ".ssh")
+	linkFile := filepath.Join(sshDir, "conf.d", "config")
+
+	if err := os.WriteFile(targetFile, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create target: %v", err)
+	}
+
+	if err := Create(linkFile, targetFile); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	tests := []struct {
+		dir  string
+		want os.FileMode
+	}{
+		{filepath.Join(tmpDir, "home"), 0755},
+		{sshDir, 0700},
+		{filepath.Join(sshDir, "conf.d"), 0700},
+	}
+	for _, tt := range tests {
+		info, err := os.Stat(tt.dir)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", tt.dir, err)
+		}
+		if info.Mode().Perm() != tt.want {
+			t.Errorf("%s permissions = %04o, want %04o", tt.dir, info.Mode().Perm(), tt.want)
+		}
+	}
+}
+
+// TestCreateWithParentMode tests that a missing parent gets the given mode
+// exactly, while an existing parent is left alone
+func TestCreateWithParentMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping permission test on Windows - permissions work differently")
+	}
+
+	tmpDir := t.TempDir()
+	targetFile := filepath.Join(tmpDir, "target.txt")
+	if err := os.WriteFile(targetFile, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create target: %v", err)
+	}
+
+	// 0775 is above the usual umask, so it must be set explicitly
+	parentDir := filepath.Join(tmpDir, "nested", "app")
+	if err := CreateWithParentMode(filepath.Join(parentDir, "link.txt"), targetFile, 0775); err != nil {
+		t.Fatalf("CreateWithParentMode() failed: %v", err)
+	}
+	tests := []struct {
+		dir  string
+		want os.FileMode
+	}{
+		{filepath.Join(tmpDir, "nested"), 0755},
+		{parentDir, 0775},
+	}
+	for _, tt := range tests {
+		info, err := os.Stat(tt.dir)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", tt.dir, err)
+		}
+		if info.Mode().Perm() != tt.want {
+			t.Errorf("%s permissions = %04o, want %04o", tt.dir, info.Mode().Perm(), tt.want)
+		}
+	}
+
+	// An existing parent keeps its mode
+	existing := filepath.Join(tmpDir, "existing")
+	os.Mkdir(existing, 0755)
+	if err := CreateWithParentMode(filepath.Join(existing, "link.txt"), targetFile, 0700); err != nil {
+		t.Fatalf("CreateWithParentMode() failed: %v", err)
+	}
+	if info, _ := os.Stat(existing); info.Mode().Perm() != 0755 {
+		t.Errorf("existing parent permissions = %04o, want 0755", info.Mode().Perm())
+	}
+}
+
 // TestRemove tests symlink removal
 func TestRemove(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -265,6 +396,8 @@ func TestCheck(t *testing.T) {
 		{"incorrect target", incorrectLink, targetFile, StatusIncorrect},
 		{"not linked", regularFile, targetFile, StatusNotLinked},
 		{"doesn't exist", filepath.Join(tmpDir, "nonexistent"), targetFile, StatusNotExist},
+		{"parent doesn't exist", filepath.Join(tmpDir, "missing-dir", "file.txt"), targetFile, StatusParentMissing},
+		{"parent is a broken symlink", filepath.Join(brokenLink, "file.txt"), targetFile, StatusParentMissing},
 	}
 
 	for _, tt := range tests {
@@ -292,6 +425,7 @@ func TestStatus_String(t *testing.T) {
 		{StatusBroken, "broken"},
 		{StatusIncorrect, "incorrect"},
 		{StatusNotLinked, "not linked"},
+		{StatusParentMissing, "parent missing"},
 	}
 
 	for _, tt := range tests {
@@ -337,6 +471,134 @@ func TestMoveFile(t *testing.T) {
 	}
 }
 
+// forceCopyFallback makes MoveFile take the cross-filesystem path for the
+// rest of the test, copying with copy.
+func forceCopyFallback(t *testing.T, copy func(src, dst string) error) {
+	t.Helper()
+	origRename, origCopy := rename, copyFileFunc
+	t.Cleanup(func() { rename, copyFileFunc = origRename, origCopy })
+	rename = func(string, string) error { return &os.LinkError{Op: "rename", Err: os.ErrInvalid} }
+	copyFileFunc = copy
+}
+
+// TestMoveFile_CopyFallback tests moving across filesystems
+func TestMoveFile_CopyFallback(t *testing.T) {
+	forceCopyFallback(t, copyFile)
+
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "src.txt")
+	dstFile := filepath.Join(tmpDir, "subdir", "dst.txt")
+	if err := os.WriteFile(srcFile, []byte("test content"), 0644); err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+
+	if err := MoveFile(srcFile, dstFile); err != nil {
+		t.Fatalf("MoveFile() failed: %v", err)
+	}
+	if _, err := os.Stat(srcFile); !os.IsNotExist(err) {
+		t.Error("source file should be removed")
+	}
+	if data, _ := os.ReadFile(dstFile); string(data) != "test content" {
+		t.Errorf("content = %q, want %q", data, "test content")
+	}
+}
+
+// TestMoveFile_ShortCopy tests that a truncated copy keeps the source
+func TestMoveFile_ShortCopy(t *testing.T) {
+	forceCopyFallback(t, func(src, dst string) error {
+		// Simulate a disk filling up halfway through the copy
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data[:len(data)/2], 0644)
+	})
+
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "src.txt")
+	dstFile := filepath.Join(tmpDir, "subdir", "dst.txt")
+	content := []byte("test content")
+	if err := os.WriteFile(srcFile, content, 0644); err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+
+	if err := MoveFile(srcFile, dstFile); err == nil {
+		t.Fatal("MoveFile() should fail on a short copy")
+	}
+
+	data, err := os.ReadFile(srcFile)
+	if err != nil {
+		t.Fatalf("source file should be kept: %v", err)
+	}
+	if string(data) != string(content) {
+		t.Errorf("source content = %q, want %q", data, content)
+	}
+	if _, err := os.Stat(dstFile); !os.IsNotExist(err) {
+		t.Error("partial destination should be removed")
+	}
+}
+
+// TestReattach tests turning a regular file into a symlink to the cloud
+// copy, overwriting it
+func TestReattach(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalPath := filepath.Join(tmpDir, "home", "config.json")
+	cloudPath := filepath.Join(tmpDir, "storage", "config.json")
+	os.MkdirAll(filepath.Dir(originalPath), 0755)
+	os.MkdirAll(filepath.Dir(cloudPath), 0755)
+	os.WriteFile(originalPath, []byte("edited"), 0644)
+	os.WriteFile(cloudPath, []byte("old"), 0644)
+
+	if err := Reattach(originalPath, cloudPath); err != nil {
+		t.Fatalf("Reattach() failed: %v", err)
+	}
+
+	status, _, _ := Check(originalPath, cloudPath)
+	if status != StatusLinked {
+		t.Errorf("status = %v, want %v", status, StatusLinked)
+	}
+	if data, _ := os.ReadFile(cloudPath); string(data) != "edited" {
+		t.Errorf("cloud content = %q, want %q", data, "edited")
+	}
+
+	// A symlink is not a file to reattach
+	if err := Reattach(originalPath, cloudPath); err == nil {
+		t.Error("Reattach() should fail for a symlink")
+	}
+}
+
+// TestReattach_RollbackOnSymlinkFailure tests that the file is moved back
+// when the symlink can't be created
+func TestReattach_RollbackOnSymlinkFailure(t *testing.T) {
+	orig := symlinkFunc
+	t.Cleanup(func() { symlinkFunc = orig })
+	symlinkFunc = func(string, string) error {
+		return &os.LinkError{Op: "symlink", Err: errorPrivilegeNotHeld}
+	}
+
+	tmpDir := t.TempDir()
+	originalPath := filepath.Join(tmpDir, "home", "config.json")
+	cloudPath := filepath.Join(tmpDir, "storage", "config.json")
+	os.MkdirAll(filepath.Dir(originalPath), 0755)
+	os.WriteFile(originalPath, []byte("content"), 0644)
+
+	err := Reattach(originalPath, cloudPath)
+	if !errors.Is(err, ErrNoSymlinkPrivilege) {
+		t.Fatalf("Reattach() error = %v, want ErrNoSymlinkPrivilege", err)
+	}
+
+	info, err := os.Lstat(originalPath)
+	if err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("original should be a regular file again (err: %v)", err)
+	}
+	if data, _ := os.ReadFile(originalPath); string(data) != "content" {
+		t.Errorf("original content = %q, want %q", data, "content")
+	}
+	if _, err := os.Stat(cloudPath); !os.IsNotExist(err) {
+		t.Error("cloud file should be moved back")
+	}
+}
+
 // TestMoveFile_PreservesPermissions tests file moving preserves permissions
 func TestMoveFile_PreservesPermissions(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -366,6 +628,49 @@ func TestMoveFile_PreservesPermissions(t *testing.T) {
 	}
 }
 
+// TestMoveFile_CopyFallbackPreservesModTime tests that moving across
+// filesystems keeps the source's modification time
+func TestMoveFile_CopyFallbackPreservesModTime(t *testing.T) {
+	forceCopyFallback(t, copyFile)
+
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "src.txt")
+	dstFile := filepath.Join(tmpDir, "subdir", "dst.txt")
+	mtime := writeOldFile(t, srcFile)
+
+	if err := MoveFile(srcFile, dstFile); err != nil {
+		t.Fatalf("MoveFile() failed: %v", err)
+	}
+	assertModTime(t, dstFile, mtime)
+}
+
+// writeOldFile creates path with its modification time a year in the past
+// and returns that time.
+func writeOldFile(t *testing.T, path string) time.Time {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("test content"), 0644); err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+	mtime := time.Now().AddDate(-1, 0, 0)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("failed to set source times: %v", err)
+	}
+	return mtime
+}
+
+// assertModTime checks that path was last modified at want, within the
+// timestamp resolution of the filesystem.
+func assertModTime(t *testing.T, path string, want time.Time) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat destination: %v", err)
+	}
+	if diff := info.ModTime().Sub(want).Abs(); diff > time.Second {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), want)
+	}
+}
+
 // TestCopyFile tests file copying
 func TestCopyFile(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -399,6 +704,95 @@ func TestCopyFile(t *testing.T) {
 	}
 }
 
+// TestSameContent tests file content comparison
+func TestSameContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.txt")
+	b := filepath.Join(tmpDir, "b.txt")
+	c := filepath.Join(tmpDir, "c.txt")
+
+	os.WriteFile(a, []byte("same"), 0644)
+	os.WriteFile(b, []byte("same"), 0644)
+	os.WriteFile(c, []byte("diff"), 0644)
+
+	same, err := SameContent(a, b)
+	if err != nil {
+		t.Fatalf("SameContent() failed: %v", err)
+	}
+	if !same {
+		t.Error("SameContent(a, b) = false, want true")
+	}
+
+	same, err = SameContent(a, c)
+	if err != nil {
+		t.Fatalf("SameContent() failed: %v", err)
+	}
+	if same {
+		t.Error("SameContent(a, c) = true, want false")
+	}
+
+	if _, err := SameContent(a, filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("SameContent() should fail for missing file")
+	}
+}
+
+// TestSameContent_Large tests files spanning several read buffers
+func TestSameContent_Large(t *testing.T) {
+	tmpDir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+	changed := append([]byte{}, data...)
+	changed[len(changed)-1] = 'x'
+
+	tests := []struct {
+		name string
+		a, b []byte
+		want bool
+	}{
+		{"identical", data, data, true},
+		{"last byte differs", data, changed, false},
+		{"both empty", nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := filepath.Join(tmpDir, "a-"+tt.name)
+			b := filepath.Join(tmpDir, "b-"+tt.name)
+			os.WriteFile(a, tt.a, 0644)
+			os.WriteFile(b, tt.b, 0644)
+
+			same, err := SameContent(a, b)
+			if err != nil {
+				t.Fatalf("SameContent() failed: %v", err)
+			}
+			if same != tt.want {
+				t.Errorf("SameContent() = %v, want %v", same, tt.want)
+			}
+		})
+	}
+}
+
+// TestSameContent_Hardlink tests that hardlinks match, and stop matching
+// once one side is replaced by a diverging copy
+func TestSameContent_Hardlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.txt")
+	b := filepath.Join(tmpDir, "b.txt")
+	os.WriteFile(a, []byte("same"), 0644)
+	if err := os.Link(a, b); err != nil {
+		t.Skipf("hardlinks not supported: %v", err)
+	}
+
+	if same, err := SameContent(a, b); err != nil || !same {
+		t.Errorf("SameContent() hardlink = %v, %v, want true, nil", same, err)
+	}
+
+	os.Remove(b)
+	os.WriteFile(b, []byte("diff"), 0644)
+	if same, err := SameContent(a, b); err != nil || same {
+		t.Errorf("SameContent() diverged copy = %v, %v, want false, nil", same, err)
+	}
+}
+
 // TestCopyFile_PreservesPermissions tests file copying preserves permissions
 func TestCopyFile_PreservesPermissions(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -454,3 +848,355 @@ func TestCheck_AbsolutePaths(t *testing.T) {
 		t.Errorf("actualTarget = %q, want %q", actualTarget, targetFile)
 	}
 }
+
+// TestCheck_RelativeTarget tests that a correct relative symlink is linked,
+// regardless of the process working directory
+func TestCheck_RelativeTarget(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetFile := filepath.Join(tmpDir, "storage", "dotsync", "app", "config.json")
+	linkFile := filepath.Join(tmpDir, "home", ".config", "app", "config.json")
+
+	os.MkdirAll(filepath.Dir(targetFile), 0755)
+	os.MkdirAll(filepath.Dir(linkFile), 0755)
+	os.WriteFile(targetFile, []byte("content"), 0644)
+
+	relTarget, err := filepath.Rel(filepath.Dir(linkFile), targetFile)
+	if err != nil {
+		t.Fatalf("failed to compute relative target: %v", err)
+	}
+	if err := os.Symlink(relTarget, linkFile); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	status, actualTarget, err := Check(linkFile, targetFile)
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if status != StatusLinked {
+		t.Errorf("status = %v, want %v", status, StatusLinked)
+	}
+	if actualTarget != relTarget {
+		t.Errorf("actualTarget = %q, want %q", actualTarget, relTarget)
+	}
+
+	// A relative symlink pointing elsewhere is still incorrect
+	otherFile := filepath.Join(tmpDir, "other.json")
+	os.WriteFile(otherFile, []byte("other"), 0644)
+	status, _, err = Check(linkFile, otherFile)
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if status != StatusIncorrect {
+		t.Errorf("status = %v, want %v", status, StatusIncorrect)
+	}
+}
+
+// TestCopyFile_PreservesModTime tests that copies keep the source's
+// modification time
+func TestCopyFile_PreservesModTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "src.txt")
+	mtime := writeOldFile(t, srcFile)
+
+	dstFile := filepath.Join(tmpDir, "dst.txt")
+	if err := CopyFile(srcFile, dstFile); err != nil {
+		t.Fatalf("CopyFile() failed: %v", err)
+	}
+	assertModTime(t, dstFile, mtime)
+
+	hashed := filepath.Join(tmpDir, "hashed.txt")
+	if _, err := CopyFileWithHash(srcFile, hashed); err != nil {
+		t.Fatalf("CopyFileWithHash() failed: %v", err)
+	}
+	assertModTime(t, hashed, mtime)
+}
+
+// TestCopyFileWithHash tests that the hash returned while copying matches the content
+func TestCopyFileWithHash(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "src.bin")
+	dstFile := filepath.Join(tmpDir, "subdir", "dst.bin")
+
+	content := bytes.Repeat([]byte("dotsync"), 200000)
+	if err := os.WriteFile(srcFile, content, 0644); err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+
+	hash, err := CopyFileWithHash(srcFile, dstFile)
+	if err != nil {
+		t.Fatalf("CopyFileWithHash() error: %v", err)
+	}
+
+	sum := sha256.Sum256(content)
+	if want := hex.EncodeToString(sum[:]); hash != want {
+		t.Errorf("CopyFileWithHash() = %q, want %q", hash, want)
+	}
+	if dstHash, _ := HashFile(dstFile); dstHash != hash {
+		t.Errorf("destination hash = %q, want %q", dstHash, hash)
+	}
+
+	if _, err := CopyFileWithHash(filepath.Join(tmpDir, "missing"), dstFile); err == nil {
+		t.Error("expected error for missing source")
+	}
+}
+
+// TestHashFile tests content hashing
+func TestHashFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a")
+	b := filepath.Join(tmpDir, "b")
+	c := filepath.Join(tmpDir, "c")
+	os.WriteFile(a, []byte("content"), 0644)
+	os.WriteFile(b, []byte("content"), 0600)
+	os.WriteFile(c, []byte("other"), 0644)
+
+	hashA, err := HashFile(a)
+	if err != nil {
+		t.Fatalf("HashFile() error: %v", err)
+	}
+	// sha256("content")
+	if want := "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"; hashA != want {
+		t.Errorf("HashFile(a) = %q, want %q", hashA, want)
+	}
+
+	hashB, _ := HashFile(b)
+	if hashB != hashA {
+		t.Error("files with the same content should hash equally")
+	}
+	hashC, _ := HashFile(c)
+	if hashC == hashA {
+		t.Error("files with different content should hash differently")
+	}
+
+	if _, err := HashFile(filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("HashFile() should fail for a missing file")
+	}
+}
+
+// TestCheck_SymlinkedHome tests that a link created through the real home
+// path is linked when checked through a symlinked home (e.g. /home -> /var/home)
+func TestCheck_SymlinkedHome(t *testing.T) {
+	tmpDir := t.TempDir()
+	realHome := filepath.Join(tmpDir, "var", "home")
+	home := filepath.Join(tmpDir, "home")
+
+	if err := os.MkdirAll(filepath.Join(realHome, "storage"), 0755); err != nil {
+		t.Fatalf("failed to create home: %v", err)
+	}
+	if err := os.Symlink(realHome, home); err != nil {
+		t.Fatalf("failed to symlink home: %v", err)
+	}
+
+	realTarget := filepath.Join(realHome, "storage", "config.json")
+	if err := os.WriteFile(realTarget, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create target: %v", err)
+	}
+	linkFile := filepath.Join(home, "config.json")
+	if err := os.Symlink(realTarget, linkFile); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	status, _, err := Check(linkFile, filepath.Join(home, "storage", "config.json"))
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if status != StatusLinked {
+		t.Errorf("status = %v, want %v", status, StatusLinked)
+	}
+
+	// The reverse: link through the symlinked home, checked via the real path
+	linkFile2 := filepath.Join(home, "other.json")
+	if err := os.Symlink(filepath.Join(home, "storage", "config.json"), linkFile2); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	status, _, err = Check(linkFile2, realTarget)
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if status != StatusLinked {
+		t.Errorf("reverse status = %v, want %v", status, StatusLinked)
+	}
+}
+
+// TestTargetWithin tests checking whether a symlink points inside a directory
+func TestTargetWithin(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage := filepath.Join(tmpDir, "storage", "dotsync")
+	os.MkdirAll(filepath.Join(storage, "app"), 0755)
+	os.WriteFile(filepath.Join(storage, "app", "config.json"), []byte("x"), 0644)
+	os.MkdirAll(filepath.Join(tmpDir, "dotfiles"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "home"), 0755)
+
+	tests := []struct {
+		name   string
+		target string
+		want   bool
+	}{
+		{"absolute into storage", filepath.Join(storage, "app", "config.json"), true},
+		{"relative into storage", filepath.Join("..", "storage", "dotsync", "app", "config.json"), true},
+		{"broken into storage", filepath.Join(storage, "gone", "x.json"), true},
+		{"other tool", filepath.Join(tmpDir, "dotfiles", "config.json"), false},
+		{"storage sibling with same prefix", filepath.Join(tmpDir, "storage", "dotsync-old", "config.json"), false},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := filepath.Join(tmpDir, "home", fmt.Sprintf("link%d", i))
+			if err := os.Symlink(tt.target, link); err != nil {
+				t.Fatalf("failed to create symlink: %v", err)
+			}
+			got, err := TargetWithin(link, storage)
+			if err != nil {
+				t.Fatalf("TargetWithin() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TargetWithin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := TargetWithin(filepath.Join(tmpDir, "home", "missing"), storage); err == nil {
+		t.Error("TargetWithin() should fail for a path that isn't a symlink")
+	}
+}
+
+// TestManagedAncestor tests detection of parents symlinked into managed storage
+func TestManagedAncestor(t *testing.T) {
+	tmpDir := t.TempDir()
+	managedDir := filepath.Join(tmpDir, "storage", "dotsync")
+	cloudAppDir := filepath.Join(managedDir, "app")
+	homeConfig := filepath.Join(tmpDir, "home", ".config")
+
+	os.MkdirAll(cloudAppDir, 0755)
+	os.MkdirAll(filepath.Join(homeConfig, "plain"), 0755)
+	if err := os.Symlink(cloudAppDir, filepath.Join(homeConfig, "app")); err != nil {
+		t.Fatalf("failed to create dir symlink: %v", err)
+	}
+
+	// Symlinked directory pointing elsewhere is not managed
+	otherDir := filepath.Join(tmpDir, "other")
+	os.MkdirAll(otherDir, 0755)
+	os.Symlink(otherDir, filepath.Join(homeConfig, "other"))
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"file under managed dir symlink", filepath.Join(homeConfig, "app", "config.json"), filepath.Join(homeConfig, "app")},
+		{"nested file under managed dir symlink", filepath.Join(homeConfig, "app", "sub", "x.json"), filepath.Join(homeConfig, "app")},
+		{"file under plain dir", filepath.Join(homeConfig, "plain", "config.json"), ""},
+		{"file under unmanaged dir symlink", filepath.Join(homeConfig, "other", "config.json"), ""},
+		{"managed dir symlink itself", filepath.Join(homeConfig, "app"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ManagedAncestor(tt.path, managedDir); got != tt.want {
+				t.Errorf("ManagedAncestor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsUnsupportedError tests detecting filesystems without symlinks
+func TestIsUnsupportedError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"EPERM", &os.LinkError{Op: "symlink", Old: "a", New: "b", Err: syscall.EPERM}, true},
+		{"ENOTSUP", &os.LinkError{Op: "symlink", Old: "a", New: "b", Err: syscall.ENOTSUP}, true},
+		{"ENOSYS", &os.LinkError{Op: "symlink", Old: "a", New: "b", Err: syscall.ENOSYS}, true},
+		{"EACCES", &os.LinkError{Op: "symlink", Old: "a", New: "b", Err: syscall.EACCES}, false},
+		{"already exists", &os.LinkError{Op: "symlink", Old: "a", New: "b", Err: os.ErrExist}, false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnsupportedError(tt.err); got != tt.want {
+				t.Errorf("isUnsupportedError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCreate_Unsupported tests that Create reports filesystems without
+// symlink support
+func TestCreate_Unsupported(t *testing.T) {
+	orig := symlinkFunc
+	t.Cleanup(func() { symlinkFunc = orig })
+	symlinkFunc = func(string, string) error {
+		return &os.LinkError{Op: "symlink", Err: syscall.EPERM}
+	}
+
+	tmpDir := t.TempDir()
+	err := Create(filepath.Join(tmpDir, "link"), filepath.Join(tmpDir, "target"))
+	if !errors.Is(err, ErrSymlinkUnsupported) {
+		t.Fatalf("Create() error = %v, want ErrSymlinkUnsupported", err)
+	}
+	// Commands differ in how to fall back to copies, so they add the hint
+	if strings.Contains(err.Error(), "--") {
+		t.Errorf("Create() error = %q, want no command-line flags", err)
+	}
+}
+
+// TestIsPrivilegeError tests detecting the Windows symlink privilege error
+func TestIsPrivilegeError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"privilege not held", &os.LinkError{Op: "symlink", Old: "a", New: "b", Err: errorPrivilegeNotHeld}, true},
+		{"permission denied", &os.LinkError{Op: "symlink", Old: "a", New: "b", Err: os.ErrPermission}, false},
+		{"already exists", &os.LinkError{Op: "symlink", Old: "a", New: "b", Err: os.ErrExist}, false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPrivilegeError(tt.err); got != tt.want {
+				t.Errorf("isPrivilegeError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCheckLoop tests detecting original and cloud paths that overlap
+func TestCheckLoop(t *testing.T) {
+	tmpDir := t.TempDir()
+	home := filepath.Join(tmpDir, "home")
+	managed := filepath.Join(tmpDir, "storage", "dotsync")
+	os.MkdirAll(home, 0755)
+	os.MkdirAll(managed, 0755)
+	// A home folder that is really the storage folder
+	os.Symlink(filepath.Join(tmpDir, "storage"), filepath.Join(home, "cloud"))
+
+	tests := []struct {
+		name     string
+		original string
+		cloud    string
+		wantErr  bool
+	}{
+		{"separate", filepath.Join(home, ".zshrc"), filepath.Join(managed, "zsh", ".zshrc"), false},
+		{"same path", filepath.Join(managed, "zsh", ".zshrc"), filepath.Join(managed, "zsh", ".zshrc"), true},
+		{"original inside storage", filepath.Join(managed, "app", "config.json"), filepath.Join(managed, "app", "app", "config.json"), true},
+		{"original inside storage via symlink", filepath.Join(home, "cloud", "dotsync", "a.json"), filepath.Join(managed, "app", "a.json"), true},
+		{"cloud inside original", filepath.Join(home, "proj"), filepath.Join(home, "proj", "dotsync", "app", "proj"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckLoop(tt.original, tt.cloud, managed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckLoop() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrLinkLoop) {
+				t.Errorf("CheckLoop() = %v, want ErrLinkLoop", err)
+			}
+		})
+	}
+}